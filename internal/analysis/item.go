@@ -0,0 +1,255 @@
+// Package analysis 基于经典测量理论的题目分析：难度（通过率）、区分度和选项分析
+// 区分度按学生在同一次测验（一份试卷或一次作业提交）中的总分计算，不同测验的总分先在测验内标准化再合并
+package analysis
+
+import (
+	"math"
+	"sort"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+)
+
+// 分析参数的默认值
+const (
+	DefaultMinResponses        = 20   // 作答人数达到该值时才给出问题标记
+	DefaultDistractorThreshold = 0.05 // 干扰项的选择率低于该值视为无效干扰项
+	groupFraction              = 0.27 // 高分组和低分组各占的比例
+)
+
+// 题目问题标记
+const (
+	FlagNegativeDiscrimination   = "negative_discrimination"    // 总分高的学生反而更容易答错
+	FlagNonFunctioningDistractor = "non_functioning_distractor" // 有几乎没有人选择的干扰项
+)
+
+// Response 一名学生在一次测验中对该题的作答
+type Response struct {
+	Form      string  // 测验标识，总分只在同一测验内比较
+	IsCorrect bool    // 是否答对
+	Score     float64 // 本题得分
+	Total     float64 // 该学生本次测验的总分，包含本题得分
+	Choices   []int   // 选择题选中的选项下标
+}
+
+// Option 选项的选择情况
+type Option struct {
+	Index          int     // 选项下标
+	Correct        bool    // 是否为正确选项
+	Count          int     // 选择人数
+	Rate           float64 // 选择率
+	UpperRate      float64 // 高分组的选择率
+	LowerRate      float64 // 低分组的选择率
+	NonFunctioning bool    // 无效干扰项：错误选项且选择率低于阈值
+}
+
+// Result 题目分析结果
+type Result struct {
+	Responses      int      // 参与分析的作答数
+	PValue         float64  // 难度，即答对比例
+	PointBiserial  float64  // 点二列相关：本题对错与其余题目总分的相关系数
+	Discrimination float64  // 区分度指数：高分组答对比例减低分组答对比例
+	UpperCount     int      // 高分组人数
+	LowerCount     int      // 低分组人数
+	Options        []Option // 选择题的选项分析
+	Flags          []string // 问题标记，作答数不足时为空
+	Sufficient     bool     // 作答数是否达到给出问题标记的要求
+}
+
+// Config 分析参数
+type Config struct {
+	MinResponses        int
+	DistractorThreshold float64
+}
+
+// Analyze 分析题目，correct 为各选项是否正确，不是选择题时为nil
+func Analyze(responses []Response, correct []bool, config Config) Result {
+	if config.MinResponses <= 0 {
+		config.MinResponses = DefaultMinResponses
+	}
+	if config.DistractorThreshold <= 0 {
+		config.DistractorThreshold = DefaultDistractorThreshold
+	}
+
+	result := Result{Responses: len(responses)}
+	if len(responses) == 0 {
+		return result
+	}
+
+	// 本题对错与其余题目总分在测验内的标准分
+	items := make([]float64, len(responses))
+	rest := restScores(responses)
+	passed := 0.0
+	for i, r := range responses {
+		if r.IsCorrect {
+			items[i] = 1
+			passed++
+		}
+	}
+	result.PValue = passed / float64(len(responses))
+	result.PointBiserial = correlation(items, rest)
+
+	// 按标准分排序后取高分组和低分组
+	order := make([]int, len(responses))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rest[order[a]] > rest[order[b]] })
+	groupSize := int(math.Round(float64(len(responses)) * groupFraction))
+	if groupSize == 0 && len(responses) >= 2 {
+		groupSize = 1
+	}
+	upper, lower := order[:groupSize], order[len(order)-groupSize:]
+	result.UpperCount, result.LowerCount = len(upper), len(lower)
+	if groupSize > 0 {
+		result.Discrimination = rate(upper, items) - rate(lower, items)
+	}
+
+	if len(correct) > 0 {
+		result.Options = analyzeOptions(responses, correct, upper, lower, config.DistractorThreshold)
+	}
+
+	result.Sufficient = len(responses) >= config.MinResponses
+	if result.Sufficient {
+		if result.PointBiserial < 0 || result.Discrimination < 0 {
+			result.Flags = append(result.Flags, FlagNegativeDiscrimination)
+		}
+		for _, option := range result.Options {
+			if option.NonFunctioning {
+				result.Flags = append(result.Flags, FlagNonFunctioningDistractor)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// restScores 其余题目的总分（去掉本题得分），在每个测验内转换为标准分
+func restScores(responses []Response) []float64 {
+	forms := make(map[string][]int)
+	for i, r := range responses {
+		forms[r.Form] = append(forms[r.Form], i)
+	}
+	scores := make([]float64, len(responses))
+	for _, indexes := range forms {
+		values := make([]float64, len(indexes))
+		for j, i := range indexes {
+			values[j] = responses[i].Total - responses[i].Score
+		}
+		mean, sd := meanStdDev(values)
+		for j, i := range indexes {
+			if sd > 0 {
+				scores[i] = (values[j] - mean) / sd
+			}
+		}
+	}
+	return scores
+}
+
+// analyzeOptions 统计每个选项的选择情况，多选题一次作答可以选择多个选项
+func analyzeOptions(responses []Response, correct []bool, upper, lower []int, threshold float64) []Option {
+	counts := func(indexes []int) []int {
+		picked := make([]int, len(correct))
+		for _, i := range indexes {
+			for _, choice := range responses[i].Choices {
+				if choice >= 0 && choice < len(correct) {
+					picked[choice]++
+				}
+			}
+		}
+		return picked
+	}
+	all := make([]int, len(responses))
+	for i := range all {
+		all[i] = i
+	}
+	total, upperCounts, lowerCounts := counts(all), counts(upper), counts(lower)
+
+	options := make([]Option, len(correct))
+	for i := range options {
+		options[i] = Option{
+			Index:   i,
+			Correct: correct[i],
+			Count:   total[i],
+			Rate:    float64(total[i]) / float64(len(responses)),
+		}
+		if len(upper) > 0 {
+			options[i].UpperRate = float64(upperCounts[i]) / float64(len(upper))
+			options[i].LowerRate = float64(lowerCounts[i]) / float64(len(lower))
+		}
+		options[i].NonFunctioning = !correct[i] && options[i].Rate < threshold
+	}
+	return options
+}
+
+// ChoiceIndexes 选择题答案对应的选项下标，不是选择题或没有选项时返回nil
+func ChoiceIndexes(question *entity.Question, answer string) []int {
+	if question.Type != entity.TypeChoice && question.Type != entity.TypeMultiChoice {
+		return nil
+	}
+	options := grading.ParseOptionTexts(question.Options)
+	if len(options) == 0 {
+		return nil
+	}
+	var indexes []int
+	for _, key := range grading.ParseChoiceSet(answer, options) {
+		if len(key) == 1 && key[0] >= 'A' && int(key[0]-'A') < len(options) {
+			indexes = append(indexes, int(key[0]-'A'))
+		}
+	}
+	return indexes
+}
+
+// CorrectOptions 选择题各选项是否为正确答案，不是选择题时返回nil
+func CorrectOptions(question *entity.Question) []bool {
+	options := grading.ParseOptionTexts(question.Options)
+	if len(options) == 0 || (question.Type != entity.TypeChoice && question.Type != entity.TypeMultiChoice) {
+		return nil
+	}
+	correct := make([]bool, len(options))
+	for _, index := range ChoiceIndexes(question, question.Answer) {
+		correct[index] = true
+	}
+	return correct
+}
+
+func rate(indexes []int, values []float64) float64 {
+	if len(indexes) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, i := range indexes {
+		sum += values[i]
+	}
+	return sum / float64(len(indexes))
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// correlation 皮尔逊相关系数，本题对错为0/1时即为点二列相关；任一方没有差异时为0
+func correlation(x, y []float64) float64 {
+	mx, sx := meanStdDev(x)
+	my, sy := meanStdDev(y)
+	if sx == 0 || sy == 0 {
+		return 0
+	}
+	sum := 0.0
+	for i := range x {
+		sum += (x[i] - mx) * (y[i] - my)
+	}
+	return sum / float64(len(x)) / (sx * sy)
+}
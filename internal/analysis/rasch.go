@@ -0,0 +1,420 @@
+package analysis
+
+import "math"
+
+// Rasch（单参数）模型：学生 n 答对题目 i 的概率为 1 / (1 + exp(b_i - θ_n))，
+// b_i 为题目难度，θ_n 为学生能力，单位为 logit，题目难度的均值固定为0
+
+// Rasch估计参数的默认值
+const (
+	DefaultMaxIterations      = 100
+	DefaultTolerance          = 0.001 // 一轮迭代中参数的最大变化小于该值时视为收敛
+	DefaultMinItemResponses   = 10    // 作答人数少于该值的题目不参与校准
+	DefaultMinPersonResponses = 2     // 作答题数少于该值的学生不参与校准
+
+	maxStep       = 1.0  // 每次牛顿迭代的最大步长
+	extremeOffset = 0.5  // 全对或全错时调整的得分
+	maxLogit      = 10.0 // 参数的取值范围
+)
+
+// Observation 一名学生对一道题的作答
+type Observation struct {
+	PersonID uint
+	ItemID   uint
+	Correct  bool
+}
+
+// RaschConfig 估计参数
+type RaschConfig struct {
+	MaxIterations      int
+	Tolerance          float64
+	MinItemResponses   int
+	MinPersonResponses int
+}
+
+// ItemEstimate 题目难度估计
+type ItemEstimate struct {
+	ItemID     uint
+	Difficulty float64 // 难度，logit
+	StdError   float64
+	Responses  int
+	Correct    int
+	Extreme    bool // 全部答对或全部答错（不计极端学生），难度按调整后的得分估计，仅供参考
+}
+
+// PersonEstimate 学生能力估计
+type PersonEstimate struct {
+	PersonID  uint
+	Ability   float64 // 能力，logit
+	StdError  float64
+	Responses int
+	Correct   int
+	Extreme   bool // 全部答对或全部答错（不计极端题目），能力按调整后的得分估计，仅供参考
+}
+
+// RaschResult 估计结果
+type RaschResult struct {
+	Items        []ItemEstimate
+	Persons      []PersonEstimate
+	Observations int // 参与估计的作答数
+	Iterations   int
+	Converged    bool
+}
+
+// FitRasch 用联合极大似然（JML）估计题目难度和学生能力
+// 先去掉作答数不足的题目和学生，全对或全错的题目和学生不参与联合估计，
+// 联合估计收敛后在其余参数固定的情况下按调整后的得分单独估计
+func FitRasch(observations []Observation, config RaschConfig) RaschResult {
+	if config.MaxIterations <= 0 {
+		config.MaxIterations = DefaultMaxIterations
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = DefaultTolerance
+	}
+	if config.MinItemResponses <= 0 {
+		config.MinItemResponses = DefaultMinItemResponses
+	}
+	if config.MinPersonResponses <= 0 {
+		config.MinPersonResponses = DefaultMinPersonResponses
+	}
+
+	observations = filterObservations(observations, config)
+	result := RaschResult{Observations: len(observations)}
+	if len(observations) == 0 {
+		return result
+	}
+
+	m := newRaschModel(observations)
+	result.Iterations, result.Converged = m.fit(config)
+	m.estimateExtremes()
+
+	for i, id := range m.itemIDs {
+		result.Items = append(result.Items, ItemEstimate{
+			ItemID:     id,
+			Difficulty: m.difficulty[i],
+			StdError:   standardError(m.itemInfo(i)),
+			Responses:  len(m.itemObs[i]),
+			Correct:    m.itemScore[i],
+			Extreme:    m.itemExtreme[i],
+		})
+	}
+	for n, id := range m.personIDs {
+		result.Persons = append(result.Persons, PersonEstimate{
+			PersonID:  id,
+			Ability:   m.ability[n],
+			StdError:  standardError(m.personInfo(n)),
+			Responses: len(m.personObs[n]),
+			Correct:   m.personScore[n],
+			Extreme:   m.personExtreme[n],
+		})
+	}
+	return result
+}
+
+// filterObservations 反复去掉作答数不足的题目和学生，直到不再变化
+func filterObservations(observations []Observation, config RaschConfig) []Observation {
+	for {
+		items, persons := map[uint]int{}, map[uint]int{}
+		for _, o := range observations {
+			items[o.ItemID]++
+			persons[o.PersonID]++
+		}
+		kept := observations[:0:0]
+		for _, o := range observations {
+			if items[o.ItemID] >= config.MinItemResponses && persons[o.PersonID] >= config.MinPersonResponses {
+				kept = append(kept, o)
+			}
+		}
+		if len(kept) == len(observations) {
+			return kept
+		}
+		observations = kept
+	}
+}
+
+type raschObs struct {
+	person, item int
+	correct      float64
+}
+
+type raschModel struct {
+	itemIDs, personIDs         []uint
+	itemObs, personObs         [][]int // 每道题、每名学生的作答在 obs 中的下标
+	obs                        []raschObs
+	itemScore, personScore     []int
+	difficulty, ability        []float64
+	itemExtreme, personExtreme []bool
+}
+
+func newRaschModel(observations []Observation) *raschModel {
+	m := &raschModel{}
+	itemIndex, personIndex := map[uint]int{}, map[uint]int{}
+	for _, o := range observations {
+		if _, ok := itemIndex[o.ItemID]; !ok {
+			itemIndex[o.ItemID] = len(m.itemIDs)
+			m.itemIDs = append(m.itemIDs, o.ItemID)
+		}
+		if _, ok := personIndex[o.PersonID]; !ok {
+			personIndex[o.PersonID] = len(m.personIDs)
+			m.personIDs = append(m.personIDs, o.PersonID)
+		}
+	}
+	m.itemObs = make([][]int, len(m.itemIDs))
+	m.personObs = make([][]int, len(m.personIDs))
+	m.itemScore = make([]int, len(m.itemIDs))
+	m.personScore = make([]int, len(m.personIDs))
+	for k, o := range observations {
+		ob := raschObs{person: personIndex[o.PersonID], item: itemIndex[o.ItemID]}
+		if o.Correct {
+			ob.correct = 1
+			m.itemScore[ob.item]++
+			m.personScore[ob.person]++
+		}
+		m.obs = append(m.obs, ob)
+		m.itemObs[ob.item] = append(m.itemObs[ob.item], k)
+		m.personObs[ob.person] = append(m.personObs[ob.person], k)
+	}
+
+	m.difficulty = make([]float64, len(m.itemIDs))
+	m.ability = make([]float64, len(m.personIDs))
+	m.itemExtreme = make([]bool, len(m.itemIDs))
+	m.personExtreme = make([]bool, len(m.personIDs))
+	m.markExtremes()
+	return m
+}
+
+// markExtremes 反复标记在联合估计使用的作答中全对或全错的题目和学生，直到不再变化
+// 去掉极端题目后，原本有对有错的学生可能在其余题目上全对或全错，反之亦然；
+// 这样的参与者留在联合估计中会使估计方程没有解，参数持续漂移而无法收敛
+func (m *raschModel) markExtremes() {
+	extreme := func(indexes []int, excluded func(raschObs) bool) bool {
+		count, score := 0, 0.0
+		for _, k := range indexes {
+			if !excluded(m.obs[k]) {
+				count++
+				score += m.obs[k].correct
+			}
+		}
+		return score == 0 || score == float64(count)
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := range m.itemIDs {
+			if !m.itemExtreme[i] && extreme(m.itemObs[i], func(o raschObs) bool { return m.personExtreme[o.person] }) {
+				m.itemExtreme[i], changed = true, true
+			}
+		}
+		for n := range m.personIDs {
+			if !m.personExtreme[n] && extreme(m.personObs[n], func(o raschObs) bool { return m.itemExtreme[o.item] }) {
+				m.personExtreme[n], changed = true, true
+			}
+		}
+	}
+}
+
+func (m *raschModel) probability(k int) float64 {
+	o := m.obs[k]
+	return 1 / (1 + math.Exp(m.difficulty[o.item]-m.ability[o.person]))
+}
+
+// fit 交替更新学生能力和题目难度，只使用非极端的题目和学生之间的作答
+// 收敛按平移到题目难度均值为0之后的参数变化判断
+func (m *raschModel) fit(config RaschConfig) (int, bool) {
+	active := func(k int) bool {
+		o := m.obs[k]
+		return !m.itemExtreme[o.item] && !m.personExtreme[o.person]
+	}
+	for iteration := 1; iteration <= config.MaxIterations; iteration++ {
+		difficulty := append([]float64(nil), m.difficulty...)
+		ability := append([]float64(nil), m.ability...)
+		for n := range m.personIDs {
+			if !m.personExtreme[n] {
+				m.updateAbility(n, active)
+			}
+		}
+		for i := range m.itemIDs {
+			if !m.itemExtreme[i] {
+				m.updateDifficulty(i, active)
+			}
+		}
+		m.center()
+		if math.Max(maxChange(difficulty, m.difficulty), maxChange(ability, m.ability)) < config.Tolerance {
+			m.correctBias()
+			return iteration, true
+		}
+	}
+	m.correctBias()
+	return config.MaxIterations, false
+}
+
+// updateAbility 一步牛顿迭代，只使用 use 选中的作答
+func (m *raschModel) updateAbility(n int, use func(int) bool) float64 {
+	score, expected, info := m.sums(m.personObs[n], use)
+	if info == 0 {
+		return 0
+	}
+	step := clampStep((score - expected) / info)
+	m.ability[n] = clampLogit(m.ability[n] + step)
+	return math.Abs(step)
+}
+
+// updateDifficulty 一步牛顿迭代，只使用 use 选中的作答
+func (m *raschModel) updateDifficulty(i int, use func(int) bool) float64 {
+	score, expected, info := m.sums(m.itemObs[i], use)
+	if info == 0 {
+		return 0
+	}
+	step := clampStep((expected - score) / info)
+	m.difficulty[i] = clampLogit(m.difficulty[i] + step)
+	return math.Abs(step)
+}
+
+// sums 选中的作答的得分、期望得分和信息量
+// 得分全对时减0.5、全错时加0.5，使极端得分也有有限的估计值
+func (m *raschModel) sums(indexes []int, use func(int) bool) (score, expected, info float64) {
+	count := 0.0
+	for _, k := range indexes {
+		if use(k) {
+			p := m.probability(k)
+			count++
+			score += m.obs[k].correct
+			expected += p
+			info += p * (1 - p)
+		}
+	}
+	switch {
+	case count == 0:
+	case score == 0:
+		score = extremeOffset
+	case score == count:
+		score = count - extremeOffset
+	}
+	return score, expected, info
+}
+
+// center 非极端题目的难度均值平移到0，学生能力同步平移
+func (m *raschModel) center() {
+	sum, count := 0.0, 0
+	for i, b := range m.difficulty {
+		if !m.itemExtreme[i] {
+			sum += b
+			count++
+		}
+	}
+	if count == 0 {
+		return
+	}
+	mean := sum / float64(count)
+	for i := range m.difficulty {
+		if !m.itemExtreme[i] {
+			m.difficulty[i] -= mean
+		}
+	}
+	for n := range m.ability {
+		if !m.personExtreme[n] {
+			m.ability[n] -= mean
+		}
+	}
+}
+
+// correctBias JML对题目难度的高估按 (L-1)/L 修正，L 为学生平均作答题数
+func (m *raschModel) correctBias() {
+	total, persons := 0, 0
+	for n := range m.personIDs {
+		if !m.personExtreme[n] {
+			total += len(m.personObs[n])
+			persons++
+		}
+	}
+	if persons == 0 {
+		return
+	}
+	length := float64(total) / float64(persons)
+	if length <= 1 {
+		return
+	}
+	for i := range m.difficulty {
+		if !m.itemExtreme[i] {
+			m.difficulty[i] *= (length - 1) / length
+		}
+	}
+}
+
+// estimateExtremes 固定其余参数，估计全对或全错的题目和学生
+func (m *raschModel) estimateExtremes() {
+	// 极端题目只使用非极端学生的作答
+	fromActivePersons := func(k int) bool { return !m.personExtreme[m.obs[k].person] }
+	for i := range m.itemIDs {
+		if m.itemExtreme[i] {
+			for j := 0; j < DefaultMaxIterations; j++ {
+				if m.updateDifficulty(i, fromActivePersons) < DefaultTolerance {
+					break
+				}
+			}
+		}
+	}
+	// 极端学生使用全部作答，此时所有题目都已有估计值
+	all := func(int) bool { return true }
+	for n := range m.personIDs {
+		if m.personExtreme[n] {
+			for j := 0; j < DefaultMaxIterations; j++ {
+				if m.updateAbility(n, all) < DefaultTolerance {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (m *raschModel) itemInfo(i int) float64 {
+	info := 0.0
+	for _, k := range m.itemObs[i] {
+		p := m.probability(k)
+		info += p * (1 - p)
+	}
+	return info
+}
+
+func (m *raschModel) personInfo(n int) float64 {
+	info := 0.0
+	for _, k := range m.personObs[n] {
+		p := m.probability(k)
+		info += p * (1 - p)
+	}
+	return info
+}
+
+func maxChange(before, after []float64) float64 {
+	change := 0.0
+	for i := range before {
+		change = math.Max(change, math.Abs(after[i]-before[i]))
+	}
+	return change
+}
+
+func standardError(info float64) float64 {
+	if info <= 0 {
+		return 0
+	}
+	return 1 / math.Sqrt(info)
+}
+
+func clampStep(step float64) float64 {
+	return math.Max(-maxStep, math.Min(maxStep, step))
+}
+
+func clampLogit(value float64) float64 {
+	return math.Max(-maxLogit, math.Min(maxLogit, value))
+}
+
+// DifficultyLevel 将Rasch难度换算为1-5的难度等级，以题目难度均值0为中等（3级），每级宽1个logit
+func DifficultyLevel(difficulty float64) int {
+	level := int(math.Floor(difficulty+0.5)) + 3
+	if level < 1 {
+		return 1
+	}
+	if level > 5 {
+		return 5
+	}
+	return level
+}
@@ -0,0 +1,593 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"testogo/internal/analysis"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// calibrationBatchSize 保存校准结果和接受校准时每批处理的数量
+const calibrationBatchSize = 500
+
+// calibrationSlot 同一时间只运行一个校准任务
+var calibrationSlot = make(chan struct{}, 1)
+
+// startCalibrationRun 在后台运行难度校准
+func startCalibrationRun(runID uint) {
+	go runCalibration(runID)
+}
+
+// runCalibration 读取答题记录并估计题目难度和学生能力，只处理排队中的任务
+func runCalibration(runID uint) {
+	calibrationSlot <- struct{}{}
+	defer func() { <-calibrationSlot }()
+	defer func() {
+		if r := recover(); r != nil {
+			failCalibrationRun(runID, fmt.Sprintf("难度校准失败: %v", r))
+		}
+	}()
+
+	result := database.DB.Model(&entity.CalibrationRun{}).
+		Where("id = ? AND status = ?", runID, entity.CalibrationQueued).
+		Update("status", entity.CalibrationRunning)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	observations, err := loadCalibrationObservations()
+	if err != nil {
+		failCalibrationRun(runID, fmt.Sprintf("读取答题记录失败: %v", err))
+		return
+	}
+	fit := analysis.FitRasch(observations, analysis.RaschConfig{})
+	if err := saveCalibrationResult(runID, fit); err != nil {
+		failCalibrationRun(runID, fmt.Sprintf("保存校准结果失败: %v", err))
+	}
+}
+
+// calibrationAnswer 单题练习、试卷和作业中的一条作答
+type calibrationAnswer struct {
+	UserID     uint
+	QuestionID uint
+	IsCorrect  bool
+	HintsUsed  int
+	CreatedAt  time.Time
+}
+
+// loadCalibrationObservations 每名学生对每道未删除题目的首次作答，首次作答查看过提示时不计入
+// 之后的作答受到首次作答和答案解析的影响，不能反映题目本身的难度
+func loadCalibrationObservations() ([]analysis.Observation, error) {
+	var answers, homeworkAnswers []calibrationAnswer
+	if err := database.DB.Model(&entity.UserAnswer{}).
+		Select("user_id, question_id, is_correct, hints_used, created_at").
+		Scan(&answers).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&entity.HomeworkQuestionAnswer{}).
+		Select("homework_submission.student_id AS user_id, homework_question_answer.question_id, " +
+			"homework_question_answer.is_correct, homework_question_answer.hints_used, homework_question_answer.created_at").
+		Joins("JOIN homework_submission ON homework_submission.id = homework_question_answer.submission_id").
+		Scan(&homeworkAnswers).Error; err != nil {
+		return nil, err
+	}
+
+	var questionIDs []uint
+	if err := database.DB.Model(&entity.Question{}).Pluck("id", &questionIDs).Error; err != nil {
+		return nil, err
+	}
+	exists := make(map[uint]bool, len(questionIDs))
+	for _, id := range questionIDs {
+		exists[id] = true
+	}
+
+	type pair struct{ userID, questionID uint }
+	first := make(map[pair]calibrationAnswer)
+	for _, answer := range append(answers, homeworkAnswers...) {
+		if !exists[answer.QuestionID] {
+			continue
+		}
+		key := pair{answer.UserID, answer.QuestionID}
+		if earlier, ok := first[key]; !ok || answer.CreatedAt.Before(earlier.CreatedAt) {
+			first[key] = answer
+		}
+	}
+
+	observations := make([]analysis.Observation, 0, len(first))
+	for key, answer := range first {
+		if answer.HintsUsed > 0 {
+			continue
+		}
+		observations = append(observations, analysis.Observation{
+			PersonID: key.userID,
+			ItemID:   key.questionID,
+			Correct:  answer.IsCorrect,
+		})
+	}
+	return observations, nil
+}
+
+// saveCalibrationResult 保存估计结果，并把最新的校准难度写到题目上
+// 本次没有参与校准的题目清除上次的校准结果，避免与新的量尺混用
+func saveCalibrationResult(runID uint, fit analysis.RaschResult) error {
+	ids := make([]uint, len(fit.Items))
+	for i, item := range fit.Items {
+		ids[i] = item.ItemID
+	}
+	authored := make(map[uint]int, len(ids))
+	if len(ids) > 0 {
+		var questions []entity.Question
+		if err := database.DB.Select("id, difficulty").Where("id IN ?", ids).Find(&questions).Error; err != nil {
+			return err
+		}
+		for _, question := range questions {
+			authored[question.ID] = question.Difficulty
+		}
+	}
+
+	items := make([]entity.ItemCalibration, 0, len(fit.Items))
+	for _, item := range fit.Items {
+		items = append(items, entity.ItemCalibration{
+			RunID:               runID,
+			QuestionID:          item.ItemID,
+			Difficulty:          item.Difficulty,
+			StdError:            item.StdError,
+			Responses:           item.Responses,
+			CorrectCount:        item.Correct,
+			Extreme:             item.Extreme,
+			AuthoredDifficulty:  authored[item.ItemID],
+			SuggestedDifficulty: analysis.DifficultyLevel(item.Difficulty),
+		})
+	}
+	learners := make([]entity.LearnerAbility, 0, len(fit.Persons))
+	for _, person := range fit.Persons {
+		learners = append(learners, entity.LearnerAbility{
+			RunID:        runID,
+			UserID:       person.PersonID,
+			Ability:      person.Ability,
+			StdError:     person.StdError,
+			Responses:    person.Responses,
+			CorrectCount: person.Correct,
+			Extreme:      person.Extreme,
+		})
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// 服务重启后重新运行时先删除上次保存的结果
+		if err := tx.Where("run_id = ?", runID).Delete(&entity.ItemCalibration{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("run_id = ?", runID).Delete(&entity.LearnerAbility{}).Error; err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			if err := tx.CreateInBatches(items, calibrationBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(learners) > 0 {
+			if err := tx.CreateInBatches(learners, calibrationBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&entity.Question{}).Where("calibration_run_id IS NOT NULL").
+			UpdateColumns(map[string]interface{}{
+				"calibrated_difficulty": nil,
+				"suggested_difficulty":  0,
+				"calibration_run_id":    nil,
+			}).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := tx.Model(&entity.Question{}).Where("id = ?", item.QuestionID).
+				UpdateColumns(map[string]interface{}{
+					"calibrated_difficulty": item.Difficulty,
+					"suggested_difficulty":  item.SuggestedDifficulty,
+					"calibration_run_id":    runID,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		result := tx.Model(&entity.CalibrationRun{}).
+			Where("id = ? AND status = ?", runID, entity.CalibrationRunning).
+			Updates(map[string]interface{}{
+				"status":            entity.CalibrationCompleted,
+				"observation_count": fit.Observations,
+				"item_count":        len(items),
+				"learner_count":     len(learners),
+				"iterations":        fit.Iterations,
+				"converged":         fit.Converged,
+				"completed_at":      &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// failCalibrationRun 将任务标记为失败
+func failCalibrationRun(runID uint, message string) {
+	database.DB.Model(&entity.CalibrationRun{}).Where("id = ?", runID).Updates(map[string]interface{}{
+		"status":        entity.CalibrationFailed,
+		"error_message": message,
+	})
+}
+
+// ResumeCalibrationRuns 服务启动时重新运行上次未完成的校准任务
+func ResumeCalibrationRuns() {
+	if database.DB == nil {
+		return
+	}
+	if err := database.DB.Model(&entity.CalibrationRun{}).Where("status = ?", entity.CalibrationRunning).
+		Update("status", entity.CalibrationQueued).Error; err != nil {
+		log.Printf("恢复难度校准任务失败: %v", err)
+		return
+	}
+	var queued []uint
+	if err := database.DB.Model(&entity.CalibrationRun{}).Where("status = ?", entity.CalibrationQueued).
+		Order("id").Pluck("id", &queued).Error; err != nil {
+		log.Printf("恢复难度校准任务失败: %v", err)
+		return
+	}
+	for _, id := range queued {
+		startCalibrationRun(id)
+	}
+}
+
+// @Summary 开始难度校准
+// @Description 在后台用Rasch模型按答题记录估计题目难度和学生能力，只使用每名学生对每道题的首次未查看提示的作答；
+// @Description 同一时间只能有一个校准任务，完成后题目的 calibrated_difficulty 和 suggested_difficulty 更新为本次结果
+// @Tags 难度校准
+// @Produce json
+// @Security BasicAuth
+// @Success 202 {object} map[string]interface{} "校准任务"
+// @Failure 409 {object} map[string]interface{} "已有正在进行的校准任务"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/calibrations [post]
+func StartCalibration(c *gin.Context) {
+	var running entity.CalibrationRun
+	err := database.DB.Where("status IN ?", []entity.CalibrationStatus{entity.CalibrationQueued, entity.CalibrationRunning}).
+		First(&running).Error
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "已有正在进行的难度校准", "run": running})
+		return
+	}
+	if err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建难度校准失败"})
+		return
+	}
+
+	run := entity.CalibrationRun{
+		CreatorID: c.GetUint("userID"),
+		Status:    entity.CalibrationQueued,
+	}
+	if err := database.DB.Create(&run).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建难度校准失败"})
+		return
+	}
+	startCalibrationRun(run.ID)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "难度校准已开始",
+		"run":     run,
+	})
+}
+
+// @Summary 难度校准列表
+// @Tags 难度校准
+// @Produce json
+// @Security BasicAuth
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "校准任务列表"
+// @Router /api/v1/calibrations [get]
+func ListCalibrations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	var total int64
+	if err := database.DB.Model(&entity.CalibrationRun{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取难度校准失败"})
+		return
+	}
+	var runs []entity.CalibrationRun
+	if err := database.DB.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取难度校准失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"items": runs,
+	})
+}
+
+// @Summary 难度校准详情
+// @Description 返回任务状态，以及建议难度与题目当前难度不同、尚未接受的题目数
+// @Tags 难度校准
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "校准任务ID"
+// @Success 200 {object} map[string]interface{} "校准任务"
+// @Failure 404 {object} map[string]interface{} "校准任务不存在"
+// @Router /api/v1/calibrations/{id} [get]
+func GetCalibration(c *gin.Context) {
+	run, ok := findCalibrationRun(c)
+	if !ok {
+		return
+	}
+
+	var changed, accepted int64
+	if err := calibrationItemsQuery(run.ID, true).Where("item_calibration.accepted_at IS NULL").
+		Count(&changed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取难度校准失败"})
+		return
+	}
+	database.DB.Model(&entity.ItemCalibration{}).Where("run_id = ? AND accepted_at IS NOT NULL", run.ID).Count(&accepted)
+
+	c.JSON(http.StatusOK, gin.H{
+		"run":            run,
+		"changed_count":  changed,
+		"accepted_count": accepted,
+	})
+}
+
+// @Summary 难度校准的题目结果
+// @Description 返回每道题的Rasch难度、建议难度和当前难度，按Rasch难度从易到难排序
+// @Tags 难度校准
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "校准任务ID"
+// @Param changed query bool false "只返回建议难度与当前难度不同的题目"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "题目结果列表"
+// @Failure 404 {object} map[string]interface{} "校准任务不存在"
+// @Router /api/v1/calibrations/{id}/items [get]
+func ListCalibrationItems(c *gin.Context) {
+	run, ok := findCalibrationRun(c)
+	if !ok {
+		return
+	}
+	changedOnly, _ := strconv.ParseBool(c.Query("changed"))
+	query := calibrationItemsQuery(run.ID, changedOnly)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取校准结果失败"})
+		return
+	}
+	var rows []struct {
+		entity.ItemCalibration
+		Title             string
+		Type              string
+		CurrentDifficulty int
+	}
+	if err := query.Select("item_calibration.*, question.title, question.type, question.difficulty AS current_difficulty").
+		Order("item_calibration.difficulty, item_calibration.id").
+		Offset((page - 1) * pageSize).Limit(pageSize).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取校准结果失败"})
+		return
+	}
+
+	items := make([]response.CalibrationItemResponse, len(rows))
+	for i, row := range rows {
+		items[i] = response.CalibrationItemResponse{
+			ItemCalibration:   row.ItemCalibration,
+			Title:             row.Title,
+			Type:              row.Type,
+			CurrentDifficulty: row.CurrentDifficulty,
+			Changed:           row.SuggestedDifficulty != row.CurrentDifficulty,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"items": items,
+	})
+}
+
+// @Summary 难度校准的学生能力
+// @Description 返回每名学生的能力估计，与题目难度在同一量尺上，按能力从高到低排序
+// @Tags 难度校准
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "校准任务ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "学生能力列表"
+// @Failure 404 {object} map[string]interface{} "校准任务不存在"
+// @Router /api/v1/calibrations/{id}/learners [get]
+func ListCalibrationLearners(c *gin.Context) {
+	run, ok := findCalibrationRun(c)
+	if !ok {
+		return
+	}
+	query := database.DB.Model(&entity.LearnerAbility{}).Where("run_id = ?", run.ID)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取学生能力失败"})
+		return
+	}
+	var learners []entity.LearnerAbility
+	if err := query.Preload("User").Order("ability desc, id").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&learners).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取学生能力失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"items": learners,
+	})
+}
+
+// @Summary 接受难度校准
+// @Description 将题目难度批量改为校准建议的难度，每道修改的题目生成一个新版本；只能接受最近一次完成的校准
+// @Description 只修改当前用户有修改权限的题目，其余题目跳过并在 question_ids 中返回
+// @Tags 难度校准
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "校准任务ID"
+// @Param request body request.AcceptCalibrationRequest false "接受的题目，为空时接受全部建议"
+// @Success 200 {object} map[string]interface{} "修改的题目数和跳过的题目"
+// @Failure 404 {object} map[string]interface{} "校准任务不存在"
+// @Failure 409 {object} map[string]interface{} "校准未完成或不是最近一次校准"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/calibrations/{id}/accept [post]
+func AcceptCalibration(c *gin.Context) {
+	run, ok := findCalibrationRun(c)
+	if !ok {
+		return
+	}
+	var req request.AcceptCalibrationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if run.Status != entity.CalibrationCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "难度校准尚未完成"})
+		return
+	}
+	var latest entity.CalibrationRun
+	if err := database.DB.Where("status = ?", entity.CalibrationCompleted).Order("id desc").First(&latest).Error; err != nil || latest.ID != run.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "只能接受最近一次难度校准的结果"})
+		return
+	}
+
+	query := calibrationItemsQuery(run.ID, true)
+	if len(req.QuestionIDs) > 0 {
+		query = query.Where("item_calibration.question_id IN ?", req.QuestionIDs)
+	} else if !req.IncludeExtreme {
+		query = query.Where("item_calibration.extreme = ?", false)
+	}
+	var items []entity.ItemCalibration
+	if err := query.Select("item_calibration.*").Order("item_calibration.id").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取校准结果失败"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	comment := fmt.Sprintf("接受难度校准（第%d次校准）", run.ID)
+	accepted := 0
+	var denied []uint
+	for start := 0; start < len(items); start += calibrationBatchSize {
+		end := start + calibrationBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		updated := 0
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			for i := range items[start:end] {
+				item := &items[start+i]
+				var question entity.Question
+				if err := tx.First(&question, item.QuestionID).Error; err != nil {
+					continue
+				}
+				// 只修改当前用户有修改权限的题目，其余题目跳过
+				ok, err := hasAccess(c, questionResource(&question), accessEdit)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					denied = append(denied, question.ID)
+					continue
+				}
+				if err := ensureBaselineRevision(tx, &question); err != nil {
+					return err
+				}
+				if err := tx.Model(&question).Update("difficulty", item.SuggestedDifficulty).Error; err != nil {
+					return err
+				}
+				if err := recordQuestionRevision(tx, &question, userID, comment); err != nil {
+					return err
+				}
+				if err := tx.Model(item).Update("accepted_at", time.Now()).Error; err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "接受难度校准失败", "accepted": accepted})
+			return
+		}
+		accepted += updated
+	}
+
+	if denied == nil {
+		denied = []uint{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "已更新题目难度",
+		"accepted":     accepted,
+		"skipped":      len(denied),
+		"question_ids": denied, // 没有修改权限而跳过的题目
+	})
+}
+
+// calibrationItemsQuery 校准结果关联题目的查询，changedOnly 时只包括建议难度与题目当前难度不同的题目
+func calibrationItemsQuery(runID uint, changedOnly bool) *gorm.DB {
+	query := database.DB.Model(&entity.ItemCalibration{}).
+		Joins("JOIN question ON question.id = item_calibration.question_id AND question.deleted_at IS NULL").
+		Where("item_calibration.run_id = ?", runID)
+	if changedOnly {
+		query = query.Where("item_calibration.suggested_difficulty <> question.difficulty")
+	}
+	return query
+}
+
+// findCalibrationRun 按路径参数查找校准任务，不存在时返回404
+func findCalibrationRun(c *gin.Context) (*entity.CalibrationRun, bool) {
+	var run entity.CalibrationRun
+	if err := database.DB.First(&run, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "难度校准不存在"})
+		return nil, false
+	}
+	return &run, true
+}
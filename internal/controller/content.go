@@ -320,11 +320,7 @@ func ListTopics(c *gin.Context) {
 		items[i] = convertToTopicResponse(&topic)
 		
 		// Get question count for each topic
-		var questionCount int64
-		database.DB.Model(&entity.Question{}).
-			Where("tags LIKE ?", "%"+topic.Code+"%").
-			Count(&questionCount)
-		items[i].QuestionCount = int(questionCount)
+		items[i].QuestionCount = int(countTopicQuestions(&topic))
 	}
 
 	c.JSON(http.StatusOK, items)
@@ -358,11 +354,7 @@ func GetTopic(c *gin.Context) {
 	resp := convertToTopicResponse(&topic)
 	
 	// Get question count
-	var questionCount int64
-	database.DB.Model(&entity.Question{}).
-		Where("tags LIKE ?", "%"+topic.Code+"%").
-		Count(&questionCount)
-	resp.QuestionCount = int(questionCount)
+	resp.QuestionCount = int(countTopicQuestions(&topic))
 
 	c.JSON(http.StatusOK, resp)
 }
@@ -403,15 +395,20 @@ func GetTopicByCode(c *gin.Context) {
 	resp := convertToTopicResponse(&topic)
 	
 	// Get question count
-	var questionCount int64
-	database.DB.Model(&entity.Question{}).
-		Where("tags LIKE ?", "%"+topic.Code+"%").
-		Count(&questionCount)
-	resp.QuestionCount = int(questionCount)
+	resp.QuestionCount = int(countTopicQuestions(&topic))
 
 	c.JSON(http.StatusOK, resp)
 }
 
+// countTopicQuestions counts questions assigned to the topic or tagged with exactly its code
+func countTopicQuestions(topic *entity.Topic) int64 {
+	var count int64
+	database.DB.Model(&entity.Question{}).
+		Where("topic_id = ? OR id IN (?)", topic.ID, taggedQuestionIDs([]string{topic.Code}, false)).
+		Count(&count)
+	return count
+}
+
 // Helper functions to convert entities to responses
 func convertToGradeResponse(grade *entity.Grade) response.GradeResponse {
 	return response.GradeResponse{
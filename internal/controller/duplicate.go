@@ -0,0 +1,430 @@
+package controller
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"testogo/internal/dedup"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/internal/search"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary 重复题目报告
+// @Description 按题干、选项、答案和媒体文件的相似度找出疑似重复的题目分组，并给出建议保留的题目
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param threshold query number false "相似度阈值（0-1），默认0.8"
+// @Param type query string false "只检查指定题型"
+// @Success 200 {object} map[string]interface{} "重复题目分组"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/duplicates [get]
+func ListDuplicateQuestions(c *gin.Context) {
+	threshold := dedup.DefaultThreshold
+	if value, err := strconv.ParseFloat(c.Query("threshold"), 64); err == nil && value > 0 && value <= 1 {
+		threshold = value
+	}
+
+	prints, questions, err := loadQuestionFingerprints()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找重复题目失败"})
+		return
+	}
+	byID := make(map[uint]*dedup.Fingerprint, len(prints))
+	var selected []*dedup.Fingerprint
+	for _, fp := range prints {
+		byID[fp.QuestionID] = fp
+		if qType := c.Query("type"); qType == "" || string(fp.Type) == qType {
+			selected = append(selected, fp)
+		}
+	}
+	clusters := dedup.NewIndex(selected).Clusters(threshold)
+
+	// 统计每道题的答题次数，用于选出建议保留的题目
+	var ids []uint
+	for _, cluster := range clusters {
+		ids = append(ids, cluster...)
+	}
+	answerCounts := make(map[uint]int64)
+	if len(ids) > 0 {
+		var rows []struct {
+			QuestionID uint
+			Count      int64
+		}
+		if err := database.DB.Model(&entity.UserAnswer{}).Select("question_id, COUNT(*) AS count").
+			Where("question_id IN ?", ids).Group("question_id").Scan(&rows).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查找重复题目失败"})
+			return
+		}
+		for _, row := range rows {
+			answerCounts[row.QuestionID] = row.Count
+		}
+	}
+
+	result := make([]response.DuplicateClusterResponse, 0, len(clusters))
+	for _, cluster := range clusters {
+		survivor := suggestSurvivor(cluster, questions, answerCounts)
+		item := response.DuplicateClusterResponse{SurvivorID: survivor}
+		for _, id := range cluster {
+			question := questions[id]
+			item.Questions = append(item.Questions, response.DuplicateQuestionResponse{
+				ID:          id,
+				Title:       question.Title,
+				Type:        string(question.Type),
+				Status:      string(question.Status),
+				AnswerCount: answerCounts[id],
+				Similarity:  math.Round(dedup.Similarity(byID[survivor], byID[id])*1000) / 1000,
+				CreatedAt:   question.CreatedAt,
+			})
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold": threshold,
+		"total":     len(result),
+		"clusters":  result,
+	})
+}
+
+// @Summary 合并重复题目
+// @Description 将重复题目的答题记录、提示查看记录、作业和试卷引用改为指向保留的题目，合并标签后删除重复题目；
+// @Description 重复题目的审核记录、修订历史、难度校准结果和共享设置随题目一起删除
+// @Tags 题目
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param request body request.MergeDuplicatesRequest true "保留的题目和要合并的重复题目"
+// @Param regrade query bool false "合并后在后台按保留题目的标准答案重新判分"
+// @Success 200 {object} map[string]interface{} "合并结果"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/duplicates/merge [post]
+func MergeDuplicateQuestions(c *gin.Context) {
+	var req request.MergeDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, id := range req.DuplicateIDs {
+		if id == req.SurvivorID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "保留的题目不能同时作为重复题目"})
+			return
+		}
+	}
+
+	var survivor entity.Question
+	if err := database.DB.First(&survivor, req.SurvivorID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "保留的题目不存在"})
+		return
+	}
+	var duplicates []entity.Question
+	if err := database.DB.Where("id IN ?", req.DuplicateIDs).Find(&duplicates).Error; err != nil || len(duplicates) != len(req.DuplicateIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "重复题目不存在"})
+		return
+	}
+
+	var summary struct {
+		UserAnswers       int64 `json:"user_answers"`
+		HomeworkAnswers   int64 `json:"homework_answers"`
+		HintReveals       int64 `json:"hint_reveals"`
+		HomeworkQuestions int   `json:"homework_questions"`
+		Papers            int   `json:"papers"`
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 答题记录改为指向保留的题目，原版本号属于被合并的题目，不再适用
+		result := tx.Model(&entity.UserAnswer{}).Where("question_id IN ?", req.DuplicateIDs).
+			Updates(map[string]interface{}{"question_id": survivor.ID, "question_revision": 0})
+		if result.Error != nil {
+			return result.Error
+		}
+		summary.UserAnswers = result.RowsAffected
+
+		result = tx.Model(&entity.HomeworkQuestionAnswer{}).Where("question_id IN ?", req.DuplicateIDs).
+			Updates(map[string]interface{}{"question_id": survivor.ID, "question_revision": 0})
+		if result.Error != nil {
+			return result.Error
+		}
+		summary.HomeworkAnswers = result.RowsAffected
+
+		// 已关联答题记录的提示查看记录随答题记录一起指向保留的题目，未关联的属于尚未提交的作答，直接删除
+		result = tx.Model(&entity.HintReveal{}).Where("question_id IN ? AND answer_id IS NOT NULL", req.DuplicateIDs).
+			Update("question_id", survivor.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		summary.HintReveals = result.RowsAffected
+		if err := tx.Where("question_id IN ? AND answer_id IS NULL", req.DuplicateIDs).
+			Delete(&entity.HintReveal{}).Error; err != nil {
+			return err
+		}
+
+		// 审核记录、修订历史和校准结果描述的是重复题目本身，不能转给保留的题目
+		for _, model := range []interface{}{&entity.QuestionReview{}, &entity.QuestionRevision{}, &entity.ItemCalibration{}} {
+			if err := tx.Where("question_id IN ?", req.DuplicateIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("resource_type = ? AND resource_id IN ?", entity.ShareQuestion, req.DuplicateIDs).
+			Delete(&entity.ResourceShare{}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if summary.HomeworkQuestions, err = mergeHomeworkQuestions(tx, survivor.ID, req.DuplicateIDs); err != nil {
+			return err
+		}
+		if summary.Papers, err = mergePaperQuestions(tx, survivor.ID, req.DuplicateIDs); err != nil {
+			return err
+		}
+
+		// 合并重复题目的标签
+		names := entity.ParseTagNames(survivor.Tags)
+		for _, duplicate := range duplicates {
+			names = append(names, entity.ParseTagNames(duplicate.Tags)...)
+		}
+		if tags := entity.JoinTagNames(entity.ParseTagNames(entity.JoinTagNames(names))); tags != survivor.Tags {
+			if err := ensureBaselineRevision(tx, &survivor); err != nil {
+				return err
+			}
+			survivor.Tags = tags
+			if err := tx.Model(&survivor).UpdateColumn("tags", tags).Error; err != nil {
+				return err
+			}
+			if err := recordQuestionRevision(tx, &survivor, c.GetUint("userID"), "合并重复题目"); err != nil {
+				return err
+			}
+		}
+
+		for _, duplicate := range duplicates {
+			if err := tx.Delete(&duplicate).Error; err != nil {
+				return err
+			}
+			if err := search.RemoveQuestion(tx, duplicate.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "合并重复题目失败"})
+		return
+	}
+
+	result := gin.H{"message": "合并成功", "survivor_id": survivor.ID, "merged": len(duplicates), "updated": summary}
+	if regrade, _ := strconv.ParseBool(c.Query("regrade")); regrade {
+		job, err := enqueueRegrade(survivor.ID, c.GetUint("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "题目已合并，但创建重新判分任务失败"})
+			return
+		}
+		result["regrade_job"] = job
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// mergeHomeworkQuestions 将作业中的重复题目替换为保留的题目
+// 同一作业同一天中已经有保留的题目（或多道重复题目）时只保留一条，返回修改或删除的记录数
+func mergeHomeworkQuestions(tx *gorm.DB, survivorID uint, duplicateIDs []uint) (int, error) {
+	var rows []entity.HomeworkQuestion
+	if err := tx.Where("question_id IN ?", append([]uint{survivorID}, duplicateIDs...)).
+		Order("homework_id, day_of_week, question_id = " + strconv.FormatUint(uint64(survivorID), 10) + " DESC, id").
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	kept := make(map[[2]uint]bool)
+	for _, row := range rows {
+		key := [2]uint{row.HomeworkID, uint(row.DayOfWeek)}
+		if kept[key] {
+			if err := tx.Delete(&entity.HomeworkQuestion{}, row.ID).Error; err != nil {
+				return 0, err
+			}
+			changed++
+			continue
+		}
+		kept[key] = true
+		if row.QuestionID != survivorID {
+			if err := tx.Model(&entity.HomeworkQuestion{}).Where("id = ?", row.ID).
+				UpdateColumn("question_id", survivorID).Error; err != nil {
+				return 0, err
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// mergePaperQuestions 将试卷题目列表中的重复题目替换为保留的题目，去除替换后重复出现的题目，返回修改的试卷数
+func mergePaperQuestions(tx *gorm.DB, survivorID uint, duplicateIDs []uint) (int, error) {
+	isDuplicate := make(map[uint]bool, len(duplicateIDs))
+	for _, id := range duplicateIDs {
+		isDuplicate[id] = true
+	}
+
+	var papers []entity.Paper
+	if err := tx.Select("id, questions").Find(&papers).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, paper := range papers {
+		var ids []uint
+		if err := json.Unmarshal([]byte(paper.Questions), &ids); err != nil {
+			continue
+		}
+
+		merged := make([]uint, 0, len(ids))
+		seen := make(map[uint]bool)
+		modified := false
+		for _, id := range ids {
+			if isDuplicate[id] {
+				id = survivorID
+				modified = true
+			}
+			if !seen[id] {
+				seen[id] = true
+				merged = append(merged, id)
+			}
+		}
+		if !modified {
+			continue
+		}
+
+		data, err := json.Marshal(merged)
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.Model(&entity.Paper{}).Where("id = ?", paper.ID).UpdateColumn("questions", string(data)).Error; err != nil {
+			return 0, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// suggestSurvivor 选出分组中建议保留的题目：优先已发布的题目，其次答题次数多的，最后是创建最早的
+func suggestSurvivor(ids []uint, questions map[uint]*entity.Question, answerCounts map[uint]int64) uint {
+	candidates := append([]uint(nil), ids...)
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := questions[candidates[i]], questions[candidates[j]]
+		if ap, bp := a.Status == entity.QuestionStatusPublished, b.Status == entity.QuestionStatusPublished; ap != bp {
+			return ap
+		}
+		if answerCounts[a.ID] != answerCounts[b.ID] {
+			return answerCounts[a.ID] > answerCounts[b.ID]
+		}
+		return a.ID < b.ID
+	})
+	return candidates[0]
+}
+
+// loadQuestionFingerprints 加载题库中所有题目的查重指纹，尚未计算指纹的旧题目会补算并保存
+func loadQuestionFingerprints() ([]*dedup.Fingerprint, map[uint]*entity.Question, error) {
+	var questions []entity.Question
+	if err := database.DB.
+		Select("id, title, type, options, answer, media_url, media_urls, status, content_hash, media_hash, created_at").
+		Find(&questions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	prints := make([]*dedup.Fingerprint, len(questions))
+	byID := make(map[uint]*entity.Question, len(questions))
+	for i := range questions {
+		question := &questions[i]
+		byID[question.ID] = question
+		if question.ContentHash != "" {
+			prints[i] = dedup.NewFingerprint(question, question.MediaHash)
+			continue
+		}
+		fp, err := updateQuestionFingerprint(database.DB, question)
+		if err != nil {
+			return nil, nil, err
+		}
+		prints[i] = fp
+	}
+	return prints, byID, nil
+}
+
+// findDuplicateMatches 在查重索引中查找与题目疑似重复的题目
+func findDuplicateMatches(ix *dedup.Index, fp *dedup.Fingerprint, questions map[uint]*entity.Question) []response.DuplicateMatchResponse {
+	var matches []response.DuplicateMatchResponse
+	for _, match := range ix.Find(fp, dedup.DefaultThreshold) {
+		item := response.DuplicateMatchResponse{
+			QuestionID: match.QuestionID,
+			Similarity: match.Similarity,
+			Exact:      match.Exact,
+		}
+		if question, ok := questions[match.QuestionID]; ok {
+			item.Title = question.Title
+		}
+		matches = append(matches, item)
+	}
+	return matches
+}
+
+// updateQuestionFingerprint 计算并保存题目的查重指纹
+func updateQuestionFingerprint(tx *gorm.DB, question *entity.Question) (*dedup.Fingerprint, error) {
+	fp := dedup.NewFingerprint(question, questionMediaHash(question))
+	if fp.ContentHash == question.ContentHash && fp.MediaHash == question.MediaHash {
+		return fp, nil
+	}
+	question.ContentHash, question.MediaHash = fp.ContentHash, fp.MediaHash
+	return fp, tx.Model(&entity.Question{}).Where("id = ?", question.ID).UpdateColumns(map[string]interface{}{
+		"content_hash": fp.ContentHash,
+		"media_hash":   fp.MediaHash,
+	}).Error
+}
+
+// questionMediaHash 计算题目媒体文件的哈希，本地上传的文件按内容计算，无法读取的文件和外部链接按地址计算
+func questionMediaHash(question *entity.Question) string {
+	var hashes []string
+	for _, url := range questionMediaURLs(question) {
+		if i := strings.LastIndex(url, "/media/"); i >= 0 {
+			name := filepath.Base(url[i+len("/media/"):])
+			if data, err := os.ReadFile(filepath.Join(UploadDir, name)); err == nil {
+				hashes = append(hashes, dedup.HashBytes(data))
+				continue
+			}
+		}
+		hashes = append(hashes, dedup.HashBytes([]byte(url)))
+	}
+	return dedup.MediaHash(hashes)
+}
+
+// questionMediaURLs 收集题目的媒体地址，MediaURLs 兼容JSON数组和逗号分隔两种格式
+func questionMediaURLs(question *entity.Question) []string {
+	var urls []string
+	if question.MediaURLs != "" {
+		if err := json.Unmarshal([]byte(question.MediaURLs), &urls); err != nil {
+			urls = strings.Split(question.MediaURLs, ",")
+		}
+	}
+	if question.MediaURL != "" {
+		urls = append(urls, question.MediaURL)
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, url := range urls {
+		if url = strings.TrimSpace(url); url != "" && !seen[url] {
+			seen[url] = true
+			result = append(result, url)
+		}
+	}
+	return result
+}
@@ -0,0 +1,366 @@
+package controller
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"testogo/internal/grading"
+	"testogo/internal/interop"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize 导出时每批从数据库读取的题目数
+const exportBatchSize = 500
+
+// questionExportColumns CSV/XLSX导出的列，前10列与Excel导入模板一致
+var questionExportColumns = []string{
+	"type", "title", "options", "answer", "grade", "subject", "topic", "difficulty", "tags", "explanation",
+	"media_urls", "layout_type", "element_data", "grading_config", "id", "status", "hints",
+}
+
+// exportContentTypes 各导出格式的文件类型
+var exportContentTypes = map[string]string{
+	"json":   "application/json",
+	"csv":    "text/csv; charset=utf-8",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"qti":    "application/zip",
+	"moodle": "application/xml",
+	"gift":   "text/plain; charset=utf-8",
+}
+
+// exportFileExtensions 文件扩展名与格式名不同的导出格式
+var exportFileExtensions = map[string]string{
+	"qti":    "zip",
+	"moodle": "xml",
+}
+
+// newInteropWriter 交换格式的写入器，不是交换格式时返回nil
+// 交换格式只支持单选、多选、判断和填空，无法转换的题目在导出文件中以注释说明
+func newInteropWriter(w io.Writer, format string) interop.Writer {
+	switch format {
+	case "qti":
+		return interop.NewQTIWriter(w)
+	case "moodle":
+		return interop.NewMoodleWriter(w)
+	case "gift":
+		return interop.NewGIFTWriter(w)
+	}
+	return nil
+}
+
+// @Summary 导出题目
+// @Description 按与题目列表相同的过滤条件导出题目；JSON格式可直接通过 /questions/import 导入，media=true 时打包为zip并附带本地媒体文件
+// @Description qti（QTI 2.1内容包）、moodle（Moodle XML）、gift 格式用于与其他平台交换题目，只包含单选、多选、判断和填空题，其余题目在文件中以注释说明；
+// @Description QTI和Moodle XML自带图片，GIFT不含图片，这三种格式忽略 media 参数
+// @Tags 题目
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/zip,application/xml,text/plain
+// @Security BasicAuth
+// @Param format query string false "导出格式：json（默认）、csv、xlsx、qti、moodle、gift"
+// @Param media query bool false "打包媒体文件"
+// @Param type query string false "题目类型"
+// @Param grade query string false "年级"
+// @Param subject query string false "科目"
+// @Param topic query string false "主题"
+// @Param tags query string false "标签过滤，多个标签用逗号分隔"
+// @Param q query string false "全文检索关键词"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/export [get]
+func ExportQuestions(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的导出格式，可选 json、csv、xlsx、qti、moodle、gift"})
+		return
+	}
+	withMedia, _ := strconv.ParseBool(c.Query("media"))
+	extension := format
+	if ext, ok := exportFileExtensions[format]; ok {
+		extension = ext
+	}
+	if withMedia && newInteropWriter(io.Discard, format) != nil {
+		withMedia = false
+	}
+
+	query, _, err := filterQuestions(c, database.DB.Model(&entity.Question{}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出题目失败"})
+		return
+	}
+
+	filename := "questions_" + time.Now().Format("20060102150405")
+	if !withMedia {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, extension))
+		if _, err := writeQuestionExport(c.Writer, format, query); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", filename))
+	zw := zip.NewWriter(c.Writer)
+	w, err := zw.Create("questions." + format)
+	if err == nil {
+		var mediaURLs []string
+		if mediaURLs, err = writeQuestionExport(w, format, query); err == nil {
+			err = writeExportMedia(zw, mediaURLs)
+		}
+	}
+	if err != nil {
+		c.Error(err)
+	}
+	zw.Close()
+}
+
+// writeQuestionExport 分批读取题目并写入导出文件，返回题目引用的媒体地址（已去重）
+func writeQuestionExport(w io.Writer, format string, query *gorm.DB) ([]string, error) {
+	var mediaURLs []string
+	seenMedia := make(map[string]bool)
+	collectMedia := func(question *entity.Question) {
+		for _, url := range questionMediaURLs(question) {
+			if !seenMedia[url] {
+				seenMedia[url] = true
+				mediaURLs = append(mediaURLs, url)
+			}
+		}
+	}
+
+	var writeRow func(question *entity.Question) error
+	var finish func() error
+	switch format {
+	case "json":
+		// 逐条写出JSON数组，避免一次性加载全部题目
+		if _, err := io.WriteString(w, "[\n"); err != nil {
+			return nil, err
+		}
+		first := true
+		writeRow = func(question *entity.Question) error {
+			data, err := json.Marshal(exportQuestion(question))
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ",\n"); err != nil {
+					return err
+				}
+			}
+			first = false
+			_, err = w.Write(data)
+			return err
+		}
+		finish = func() error {
+			_, err := io.WriteString(w, "\n]\n")
+			return err
+		}
+	case "csv":
+		// 写入UTF-8 BOM，Excel打开时中文不会乱码
+		if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+			return nil, err
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.Write(questionExportColumns); err != nil {
+			return nil, err
+		}
+		writeRow = func(question *entity.Question) error {
+			return cw.Write(exportRow(question))
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case "xlsx":
+		f := excelize.NewFile()
+		defer f.Close()
+		sheet := "questions"
+		if err := f.SetSheetName("Sheet1", sheet); err != nil {
+			return nil, err
+		}
+		sw, err := f.NewStreamWriter(sheet)
+		if err != nil {
+			return nil, err
+		}
+		row := 1
+		appendRow := func(values []string) error {
+			cells := make([]interface{}, len(values))
+			for i, value := range values {
+				cells[i] = value
+			}
+			cell, err := excelize.CoordinatesToCellName(1, row)
+			if err != nil {
+				return err
+			}
+			row++
+			return sw.SetRow(cell, cells)
+		}
+		if err := appendRow(questionExportColumns); err != nil {
+			return nil, err
+		}
+		writeRow = func(question *entity.Question) error {
+			return appendRow(exportRow(question))
+		}
+		finish = func() error {
+			if err := sw.Flush(); err != nil {
+				return err
+			}
+			_, err := f.WriteTo(w)
+			return err
+		}
+	default:
+		iw := newInteropWriter(w, format)
+		if iw == nil {
+			return nil, fmt.Errorf("unsupported export format: %s", format)
+		}
+		writeRow = func(question *entity.Question) error {
+			return iw.Write(question, loadInteropMedia(question))
+		}
+		finish = iw.Close
+	}
+
+	var batch []entity.Question
+	result := query.FindInBatches(&batch, exportBatchSize, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := writeRow(&batch[i]); err != nil {
+				return err
+			}
+			collectMedia(&batch[i])
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return mediaURLs, finish()
+}
+
+// writeExportMedia 将本地上传的媒体文件打包到 media/ 目录，外部链接和已丢失的文件跳过
+func writeExportMedia(zw *zip.Writer, urls []string) error {
+	for _, url := range urls {
+		i := strings.LastIndex(url, "/media/")
+		if i < 0 {
+			continue
+		}
+		name := filepath.Base(url[i+len("/media/"):])
+		data, err := os.ReadFile(filepath.Join(UploadDir, name))
+		if err != nil {
+			continue
+		}
+		w, err := zw.Create("media/" + name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadInteropMedia 读取题目引用的本地媒体文件，外部链接和已丢失的文件只保留地址
+func loadInteropMedia(question *entity.Question) []interop.Media {
+	var media []interop.Media
+	for _, url := range questionMediaURLs(question) {
+		m := interop.Media{Name: filepath.Base(url), URL: url}
+		if i := strings.LastIndex(url, "/media/"); i >= 0 {
+			m.Name = filepath.Base(url[i+len("/media/"):])
+			if data, err := os.ReadFile(filepath.Join(UploadDir, m.Name)); err == nil {
+				m.Data = data
+			}
+		}
+		media = append(media, m)
+	}
+	return media
+}
+
+// exportQuestion 转换为导入时使用的请求格式，科目和主题只导出代码，ID在不同环境中不通用
+func exportQuestion(question *entity.Question) request.CreateQuestionRequest {
+	item := request.CreateQuestionRequest{
+		Title:       question.Title,
+		Type:        string(question.Type),
+		Difficulty:  question.Difficulty,
+		Grade:       question.Grade,
+		Subject:     question.Subject,
+		Topic:       question.Topic,
+		Options:     question.Options,
+		Answer:      question.Answer,
+		Explanation: question.Explanation,
+		MediaURLs:   question.MediaURLs,
+		LayoutType:  question.LayoutType,
+		ElementData: question.ElementData,
+		Tags:        question.Tags,
+		Hints:       question.Hints,
+	}
+	if item.MediaURLs == "" && question.MediaURL != "" {
+		data, _ := json.Marshal([]string{question.MediaURL})
+		item.MediaURLs = string(data)
+	}
+	if question.GradingConfig != "" {
+		config := grading.ParseGradingConfig(question.GradingConfig)
+		item.GradingConfig = &config
+	}
+	return item
+}
+
+// exportRow 生成CSV/XLSX的一行，列顺序与 questionExportColumns 一致
+func exportRow(question *entity.Question) []string {
+	item := exportQuestion(question)
+	return []string{
+		item.Type,
+		item.Title,
+		exportOptions(item.Options),
+		item.Answer,
+		item.Grade,
+		item.Subject,
+		item.Topic,
+		strconv.Itoa(item.Difficulty),
+		item.Tags,
+		item.Explanation,
+		item.MediaURLs,
+		item.LayoutType,
+		item.ElementData,
+		question.GradingConfig,
+		strconv.FormatUint(uint64(question.ID), 10),
+		string(question.Status),
+		item.Hints,
+	}
+}
+
+// exportOptions 纯文字选项导出为用 | 分隔的文本，便于在表格中编辑；含图片等结构化选项或选项中含有 | 时保留JSON
+func exportOptions(options string) string {
+	// 带图片的选项无法用文字表示，保留JSON
+	var structured []entity.QuestionOption
+	if err := json.Unmarshal([]byte(options), &structured); err == nil {
+		for _, option := range structured {
+			if option.ImageURL != "" {
+				return options
+			}
+		}
+	}
+	texts := grading.ParseOptionTexts(options)
+	if texts == nil {
+		return options
+	}
+	for _, text := range texts {
+		if strings.Contains(text, "|") {
+			return options
+		}
+	}
+	return strings.Join(texts, "|")
+}
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"testogo/internal/grading"
 	"testogo/internal/model/entity"
 	"testogo/internal/model/request"
 	"testogo/internal/model/response"
@@ -27,6 +29,59 @@ func CreateHomework(c *gin.Context) {
 	// Get current user (teacher/admin)
 	userID := c.GetUint("userID")
 
+	// Only published questions can be assigned as homework
+	questionIDs := make([]uint, len(req.Questions))
+	for i, question := range req.Questions {
+		questionIDs[i] = question.QuestionID
+	}
+	unpublished, err := unpublishedQuestionIDs(questionIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: "Failed to check question status",
+		})
+		return
+	}
+	if len(unpublished) > 0 {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Only published questions can be assigned, unpublished or missing: %v", unpublished),
+		})
+		return
+	}
+
+	// Teachers can only use questions and reinforcement settings they have access to
+	denied, err := inaccessibleIDs(c, &entity.Question{}, entity.ShareQuestion, questionIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: "Failed to check permissions",
+		})
+		return
+	}
+	if len(denied) > 0 {
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Error: fmt.Sprintf("No access to questions: %v", denied),
+		})
+		return
+	}
+	var settingIDs []uint
+	for _, assignment := range req.StudentAssignments {
+		if assignment.ReinforcementSettingID != nil {
+			settingIDs = append(settingIDs, *assignment.ReinforcementSettingID)
+		}
+	}
+	denied, err = inaccessibleIDs(c, &entity.ReinforcementSetting{}, entity.ShareReinforcementSetting, settingIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: "Failed to check permissions",
+		})
+		return
+	}
+	if len(denied) > 0 {
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Error: fmt.Sprintf("No access to reinforcement settings: %v", denied),
+		})
+		return
+	}
+
 	// Start transaction
 	tx := database.DB.Begin()
 	defer func() {
@@ -131,7 +186,7 @@ func ListHomework(c *gin.Context) {
 
 	// Get current user info
 	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
+	userRole := c.GetString("role")
 
 	// Build query
 	query := database.DB.Model(&entity.Homework{}).Preload("Creator")
@@ -139,17 +194,11 @@ func ListHomework(c *gin.Context) {
 	// Apply role-based filtering
 	if userRole == "user" { // Student
 		// Only show homework assigned to this student
-		query = query.Joins("JOIN homework_assignments ON homework_assignments.homework_id = homework.id").
-			Where("homework_assignments.student_id = ?", userID)
+		query = query.Where("id IN (?)", database.DB.Model(&entity.HomeworkAssignment{}).
+			Select("homework_id").Where("student_id = ?", userID))
 	} else if userRole == "teacher" {
-		// Teachers see only their own homework unless AdminView is specified
-		if !req.AdminView {
-			query = query.Where("creator_id = ?", userID)
-		}
-		// If AdminView is true but user is teacher, still restrict to own homework
-		if req.AdminView {
-			query = query.Where("creator_id = ?", userID)
-		}
+		// Teachers see their own homework and homework shared with them or the whole school
+		query = scopeVisible(c, query, entity.ShareHomework)
 	} else if userRole == "admin" {
 		// Admin can see all homework when AdminView is true, otherwise their own
 		if !req.AdminView {
@@ -250,7 +299,7 @@ func GetHomework(c *gin.Context) {
 
 	// Check access permissions
 	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
+	userRole := c.GetString("role")
 	
 	if userRole == "user" {
 		// Check if student is assigned to this homework
@@ -267,10 +316,7 @@ func GetHomework(c *gin.Context) {
 			})
 			return
 		}
-	} else if userRole == "teacher" && homework.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	} else if userRole == "teacher" && !checkAccess(c, homeworkResource(&homework), accessView) {
 		return
 	}
 	// Admin has access to all homework
@@ -302,10 +348,6 @@ func UpdateHomework(c *gin.Context) {
 		return
 	}
 
-	// Get current user
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
 	// Find homework
 	var homework entity.Homework
 	if err := database.DB.First(&homework, homeworkID).Error; err != nil {
@@ -322,13 +364,9 @@ func UpdateHomework(c *gin.Context) {
 	}
 
 	// Check permissions
-	if userRole == "teacher" && homework.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if !checkAccess(c, homeworkResource(&homework), accessEdit) {
 		return
 	}
-	// Admin has access to all homework
 
 	// Update fields
 	updates := make(map[string]interface{})
@@ -379,10 +417,6 @@ func DeleteHomework(c *gin.Context) {
 		return
 	}
 
-	// Get current user
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
 	// Find homework
 	var homework entity.Homework
 	if err := database.DB.First(&homework, homeworkID).Error; err != nil {
@@ -399,13 +433,9 @@ func DeleteHomework(c *gin.Context) {
 	}
 
 	// Check permissions
-	if userRole == "teacher" && homework.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if !checkAccess(c, homeworkResource(&homework), accessOwner) {
 		return
 	}
-	// Admin has access to all homework
 
 	// Soft delete
 	if err := database.DB.Delete(&homework).Error; err != nil {
@@ -457,6 +487,11 @@ func CopyHomework(c *gin.Context) {
 		return
 	}
 
+	// Check permissions, copying requires view access to the source homework
+	if !checkAccess(c, homeworkResource(&sourceHomework), accessView) {
+		return
+	}
+
 	// Start transaction
 	tx := database.DB.Begin()
 	defer func() {
@@ -487,9 +522,29 @@ func CopyHomework(c *gin.Context) {
 		return
 	}
 
-	// Copy questions if requested
+	// Copy questions if requested, skipping questions that are no longer published
 	if req.CopyQuestions {
+		sourceIDs := make([]uint, len(sourceHomework.HomeworkQuestions))
+		for i, question := range sourceHomework.HomeworkQuestions {
+			sourceIDs[i] = question.QuestionID
+		}
+		unpublished, err := unpublishedQuestionIDs(sourceIDs)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Error: "Failed to check question status",
+			})
+			return
+		}
+		skip := make(map[uint]bool, len(unpublished))
+		for _, id := range unpublished {
+			skip[id] = true
+		}
+
 		for _, question := range sourceHomework.HomeworkQuestions {
+			if skip[question.QuestionID] {
+				continue
+			}
 			newQuestion := entity.HomeworkQuestion{
 				HomeworkID: newHomework.ID,
 				QuestionID: question.QuestionID,
@@ -613,6 +668,8 @@ func SubmitHomework(c *gin.Context) {
 	// Calculate score and correctness
 	totalQuestions := len(req.QuestionAnswers)
 	correctAnswers := 0
+	earnedScore := 0.0
+	results := make([]response.GradedAnswerResponse, 0, totalQuestions)
 	
 	// Create submission record
 	submission := entity.HomeworkSubmission{
@@ -640,32 +697,54 @@ func SubmitHomework(c *gin.Context) {
 			continue
 		}
 
-		// Check if answer is correct (simplified - real logic would be more complex)
-		isCorrect := answer.Answer == question.Answer
-		if isCorrect {
+		// Grade with the same engine used for practice and papers
+		answerText := submittedAnswer(answer.Answer, answer.Blanks)
+		result := grading.Grade(&question, answerText)
+		if result.IsCorrect {
 			correctAnswers++
 		}
+		earnedScore += result.Score
 
 		// Create answer record
 		questionAnswer := entity.HomeworkQuestionAnswer{
 			SubmissionID: submission.ID,
 			QuestionID:   answer.QuestionID,
-			Answer:       answer.Answer,
-			IsCorrect:    isCorrect,
+			Answer:       answerText,
+			IsCorrect:    result.IsCorrect,
+			Score:        result.Points,
 			TimeSpent:    answer.TimeSpent,
+
+			QuestionRevision: question.Revision,
 		}
 
-		if err := tx.Create(&questionAnswer).Error; err != nil {
+		// Link hints revealed while answering to this answer record
+		hints := hintScope{UserID: userID, QuestionID: question.ID, Context: entity.HintContextHomework, ContextID: req.HomeworkID}
+		used, err := hints.hintsUsed(tx)
+		if err == nil {
+			questionAnswer.HintsUsed = used
+			err = tx.Create(&questionAnswer).Error
+		}
+		if err == nil {
+			err = hints.attach(tx, questionAnswer.ID)
+		}
+		if err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 				Error: "Failed to save answer",
 			})
 			return
 		}
+
+		graded := convertToGradedAnswerResponse(answer.QuestionID, result)
+		graded.HintsUsed = used
+		results = append(results, graded)
 	}
 
-	// Update submission with final score
-	score := int(float64(correctAnswers) / float64(totalQuestions) * 100)
+	// Update submission with final score (partial credit counts towards the score)
+	score := 0
+	if totalQuestions > 0 {
+		score = int(earnedScore / float64(totalQuestions) * 100)
+	}
 	if err := tx.Model(&submission).Updates(map[string]interface{}{
 		"questions_correct": correctAnswers,
 		"score":            score,
@@ -719,6 +798,7 @@ func SubmitHomework(c *gin.Context) {
 			"correct_answers":   correctAnswers,
 			"total_questions":   totalQuestions,
 			"submission_id":     submission.ID,
+			"results":           results,
 		},
 	})
 }
@@ -829,9 +909,6 @@ func GetHomeworkSubmissions(c *gin.Context) {
 	}
 
 	// Verify homework exists and user has access
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-	
 	var homework entity.Homework
 	if err := database.DB.First(&homework, homeworkID).Error; err != nil {
 		c.JSON(http.StatusNotFound, response.ErrorResponse{
@@ -841,13 +918,9 @@ func GetHomeworkSubmissions(c *gin.Context) {
 	}
 
 	// Check permissions
-	if userRole == "teacher" && homework.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if !checkAccess(c, homeworkResource(&homework), accessView) {
 		return
 	}
-	// Admin has access to all homework
 
 	// Build query
 	query := database.DB.Model(&entity.HomeworkSubmission{}).
@@ -933,7 +1006,6 @@ func AdjustHomework(c *gin.Context) {
 
 	// Get current user
 	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
 
 	// Verify homework exists and user has access
 	var homework entity.Homework
@@ -945,13 +1017,9 @@ func AdjustHomework(c *gin.Context) {
 	}
 
 	// Check permissions
-	if userRole == "teacher" && homework.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if !checkAccess(c, homeworkResource(&homework), accessEdit) {
 		return
 	}
-	// Admin has access to all homework
 
 	// Serialize changes
 	changesJSON, _ := json.Marshal(req.Changes)
@@ -982,18 +1050,12 @@ func AdjustHomework(c *gin.Context) {
 
 // GetHomeworkHistory retrieves homework history for copying
 func GetHomeworkHistory(c *gin.Context) {
-	// Get current user
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
 	// Build query
 	query := database.DB.Model(&entity.Homework{}).
 		Preload("Creator")
 
-	// Apply role-based filtering
-	if userRole == "teacher" {
-		query = query.Where("creator_id = ?", userID)
-	}
+	// Apply role-based filtering, teachers can copy homework shared with them or the whole school
+	query = scopeVisible(c, query, entity.ShareHomework)
 	// Admin can see all homework
 
 	// Get recent homework for copying
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -8,8 +9,12 @@ import (
 	"strings"
 	"time"
 
+	"testogo/internal/dedup"
+	"testogo/internal/grading"
 	"testogo/internal/model/entity"
 	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/internal/payload"
 	"testogo/pkg/database"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +34,10 @@ var importAllowedExtensions = map[string]bool{
 	".xls":  true,
 	".docx": true,
 	".doc":  true,
+	".zip":  true,
+	".xml":  true,
+	".gift": true,
+	".txt":  true,
 }
 
 // ImportQuestionResponse 导入题目响应
@@ -44,10 +53,34 @@ type ImportQuestionResponse struct {
 	Status        string   `json:"status"`    // pending, approved, rejected
 	ErrorMessage  string   `json:"error_message,omitempty"`
 	OriginalIndex int      `json:"original_index"` // 在原文件中的位置
+
+	// 题目属性，确认导入时随题目一起提交
+	Difficulty  int    `json:"difficulty"`
+	Grade       string `json:"grade"`
+	Subject     string `json:"subject"`
+	Topic       string `json:"topic"`
+	Tags        string `json:"tags"`
+	Explanation string `json:"explanation"`
+	LayoutType  string `json:"layout_type,omitempty"`
+	ElementData string `json:"element_data,omitempty"`
+
+	// 识别过程中发现的问题，需要老师核对
+	Warnings []string `json:"warnings,omitempty"`
+	// 题目所在的页码，仅PDF导入
+	Page int `json:"page,omitempty"`
+	// 导入任务中已写入题库的题目ID
+	QuestionID *uint `json:"question_id,omitempty"`
+	// 填空题的其他可接受答案，仅QTI、Moodle XML和GIFT导入
+	GradingConfig *entity.GradingConfig `json:"grading_config,omitempty"`
+
+	// 题库中疑似重复的题目
+	Duplicates []response.DuplicateMatchResponse `json:"duplicates,omitempty"`
 }
 
 // @Summary 上传文件进行题目导入
-// @Description 支持PDF、图片、Excel等格式的题目批量导入
+// @Description 支持PDF、图片、Excel、Word等格式的题目批量导入，Excel文件格式见 /import/questions/template
+// @Description 也支持QTI 2.1内容包(.zip)、Moodle XML(.xml)和GIFT(.gift/.txt)，不支持的题型逐题报告
+// @Description 文件在后台解析，通过 /import/jobs/{id} 查询进度，解析完成后在 /import/jobs/{id}/items 中核对并确认导入
 // @Tags 题目导入
 // @Accept multipart/form-data
 // @Produce json
@@ -56,7 +89,7 @@ type ImportQuestionResponse struct {
 // @Param auto_detect formData boolean false "是否自动检测题型"
 // @Param default_grade formData string false "默认年级"
 // @Param default_subject formData string false "默认科目"
-// @Success 200 {object} map[string]interface{} "返回解析的题目列表"
+// @Success 202 {object} map[string]interface{} "返回创建的导入任务"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
 // @Failure 500 {object} map[string]interface{} "服务器内部错误"
 // @Router /api/v1/import/questions [post]
@@ -97,31 +130,33 @@ func ImportQuestions(c *gin.Context) {
 		return
 	}
 
-	// 获取可选参数
-	autoDetect := c.DefaultPostForm("auto_detect", "true") == "true"
-	defaultGrade := c.DefaultPostForm("default_grade", "grade1")
-	defaultSubject := c.DefaultPostForm("default_subject", "math")
-
-	// 解析文件内容
-	questions, err := parseImportFile(savedFilePath, ext, autoDetect, defaultGrade, defaultSubject)
-	if err != nil {
-		// 清理上传的文件
+	// 创建导入任务，文件在后台解析
+	job := entity.ImportJob{
+		CreatorID:      c.GetUint("userID"),
+		FileName:       file.Filename,
+		FileID:         savedFileName,
+		Format:         ext,
+		AutoDetect:     c.DefaultPostForm("auto_detect", "true") == "true",
+		DefaultGrade:   c.DefaultPostForm("default_grade", "grade1"),
+		DefaultSubject: c.DefaultPostForm("default_subject", "math"),
+		Status:         entity.ImportJobQueued,
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
 		os.Remove(savedFilePath)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析文件失败: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建导入任务失败"})
 		return
 	}
+	startImportJob(job.ID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "文件解析成功",
-		"file_id":        savedFileName,
-		"questions":      questions,
-		"total_count":    len(questions),
-		"detected_count": countDetectedQuestions(questions),
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "导入任务已创建",
+		"file_id": savedFileName,
+		"job":     job,
 	})
 }
 
 // @Summary 确认导入题目
-// @Description 确认并保存解析的题目到数据库
+// @Description 确认并保存解析的题目到数据库，题目由调用方提交；导入任务请使用 /import/jobs/{id}/confirm
 // @Tags 题目导入
 // @Accept json
 // @Produce json
@@ -155,28 +190,24 @@ func ConfirmImportQuestions(c *gin.Context) {
 			continue // 跳过未批准的题目
 		}
 
-		// 创建题目实体
-		question := entity.Question{
-			Title:       questionData.Title,
-			Type:        entity.QuestionType(questionData.Type),
-			Difficulty:  questionData.Difficulty,
-			Grade:       questionData.Grade,
-			Subject:     questionData.Subject,
-			Topic:       questionData.Topic,
-			Options:     strings.Join(questionData.Options, ","), // 简单处理，实际应该用JSON
-			Answer:      questionData.Answer,
-			Explanation: questionData.Explanation,
-			CreatorID:   userID,
-			MediaURLs:   strings.Join(questionData.MediaURLs, ","), // 简单处理，实际应该用JSON
-			LayoutType:  questionData.LayoutType,
-			ElementData: questionData.ElementData,
-			Tags:        questionData.Tags,
+		if err := grading.ValidateGradingConfig(questionData.GradingConfig); err != nil {
+			errors = append(errors, fmt.Sprintf("判分配置无效: %s - %v", questionData.Title, err))
+			continue
 		}
 
+		question, err := importedQuestion(&questionData, userID)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("题目数据格式错误: %s - %v", questionData.Title, err))
+			continue
+		}
 		if err := tx.Create(&question).Error; err != nil {
 			errors = append(errors, fmt.Sprintf("创建题目失败: %s - %v", questionData.Title, err))
 			continue
 		}
+		if err := recordQuestionRevision(tx, &question, userID, "导入题目"); err != nil {
+			errors = append(errors, fmt.Sprintf("保存题目版本失败: %s - %v", questionData.Title, err))
+			continue
+		}
 
 		createdQuestions = append(createdQuestions, question)
 	}
@@ -210,6 +241,41 @@ func ConfirmImportQuestions(c *gin.Context) {
 	})
 }
 
+// importedQuestion 由确认导入的题目数据创建题目实体，导入的题目均为草稿
+// 选项和媒体地址编码为JSON数组，并按题型校验题目数据
+func importedQuestion(questionData *request.ImportQuestionData, userID uint) (entity.Question, error) {
+	fields, errs := payload.Normalize(payload.Payload{
+		Type:        entity.QuestionType(questionData.Type),
+		Options:     encodeImportList(questionData.Options),
+		Answer:      questionData.Answer,
+		MediaURLs:   encodeImportList(questionData.MediaURLs),
+		ElementData: questionData.ElementData,
+	})
+	if len(errs) > 0 {
+		return entity.Question{}, errs
+	}
+
+	return entity.Question{
+		Title:       questionData.Title,
+		Type:        entity.QuestionType(questionData.Type),
+		Difficulty:  questionData.Difficulty,
+		Grade:       questionData.Grade,
+		Subject:     questionData.Subject,
+		Topic:       questionData.Topic,
+		Options:     fields.Options,
+		Answer:      questionData.Answer,
+		Explanation: questionData.Explanation,
+		CreatorID:   userID,
+		MediaURLs:   fields.MediaURLs,
+		LayoutType:  questionData.LayoutType,
+		ElementData: fields.ElementData,
+		Tags:        questionData.Tags,
+		Status:      entity.QuestionStatusDraft,
+
+		GradingConfig: encodeGradingConfig(questionData.GradingConfig),
+	}, nil
+}
+
 // 解析导入文件
 func parseImportFile(filePath, ext string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
 	switch ext {
@@ -221,49 +287,43 @@ func parseImportFile(filePath, ext string, autoDetect bool, defaultGrade, defaul
 		return parseExcelFile(filePath, autoDetect, defaultGrade, defaultSubject)
 	case ".docx", ".doc":
 		return parseWordFile(filePath, autoDetect, defaultGrade, defaultSubject)
+	case ".zip", ".xml", ".gift", ".txt":
+		return parseInteropFile(filePath, ext, defaultGrade, defaultSubject)
 	default:
 		return nil, fmt.Errorf("不支持的文件格式: %s", ext)
 	}
 }
 
-// 解析PDF文件 (简化版本，实际需要PDF解析库)
-func parsePDFFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
-	// TODO: 实际实现需要使用PDF解析库，如 github.com/ledongthuc/pdf
-	// 这里提供一个模拟的实现
-	questions := []ImportQuestionResponse{
-		{
-			ID:            0,
-			Title:         "示例题目：蝴蝶比花朵（）",
-			Type:          "comparison",
-			DetectedType:  "comparison",
-			Confidence:    0.85,
-			Options:       []string{"多", "少", "一样"},
-			Answer:        "多",
-			MediaURLs:     []string{},
-			Status:        "pending",
-			OriginalIndex: 1,
-		},
-	}
-
-	return questions, nil
-}
-
 // 解析图片文件
 func parseImageFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
 	// TODO: 实际实现需要OCR和图像识别
 	return []ImportQuestionResponse{}, nil
 }
 
-// 解析Excel文件
-func parseExcelFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
-	// TODO: 实际实现需要Excel解析库
-	return []ImportQuestionResponse{}, nil
-}
+// flagImportDuplicates 为解析出的题目标记题库中疑似重复的题目
+func flagImportDuplicates(questions []ImportQuestionResponse) error {
+	if len(questions) == 0 {
+		return nil
+	}
+	prints, bank, err := loadQuestionFingerprints()
+	if err != nil {
+		return err
+	}
+	ix := dedup.NewIndex(prints)
 
-// 解析Word文件
-func parseWordFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
-	// TODO: 实际实现需要Word解析库
-	return []ImportQuestionResponse{}, nil
+	for i := range questions {
+		options, _ := json.Marshal(questions[i].Options)
+		mediaURLs, _ := json.Marshal(questions[i].MediaURLs)
+		question := entity.Question{
+			Title:     questions[i].Title,
+			Type:      entity.QuestionType(questions[i].Type),
+			Options:   string(options),
+			Answer:    questions[i].Answer,
+			MediaURLs: string(mediaURLs),
+		}
+		questions[i].Duplicates = findDuplicateMatches(ix, dedup.NewFingerprint(&question, questionMediaHash(&question)), bank)
+	}
+	return nil
 }
 
 // 统计检测到的题目数量
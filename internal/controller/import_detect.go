@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+	"testogo/internal/payload"
+)
+
+// importReviewConfidence 置信度低于该值的题目在预览中提示老师核对
+const importReviewConfidence = 0.6
+
+// importTypeAliases 导入文件中题型名称到题型的映射，名称比较时不区分大小写
+var importTypeAliases = map[string]entity.QuestionType{
+	"choice": entity.TypeChoice, "单选": entity.TypeChoice, "单选题": entity.TypeChoice, "选择题": entity.TypeChoice,
+	"multichoice": entity.TypeMultiChoice, "多选": entity.TypeMultiChoice, "多选题": entity.TypeMultiChoice,
+	"judge": entity.TypeJudge, "判断": entity.TypeJudge, "判断题": entity.TypeJudge,
+	"fillin": entity.TypeFillIn, "填空": entity.TypeFillIn, "填空题": entity.TypeFillIn,
+	"math": entity.TypeMath, "计算": entity.TypeMath, "计算题": entity.TypeMath, "加减法": entity.TypeMath,
+	"comparison": entity.TypeComparison, "比较": entity.TypeComparison, "比较题": entity.TypeComparison,
+	"reasoning": entity.TypeReasoning, "推理": entity.TypeReasoning, "推理题": entity.TypeReasoning, "找规律": entity.TypeReasoning,
+	"visual": entity.TypeVisual, "图片题": entity.TypeVisual,
+	"circleselect": entity.TypeCircleSelect, "圈选": entity.TypeCircleSelect, "圈选题": entity.TypeCircleSelect,
+}
+
+var (
+	// optionPrefixPattern 选项前的序号，如 "A." "B、" "C："
+	optionPrefixPattern = regexp.MustCompile(`^[A-Ha-h]\s*[.．、:：)）]\s*`)
+	// arithmeticPattern 只由数字、运算符和填空括号组成的算式
+	arithmeticPattern = regexp.MustCompile(`^[\d\s+\-×÷*/=＝＋－()（）?？_□]+$`)
+	// blankPattern 题干中的填空位置
+	blankPattern = regexp.MustCompile(`（\s*）|\(\s*\)|_{2,}|＿{2,}|□`)
+	// choiceLettersPattern 由选项字母组成的答案，如 "A" "AC" "A,C"
+	choiceLettersPattern = regexp.MustCompile(`^[A-Ha-h](\s*[,，、]?\s*[A-Ha-h])*$`)
+)
+
+// parseImportType 解析导入文件中的题型名称，无法识别时返回空
+func parseImportType(name string) entity.QuestionType {
+	return importTypeAliases[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// detectQuestionType 根据题干、选项和答案推测题型，返回题型和置信度（0-1），无法推测时返回空题型
+func detectQuestionType(title string, options []string, answer string) (entity.QuestionType, float64) {
+	answer = strings.TrimSpace(answer)
+	isJudgeAnswer := isJudgeValue(answer)
+
+	switch {
+	case len(options) >= 2 && choiceLettersPattern.MatchString(answer):
+		if len(grading.ParseChoiceSet(answer, nil)) > 1 {
+			return entity.TypeMultiChoice, 0.9
+		}
+		return entity.TypeChoice, 0.9
+	case len(options) == 2 && isJudgeAnswer && isJudgeValue(options[0]) && isJudgeValue(options[1]):
+		return entity.TypeJudge, 0.9
+	case len(options) >= 2:
+		for _, option := range options {
+			if option == answer {
+				return entity.TypeChoice, 0.8
+			}
+		}
+		return entity.TypeChoice, 0.6
+	case isJudgeAnswer && answer != "0" && answer != "1":
+		return entity.TypeJudge, 0.8
+	case strings.Contains(title, "找规律"):
+		return entity.TypeReasoning, 0.7
+	case strings.Contains(title, "比") && (answer == "多" || answer == "少" || answer == "一样" || answer == "一样多"):
+		return entity.TypeComparison, 0.8
+	case arithmeticPattern.MatchString(title) && strings.ContainsAny(title, "+-×÷*/＋－"):
+		return entity.TypeMath, 0.9
+	case blankPattern.MatchString(title):
+		return entity.TypeFillIn, 0.7
+	case answer != "":
+		return entity.TypeFillIn, 0.4
+	}
+	return "", 0
+}
+
+// isJudgeValue 判断文本是否为判断题答案，如 对/错、√/×、true/false
+func isJudgeValue(text string) bool {
+	answer := grading.NormalizeJudgeAnswer(text)
+	return answer == "true" || answer == "false"
+}
+
+// splitImportList 解析列表文本：JSON字符串数组，或用 | 、换行分隔的文本
+func splitImportList(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var items []string
+	if strings.HasPrefix(text, "[") && json.Unmarshal([]byte(text), &items) == nil {
+		return items
+	}
+	for _, item := range strings.FieldsFunc(text, func(r rune) bool { return r == '|' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitImportOptions 解析选项文本，兼容题目的选项JSON（含图片等结构化选项），文本选项去掉 "A." 等序号
+func splitImportOptions(text string) []string {
+	if strings.HasPrefix(strings.TrimSpace(text), "[") {
+		if options := grading.ParseOptionTexts(text); options != nil {
+			return options
+		}
+	}
+
+	var options []string
+	for _, item := range splitImportList(text) {
+		if item = strings.TrimSpace(optionPrefixPattern.ReplaceAllString(item, "")); item != "" {
+			options = append(options, item)
+		}
+	}
+	return options
+}
+
+// parseImportDifficulty 解析难度，支持1-5和 easy/medium/hard、简单/中等/困难，空值默认为1
+func parseImportDifficulty(text string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "":
+		return 1, nil
+	case "easy", "简单":
+		return 1, nil
+	case "medium", "中等":
+		return 2, nil
+	case "hard", "困难":
+		return 3, nil
+	}
+	difficulty, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || difficulty < 1 || difficulty > 5 {
+		return 0, fmt.Errorf("难度应为1-5或简单/中等/困难: %s", text)
+	}
+	return difficulty, nil
+}
+
+// validateImportQuestion 检查导入题目的必填项和题型相关的约束，返回全部错误
+func validateImportQuestion(question *ImportQuestionResponse) []string {
+	var errs []string
+	if strings.TrimSpace(question.Title) == "" {
+		errs = append(errs, "题目内容不能为空")
+	}
+	qType := entity.QuestionType(question.Type)
+	if qType == "" {
+		errs = append(errs, "无法识别题型，请填写题型")
+		return errs
+	}
+	if parseImportType(string(qType)) != qType {
+		errs = append(errs, fmt.Sprintf("未知题型: %s", question.Type))
+		return errs
+	}
+	if qType != entity.TypeVisual && strings.TrimSpace(question.Answer) == "" {
+		errs = append(errs, "答案不能为空")
+	}
+
+	switch qType {
+	case entity.TypeChoice, entity.TypeMultiChoice:
+		if len(question.Options) < 2 {
+			errs = append(errs, "选择题至少需要两个选项")
+			break
+		}
+		letters := grading.ParseChoiceSet(question.Answer, question.Options)
+		for _, letter := range letters {
+			key := strings.ToUpper(letter)
+			if len(key) != 1 || key[0] < 'A' || key[0] > 'Z' {
+				errs = append(errs, fmt.Sprintf("答案 %s 不是选项字母或选项内容", letter))
+			} else if int(key[0]-'A') >= len(question.Options) {
+				errs = append(errs, fmt.Sprintf("答案 %s 超出选项范围", key))
+			}
+		}
+		if qType == entity.TypeChoice && len(letters) > 1 {
+			errs = append(errs, "单选题只能有一个答案")
+		}
+	case entity.TypeJudge:
+		if !isJudgeValue(question.Answer) {
+			errs = append(errs, "判断题答案应为 对/错 或 true/false")
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// 选项、媒体地址和题目元素数据按题型的结构校验
+	_, fieldErrs := payload.Normalize(payload.Payload{
+		Type:        qType,
+		Options:     encodeImportList(question.Options),
+		Answer:      question.Answer,
+		MediaURLs:   encodeImportList(question.MediaURLs),
+		ElementData: question.ElementData,
+	})
+	for _, err := range fieldErrs {
+		errs = append(errs, err.Field+": "+err.Message)
+	}
+	return errs
+}
+
+// encodeImportList 将导入的选项或媒体地址编码为JSON数组，空列表返回空字符串
+func encodeImportList(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+// finalizeImportQuestion 校验题目并设置状态和置信度，parseErrs 为解析时发现的错误
+// 有错误的题目标记为 rejected，置信度为0；其余为 pending，置信度取题型识别的置信度，置信度较低时附加提示
+func finalizeImportQuestion(question *ImportQuestionResponse, typeConfidence float64, parseErrs []string) {
+	if errs := append(parseErrs, validateImportQuestion(question)...); len(errs) > 0 {
+		question.Status = "rejected"
+		question.ErrorMessage = strings.Join(errs, "；")
+		question.Confidence = 0
+		return
+	}
+	question.Status = "pending"
+	question.Confidence = typeConfidence
+	if typeConfidence < importReviewConfidence {
+		question.Warnings = append(question.Warnings, "识别置信度较低，请核对题型和内容")
+	}
+}
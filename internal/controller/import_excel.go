@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"testogo/internal/model/entity"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// excelSheetNames 优先读取的工作表名称，都不存在时读取第一个工作表
+var excelSheetNames = []string{"questions", "题目"}
+
+// excelColumn Excel导入模板中的一列
+type excelColumn struct {
+	Key         string   // 列标识，与导出文件的表头一致
+	Title       string   // 模板中的中文表头
+	Aliases     []string // 其他可识别的表头
+	Required    bool
+	Description string
+}
+
+// excelColumns Excel导入模板的列，表头可以是列标识、中文表头或 "中文表头(列标识)"，不区分大小写
+var excelColumns = []excelColumn{
+	{Key: "type", Title: "题型", Aliases: []string{"类型"}, Description: "choice/单选、multichoice/多选、judge/判断、fillin/填空、math/计算、comparison/比较、reasoning/推理、visual/图片题、circleselect/圈选；留空时自动识别"},
+	{Key: "title", Title: "题目", Aliases: []string{"题干", "题目内容"}, Required: true, Description: "题目内容"},
+	{Key: "options", Title: "选项", Description: "选择题的选项，用 | 或换行分隔，可带 A. B. 等序号；也可填写JSON数组"},
+	{Key: "answer", Title: "答案", Description: "选择题填写选项字母（多选如 AC）或选项内容，判断题填写 对/错；图片题可留空"},
+	{Key: "grade", Title: "年级", Description: "年级代码，留空时使用上传时指定的默认年级"},
+	{Key: "subject", Title: "科目", Description: "科目代码，留空时使用上传时指定的默认科目"},
+	{Key: "topic", Title: "主题", Description: "主题代码"},
+	{Key: "difficulty", Title: "难度", Description: "1-5，或 简单/中等/困难，留空为1"},
+	{Key: "tags", Title: "标签", Description: "多个标签用逗号分隔"},
+	{Key: "explanation", Title: "解析", Description: "答案解析"},
+	{Key: "media_urls", Title: "图片", Aliases: []string{"媒体"}, Description: "已上传图片的地址，多个用 | 分隔"},
+	{Key: "layout_type", Title: "布局", Description: "可选，题目布局类型"},
+	{Key: "element_data", Title: "元素数据", Description: "可选，题目元素的JSON数据"},
+}
+
+// excelTemplateExamples 导入模板中的示例行，列顺序与 excelColumns 一致
+var excelTemplateExamples = [][]string{
+	{"单选", "3 + 4 = ?", "A. 6|B. 7|C. 8", "B", "grade1", "math", "addition", "1", "加法,十以内", "3 加 4 等于 7"},
+	{"多选", "下面哪些数是双数？", "A. 2|B. 3|C. 4|D. 5", "AC", "grade1", "math", "", "2", "双数", ""},
+	{"判断", "10 比 9 大。", "", "对", "grade1", "math", "comparison", "简单", "", ""},
+	{"填空", "5 + (  ) = 8", "", "3", "grade1", "math", "addition", "2", "", ""},
+	{"", "蝴蝶比花朵多还是少？", "多|少|一样", "多", "grade1", "math", "comparison", "", "", "题型留空时自动识别"},
+}
+
+// @Summary 下载Excel导入模板
+// @Description 下载题目导入的Excel模板，第一个工作表为题目（每行一道题，含示例行），"说明"工作表介绍各列的填写方法
+// @Tags 题目导入
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BasicAuth
+// @Success 200 {file} file "Excel模板"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/import/questions/template [get]
+func DownloadImportTemplate(c *gin.Context) {
+	f, err := buildImportTemplate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成导入模板失败"})
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", exportContentTypes["xlsx"])
+	c.Header("Content-Disposition", "attachment; filename=question_import_template.xlsx")
+	if _, err := f.WriteTo(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// buildImportTemplate 生成Excel导入模板
+func buildImportTemplate() (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheet := excelSheetNames[0]
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	header := make([]string, len(excelColumns))
+	for i, column := range excelColumns {
+		header[i] = fmt.Sprintf("%s(%s)", column.Title, column.Key)
+	}
+	rows := append([][]string{header}, excelTemplateExamples...)
+	for i, row := range rows {
+		if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if err := f.SetColWidth(sheet, "A", "M", 16); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	guide := "说明"
+	if _, err := f.NewSheet(guide); err != nil {
+		f.Close()
+		return nil, err
+	}
+	guideRows := [][]string{{"列", "必填", "说明"}}
+	for _, column := range excelColumns {
+		required := ""
+		if column.Required {
+			required = "是"
+		}
+		guideRows = append(guideRows, []string{fmt.Sprintf("%s(%s)", column.Title, column.Key), required, column.Description})
+	}
+	guideRows = append(guideRows,
+		[]string{},
+		[]string{"每行一道题，第一行为表头；导入前请删除示例行。列的顺序可以调整，不需要的列可以删除。"},
+		[]string{"题目导出的xlsx文件也可以直接导入。"},
+	)
+	for i, row := range guideRows {
+		if err := f.SetSheetRow(guide, fmt.Sprintf("A%d", i+1), &row); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if err := f.SetColWidth(guide, "C", "C", 80); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// 解析Excel文件，每行一道题，列格式见 excelColumns
+func parseExcelFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
+	if strings.ToLower(filepath.Ext(filePath)) == ".xls" {
+		return nil, errors.New("暂不支持.xls格式，请另存为.xlsx后上传")
+	}
+
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开Excel文件: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(excelImportSheet(f))
+	if err != nil {
+		return nil, err
+	}
+
+	// 第一个非空行为表头
+	headerRow := 0
+	for headerRow < len(rows) && isBlankRow(rows[headerRow]) {
+		headerRow++
+	}
+	if headerRow == len(rows) {
+		return nil, errors.New("Excel文件中没有数据")
+	}
+	columns := make(map[string]int)
+	for i, header := range rows[headerRow] {
+		if key := matchExcelColumn(header); key != "" {
+			if _, exists := columns[key]; !exists {
+				columns[key] = i
+			}
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("Excel表头中缺少题目(title)列，请使用导入模板")
+	}
+
+	questions := []ImportQuestionResponse{}
+	for i := headerRow + 1; i < len(rows); i++ {
+		if isBlankRow(rows[i]) {
+			continue
+		}
+		cell := func(key string) string {
+			if index, ok := columns[key]; ok && index < len(rows[i]) {
+				return strings.TrimSpace(rows[i][index])
+			}
+			return ""
+		}
+		questions = append(questions, parseExcelRow(cell, i+1, autoDetect, defaultGrade, defaultSubject))
+	}
+	return questions, nil
+}
+
+// parseExcelRow 解析一行题目，rowNumber 为Excel中的行号
+func parseExcelRow(cell func(key string) string, rowNumber int, autoDetect bool, defaultGrade, defaultSubject string) ImportQuestionResponse {
+	question := ImportQuestionResponse{
+		Title:         cell("title"),
+		Options:       splitImportOptions(cell("options")),
+		Answer:        cell("answer"),
+		MediaURLs:     splitImportList(cell("media_urls")),
+		Grade:         cell("grade"),
+		Subject:       cell("subject"),
+		Topic:         cell("topic"),
+		Tags:          entity.JoinTagNames(entity.ParseTagNames(cell("tags"))),
+		Explanation:   cell("explanation"),
+		LayoutType:    cell("layout_type"),
+		ElementData:   cell("element_data"),
+		OriginalIndex: rowNumber,
+	}
+	if question.Options == nil {
+		question.Options = []string{}
+	}
+	if question.MediaURLs == nil {
+		question.MediaURLs = []string{}
+	}
+	if question.Grade == "" {
+		question.Grade = defaultGrade
+	}
+	if question.Subject == "" {
+		question.Subject = defaultSubject
+	}
+
+	var errs []string
+	difficulty, err := parseImportDifficulty(cell("difficulty"))
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	question.Difficulty = difficulty
+
+	confidence := 1.0
+	typeName := cell("type")
+	switch {
+	case typeName != "":
+		question.Type = typeName
+		if qType := parseImportType(typeName); qType != "" {
+			question.Type = string(qType)
+			question.DetectedType = string(qType)
+		}
+	case autoDetect:
+		qType, detected := detectQuestionType(question.Title, question.Options, question.Answer)
+		question.Type = string(qType)
+		question.DetectedType = string(qType)
+		confidence = detected
+	}
+
+	finalizeImportQuestion(&question, confidence, errs)
+	return question
+}
+
+// excelImportSheet 返回要导入的工作表名称
+func excelImportSheet(f *excelize.File) string {
+	sheets := f.GetSheetList()
+	for _, name := range excelSheetNames {
+		for _, sheet := range sheets {
+			if strings.EqualFold(sheet, name) {
+				return sheet
+			}
+		}
+	}
+	return f.GetSheetName(0)
+}
+
+// matchExcelColumn 根据表头识别列，无法识别时返回空
+// 表头可以是 "题型"、"type" 或模板中的 "题型(type)"
+func matchExcelColumn(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	candidates := []string{header}
+	if open := strings.IndexAny(header, "(（"); open > 0 {
+		inner := strings.TrimRight(header[open:], ")）")
+		inner = strings.TrimLeft(inner, "(（")
+		candidates = append(candidates, strings.TrimSpace(header[:open]), strings.TrimSpace(inner))
+	}
+
+	for _, candidate := range candidates {
+		for _, column := range excelColumns {
+			if candidate == column.Key || candidate == column.Title {
+				return column.Key
+			}
+			for _, alias := range column.Aliases {
+				if candidate == alias {
+					return column.Key
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
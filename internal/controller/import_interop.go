@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+
+	"testogo/internal/interop"
+	"testogo/internal/model/entity"
+)
+
+// 解析交换格式文件：QTI 2.1内容包（zip）、Moodle XML或单个QTI题目（xml）、GIFT（gift/txt）
+// 这些格式自带题型和答案，不需要自动检测；无法转换的题目作为驳回的题目返回，并说明原因
+func parseInteropFile(filePath, ext string, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
+	var items []interop.Item
+	switch ext {
+	case ".zip":
+		r, err := zip.OpenReader(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("无法打开QTI内容包: %v", err)
+		}
+		defer r.Close()
+		if items, err = interop.ParseQTIPackage(&r.Reader, saveImportImage); err != nil {
+			return nil, err
+		}
+	case ".xml", ".gift", ".txt":
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if ext == ".xml" {
+			items, err = interop.ParseXML(data, saveImportImage)
+		} else {
+			items, err = interop.ParseGIFT(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的文件格式: %s", ext)
+	}
+	return buildInteropQuestions(items, defaultGrade, defaultSubject), nil
+}
+
+// buildInteropQuestions 将交换格式中的题目转换为导入预览
+func buildInteropQuestions(items []interop.Item, defaultGrade, defaultSubject string) []ImportQuestionResponse {
+	questions := make([]ImportQuestionResponse, 0, len(items))
+	for i, item := range items {
+		question := ImportQuestionResponse{
+			Title:         item.Title,
+			Type:          string(item.Type),
+			DetectedType:  string(item.Type),
+			Options:       item.Options,
+			Answer:        item.Answer,
+			MediaURLs:     item.MediaURLs,
+			OriginalIndex: i + 1,
+			Difficulty:    1,
+			Grade:         defaultGrade,
+			Subject:       defaultSubject,
+			Explanation:   item.Explanation,
+		}
+		if question.Options == nil {
+			question.Options = []string{}
+		}
+		if question.MediaURLs == nil {
+			question.MediaURLs = []string{}
+		}
+		if len(item.AcceptedAnswers) > 0 {
+			question.GradingConfig = &entity.GradingConfig{AcceptedAnswers: item.AcceptedAnswers}
+		}
+
+		// 无法转换的题目其余字段不完整，只报告原因
+		if item.Error != "" {
+			if question.Title == "" {
+				question.Title = item.Identifier
+			}
+			question.Status = "rejected"
+			question.ErrorMessage = fmt.Sprintf("%s: %s", item.Identifier, item.Error)
+			questions = append(questions, question)
+			continue
+		}
+		finalizeImportQuestion(&question, 1.0, nil)
+		questions = append(questions, question)
+	}
+	return questions
+}
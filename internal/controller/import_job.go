@@ -0,0 +1,718 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	importWorkers         = 2              // 同时解析的导入任务数，其余任务排队
+	importItemBatchSize   = 200            // 保存预览题目时每批写入的数量
+	importCommitBatchSize = 100            // 确认导入时每个事务写入题库的题目数
+	importOrphanAge       = 24 * time.Hour // 导入目录中无任务引用的文件超过该时间后清理
+)
+
+// importWorkerSlots 限制同时解析的任务数
+var importWorkerSlots = make(chan struct{}, importWorkers)
+
+// startImportJob 在后台解析导入任务
+func startImportJob(jobID uint) {
+	go runImportJob(jobID)
+}
+
+// runImportJob 解析导入文件并保存预览题目，只处理排队中的任务
+func runImportJob(jobID uint) {
+	importWorkerSlots <- struct{}{}
+	defer func() { <-importWorkerSlots }()
+	defer func() {
+		if r := recover(); r != nil {
+			failImportJob(jobID, fmt.Sprintf("解析文件失败: %v", r))
+		}
+	}()
+
+	// 任务在排队期间可能已被删除
+	result := database.DB.Model(&entity.ImportJob{}).
+		Where("id = ? AND status = ?", jobID, entity.ImportJobQueued).
+		Updates(map[string]interface{}{"status": entity.ImportJobParsing, "progress": 10})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+	var job entity.ImportJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return
+	}
+
+	questions, err := parseImportFile(filepath.Join(ImportDir, job.FileID), job.Format, job.AutoDetect, job.DefaultGrade, job.DefaultSubject)
+	if err != nil {
+		failImportJob(job.ID, fmt.Sprintf("解析文件失败: %v", err))
+		return
+	}
+	setImportJobProgress(job.ID, 60)
+
+	// 标记与题库中已有题目疑似重复的题目
+	if err := flagImportDuplicates(questions); err != nil {
+		failImportJob(job.ID, "查找重复题目失败")
+		return
+	}
+	setImportJobProgress(job.ID, 80)
+
+	if err := saveImportJobItems(&job, questions); err != nil {
+		failImportJob(job.ID, fmt.Sprintf("保存解析结果失败: %v", err))
+	}
+}
+
+// saveImportJobItems 保存预览题目，并将任务标记为等待核对
+// 重新解析时先删除上次保存的题目，避免服务重启后重复
+func saveImportJobItems(job *entity.ImportJob, questions []ImportQuestionResponse) error {
+	items := make([]entity.ImportJobItem, 0, len(questions))
+	for i := range questions {
+		data, err := json.Marshal(questions[i])
+		if err != nil {
+			return err
+		}
+		items = append(items, entity.ImportJobItem{
+			JobID:         job.ID,
+			OriginalIndex: questions[i].OriginalIndex,
+			Status:        questions[i].Status,
+			Data:          string(data),
+		})
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", job.ID).Delete(&entity.ImportJobItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			if err := tx.CreateInBatches(items, importItemBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&entity.ImportJob{}).
+			Where("id = ? AND status = ?", job.ID, entity.ImportJobParsing).
+			Updates(map[string]interface{}{
+				"status":         entity.ImportJobAwaitingReview,
+				"progress":       100,
+				"total_count":    len(questions),
+				"detected_count": countDetectedQuestions(questions),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// failImportJob 将任务标记为失败，并清理上传的文件
+func failImportJob(jobID uint, message string) {
+	var job entity.ImportJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return
+	}
+	database.DB.Model(&job).Updates(map[string]interface{}{
+		"status":        entity.ImportJobFailed,
+		"error_message": message,
+	})
+	removeImportFile(job.FileID)
+}
+
+func setImportJobProgress(jobID uint, progress int) {
+	database.DB.Model(&entity.ImportJob{}).Where("id = ?", jobID).Update("progress", progress)
+}
+
+// removeImportFile 删除导入目录中的文件
+func removeImportFile(fileID string) {
+	if fileID != "" {
+		os.Remove(filepath.Join(ImportDir, filepath.Base(fileID)))
+	}
+}
+
+// ResumeImportJobs 服务启动时重新解析上次未完成的任务，并清理导入目录中无任务引用的文件
+func ResumeImportJobs() {
+	if database.DB == nil {
+		return
+	}
+	// 解析中的任务在服务重启时中断，重新排队
+	if err := database.DB.Model(&entity.ImportJob{}).Where("status = ?", entity.ImportJobParsing).
+		Updates(map[string]interface{}{"status": entity.ImportJobQueued, "progress": 0}).Error; err != nil {
+		log.Printf("恢复导入任务失败: %v", err)
+		return
+	}
+	var queued []uint
+	if err := database.DB.Model(&entity.ImportJob{}).Where("status = ?", entity.ImportJobQueued).
+		Order("id").Pluck("id", &queued).Error; err != nil {
+		log.Printf("恢复导入任务失败: %v", err)
+		return
+	}
+	for _, id := range queued {
+		startImportJob(id)
+	}
+
+	if err := cleanupImportFiles(); err != nil {
+		log.Printf("清理导入文件失败: %v", err)
+	}
+}
+
+// cleanupImportFiles 删除导入目录中不属于未完成任务、且超过 importOrphanAge 的文件
+func cleanupImportFiles() error {
+	entries, err := os.ReadDir(ImportDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var fileIDs []string
+	if err := database.DB.Model(&entity.ImportJob{}).
+		Where("status IN ?", []entity.ImportJobStatus{entity.ImportJobQueued, entity.ImportJobParsing, entity.ImportJobAwaitingReview}).
+		Pluck("file_id", &fileIDs).Error; err != nil {
+		return err
+	}
+	inUse := make(map[string]bool, len(fileIDs))
+	for _, id := range fileIDs {
+		inUse[id] = true
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || inUse[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < importOrphanAge {
+			continue
+		}
+		os.Remove(filepath.Join(ImportDir, entry.Name()))
+	}
+	return nil
+}
+
+// @Summary 导入任务列表
+// @Description 返回当前用户的导入任务，管理员可通过 all=true 查看全部任务
+// @Tags 题目导入
+// @Produce json
+// @Security BasicAuth
+// @Param status query string false "任务状态：queued、parsing、awaiting_review、committed、failed"
+// @Param all query bool false "查看全部任务（仅管理员）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "导入任务列表"
+// @Router /api/v1/import/jobs [get]
+func ListImportJobs(c *gin.Context) {
+	query := database.DB.Model(&entity.ImportJob{}).Order("id desc")
+	if !(c.GetString("role") == "admin" && c.Query("all") == "true") {
+		query = query.Where("creator_id = ?", c.GetUint("userID"))
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导入任务失败"})
+		return
+	}
+	var jobs []entity.ImportJob
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导入任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"items": jobs,
+	})
+}
+
+// @Summary 导入任务详情
+// @Description 返回任务状态、进度和各状态的预览题目数量
+// @Tags 题目导入
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} map[string]interface{} "导入任务"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/v1/import/jobs/{id} [get]
+func GetImportJob(c *gin.Context) {
+	job, ok := findImportJob(c)
+	if !ok {
+		return
+	}
+
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	if err := database.DB.Model(&entity.ImportJobItem{}).Select("status, COUNT(*) AS count").
+		Where("job_id = ?", job.ID).Group("status").Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导入任务失败"})
+		return
+	}
+	itemCounts := make(map[string]int, len(counts))
+	for _, count := range counts {
+		itemCounts[count.Status] = count.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":         job,
+		"item_counts": itemCounts,
+	})
+}
+
+// @Summary 导入任务的预览题目
+// @Description 按原文件中的顺序返回解析出的题目
+// @Tags 题目导入
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "任务ID"
+// @Param status query string false "题目状态：pending、approved、rejected、imported"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "预览题目列表"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/v1/import/jobs/{id}/items [get]
+func ListImportJobItems(c *gin.Context) {
+	job, ok := findImportJob(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&entity.ImportJobItem{}).Where("job_id = ?", job.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预览题目失败"})
+		return
+	}
+	var items []entity.ImportJobItem
+	if err := query.Order("original_index, id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预览题目失败"})
+		return
+	}
+
+	questions := make([]ImportQuestionResponse, len(items))
+	for i := range items {
+		questions[i] = importItemQuestion(&items[i])
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"items": questions,
+	})
+}
+
+// @Summary 编辑预览题目
+// @Description 修改一道预览题目的内容和状态，修改后重新校验并查找重复题目；内容有误的题目不能设为 approved
+// @Tags 题目导入
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "任务ID"
+// @Param item_id path int true "预览题目ID"
+// @Param request body request.ImportQuestionData true "题目内容"
+// @Success 200 {object} ImportQuestionResponse "修改后的预览题目"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "任务或题目不存在"
+// @Failure 409 {object} map[string]interface{} "任务或题目当前状态不能修改"
+// @Router /api/v1/import/jobs/{id}/items/{item_id} [put]
+func UpdateImportJobItem(c *gin.Context) {
+	job, ok := findImportJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != entity.ImportJobAwaitingReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务当前状态不能修改题目"})
+		return
+	}
+
+	var item entity.ImportJobItem
+	if err := database.DB.Where("id = ? AND job_id = ?", c.Param("item_id"), job.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "预览题目不存在"})
+		return
+	}
+	if item.Status == entity.ImportItemImported {
+		c.JSON(http.StatusConflict, gin.H{"error": "题目已导入题库，请在题库中修改"})
+		return
+	}
+
+	var req request.ImportQuestionData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status == "" {
+		req.Status = item.Status
+	}
+	if req.Status != entity.ImportItemPending && req.Status != entity.ImportItemApproved && req.Status != entity.ImportItemRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的题目状态"})
+		return
+	}
+	if err := grading.ValidateGradingConfig(req.GradingConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	question := importItemQuestion(&item)
+	applyImportQuestionData(&question, &req)
+	errs := validateImportQuestion(&question)
+	if len(errs) > 0 && req.Status == entity.ImportItemApproved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目内容有误", "details": errs})
+		return
+	}
+	question.ErrorMessage = strings.Join(errs, "；")
+	question.Status = req.Status
+	edited := []ImportQuestionResponse{question}
+	if err := flagImportDuplicates(edited); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找重复题目失败"})
+		return
+	}
+	question = edited[0]
+
+	data, err := json.Marshal(question)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存题目失败"})
+		return
+	}
+	if err := database.DB.Model(&item).Updates(map[string]interface{}{
+		"status": question.Status,
+		"data":   string(data),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存题目失败"})
+		return
+	}
+	c.JSON(http.StatusOK, question)
+}
+
+// @Summary 批量设置预览题目状态
+// @Description 批量通过、驳回或重置预览题目；内容有误的题目不会被通过，在 skipped 中返回
+// @Tags 题目导入
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "任务ID"
+// @Param request body request.ReviewImportItemsRequest true "题目ID和状态，题目ID为空时处理全部待核对的题目"
+// @Success 200 {object} map[string]interface{} "处理结果"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Failure 409 {object} map[string]interface{} "任务当前状态不能修改"
+// @Router /api/v1/import/jobs/{id}/items/review [post]
+func ReviewImportJobItems(c *gin.Context) {
+	job, ok := findImportJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != entity.ImportJobAwaitingReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务当前状态不能修改题目"})
+		return
+	}
+
+	var req request.ReviewImportItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Where("job_id = ? AND status <> ?", job.ID, entity.ImportItemImported)
+	if len(req.ItemIDs) > 0 {
+		query = query.Where("id IN ?", req.ItemIDs)
+	} else {
+		query = query.Where("status = ?", entity.ImportItemPending)
+	}
+	var items []entity.ImportJobItem
+	if err := query.Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预览题目失败"})
+		return
+	}
+
+	var updated []uint
+	skipped := []gin.H{}
+	for i := range items {
+		if req.Status == entity.ImportItemApproved {
+			question := importItemQuestion(&items[i])
+			if errs := validateImportQuestion(&question); len(errs) > 0 {
+				skipped = append(skipped, gin.H{"id": items[i].ID, "error": strings.Join(errs, "；")})
+				continue
+			}
+		}
+		updated = append(updated, items[i].ID)
+	}
+	if len(updated) > 0 {
+		if err := database.DB.Model(&entity.ImportJobItem{}).Where("id IN ?", updated).
+			Update("status", req.Status).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存题目状态失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"updated_count": len(updated),
+		"skipped":       skipped,
+	})
+}
+
+// @Summary 确认导入任务
+// @Description 将任务中已通过的题目分批写入题库，每批一个事务；中途失败时已写入的题目保留，再次确认会继续导入剩余题目
+// @Tags 题目导入
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} map[string]interface{} "导入结果"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Failure 409 {object} map[string]interface{} "任务当前状态不能确认导入"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/import/jobs/{id}/confirm [post]
+func ConfirmImportJob(c *gin.Context) {
+	job, ok := findImportJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != entity.ImportJobAwaitingReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务当前状态不能确认导入"})
+		return
+	}
+
+	var remaining int64
+	if err := database.DB.Model(&entity.ImportJobItem{}).
+		Where("job_id = ? AND status = ?", job.ID, entity.ImportItemApproved).
+		Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预览题目失败"})
+		return
+	}
+	if remaining == 0 && job.ImportedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有已通过的题目，请先核对题目"})
+		return
+	}
+	database.DB.Model(job).Update("progress", 0)
+
+	userID := c.GetUint("userID")
+	imported := 0
+	for {
+		var items []entity.ImportJobItem
+		if err := database.DB.Where("job_id = ? AND status = ?", job.ID, entity.ImportItemApproved).
+			Order("original_index, id").Limit(importCommitBatchSize).Find(&items).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预览题目失败", "imported_count": imported})
+			return
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return commitImportJobItems(tx, items, userID)
+		}); err != nil {
+			database.DB.Model(job).Update("error_message", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":          "导入过程中出现错误，已导入的题目保留，可再次确认继续导入",
+				"details":        []string{err.Error()},
+				"imported_count": imported,
+			})
+			return
+		}
+		imported += len(items)
+		database.DB.Model(job).Updates(map[string]interface{}{
+			"imported_count": gorm.Expr("imported_count + ?", len(items)),
+			"progress":       imported * 100 / int(remaining),
+		})
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(job).Updates(map[string]interface{}{
+		"status":        entity.ImportJobCommitted,
+		"progress":      100,
+		"error_message": "",
+		"completed_at":  &now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存任务状态失败", "imported_count": imported})
+		return
+	}
+	removeImportFile(job.FileID)
+
+	database.DB.First(job, job.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "题目导入成功",
+		"imported_count": imported,
+		"job":            job,
+	})
+}
+
+// commitImportJobItems 将一批预览题目写入题库，并记录创建的题目ID
+func commitImportJobItems(tx *gorm.DB, items []entity.ImportJobItem, userID uint) error {
+	for i := range items {
+		preview := importItemQuestion(&items[i])
+		questionData := importQuestionData(&preview)
+		if err := grading.ValidateGradingConfig(questionData.GradingConfig); err != nil {
+			return fmt.Errorf("判分配置无效: %s - %v", questionData.Title, err)
+		}
+
+		// 先按状态锁定题目，同一任务被重复确认时不会重复创建
+		result := tx.Model(&entity.ImportJobItem{}).
+			Where("id = ? AND status = ?", items[i].ID, entity.ImportItemApproved).
+			Update("status", entity.ImportItemImported)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("题目正在导入，请勿重复确认")
+		}
+
+		question, err := importedQuestion(&questionData, userID)
+		if err != nil {
+			return fmt.Errorf("题目数据格式错误: %s - %v", questionData.Title, err)
+		}
+		if err := tx.Create(&question).Error; err != nil {
+			return fmt.Errorf("创建题目失败: %s - %v", questionData.Title, err)
+		}
+		if err := recordQuestionRevision(tx, &question, userID, "导入题目"); err != nil {
+			return fmt.Errorf("保存题目版本失败: %s - %v", questionData.Title, err)
+		}
+		if err := tx.Model(&items[i]).Update("question_id", question.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// @Summary 删除导入任务
+// @Description 删除任务、预览题目和上传的文件，已导入题库的题目不受影响；解析中的任务不能删除
+// @Tags 题目导入
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Failure 409 {object} map[string]interface{} "任务正在解析"
+// @Router /api/v1/import/jobs/{id} [delete]
+func DeleteImportJob(c *gin.Context) {
+	job, ok := findImportJob(c)
+	if !ok {
+		return
+	}
+	if job.Status == entity.ImportJobParsing {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务正在解析，请稍后再试"})
+		return
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", job.ID).Delete(&entity.ImportJobItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(job).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除导入任务失败"})
+		return
+	}
+	removeImportFile(job.FileID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "导入任务已删除"})
+}
+
+// findImportJob 查找路径中的导入任务，只有创建者和管理员可以访问；未找到时已写入响应
+func findImportJob(c *gin.Context) (*entity.ImportJob, bool) {
+	var job entity.ImportJob
+	if err := database.DB.First(&job, c.Param("id")).Error; err != nil ||
+		(job.CreatorID != c.GetUint("userID") && c.GetString("role") != "admin") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导入任务不存在"})
+		return nil, false
+	}
+	return &job, true
+}
+
+// importItemQuestion 将保存的预览题目还原为导入题目响应，状态以数据库中的为准
+func importItemQuestion(item *entity.ImportJobItem) ImportQuestionResponse {
+	var question ImportQuestionResponse
+	json.Unmarshal([]byte(item.Data), &question)
+	question.ID = item.ID
+	question.Status = item.Status
+	question.QuestionID = item.QuestionID
+	if question.Options == nil {
+		question.Options = []string{}
+	}
+	if question.MediaURLs == nil {
+		question.MediaURLs = []string{}
+	}
+	return question
+}
+
+// applyImportQuestionData 用老师编辑后的内容覆盖预览题目
+func applyImportQuestionData(question *ImportQuestionResponse, data *request.ImportQuestionData) {
+	question.Title = data.Title
+	question.Type = data.Type
+	question.Difficulty = data.Difficulty
+	question.Grade = data.Grade
+	question.Subject = data.Subject
+	question.Topic = data.Topic
+	question.Options = data.Options
+	question.Answer = data.Answer
+	question.Explanation = data.Explanation
+	question.MediaURLs = data.MediaURLs
+	question.LayoutType = data.LayoutType
+	question.ElementData = data.ElementData
+	question.Tags = data.Tags
+	question.GradingConfig = data.GradingConfig
+	if question.Options == nil {
+		question.Options = []string{}
+	}
+	if question.MediaURLs == nil {
+		question.MediaURLs = []string{}
+	}
+}
+
+// importQuestionData 预览题目转换为确认导入的题目数据
+func importQuestionData(question *ImportQuestionResponse) request.ImportQuestionData {
+	return request.ImportQuestionData{
+		Title:         question.Title,
+		Type:          question.Type,
+		Difficulty:    question.Difficulty,
+		Grade:         question.Grade,
+		Subject:       question.Subject,
+		Topic:         question.Topic,
+		Options:       question.Options,
+		Answer:        question.Answer,
+		Explanation:   question.Explanation,
+		MediaURLs:     question.MediaURLs,
+		LayoutType:    question.LayoutType,
+		ElementData:   question.ElementData,
+		Tags:          question.Tags,
+		Status:        question.Status,
+		ErrorMessage:  question.ErrorMessage,
+		GradingConfig: question.GradingConfig,
+	}
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfMinCharsPerPage 平均每页提取到的文字少于该数量时，认为是没有文字层的扫描件
+const pdfMinCharsPerPage = 10
+
+// pdfPageNumberPattern 页眉页脚中的页码，如 "第 1 页 共 3 页" "- 1 -" "1/3"
+var pdfPageNumberPattern = regexp.MustCompile(`^(?:第\s*\d+\s*页.*|-\s*\d+\s*-|\d+\s*/\s*\d+)$`)
+
+// 解析PDF文件：按文字层逐页提取文字行，用与Word相同的规则切分为题目
+// 扫描件没有文字层，无法解析，需要使用图片导入
+func parsePDFFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
+	f, r, err := pdf.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开PDF文件: %v", err)
+	}
+	defer f.Close()
+
+	pages := r.NumPage()
+	var lines []worksheetLine
+	chars := 0
+	for i := 1; i <= pages; i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		pageLines, err := readPDFLines(page)
+		if err != nil {
+			return nil, fmt.Errorf("无法读取PDF第%d页: %v", i, err)
+		}
+		for _, text := range pageLines {
+			chars += len([]rune(strings.Join(strings.Fields(text), "")))
+			if pdfPageNumberPattern.MatchString(text) {
+				continue
+			}
+			lines = append(lines, worksheetLine{Text: text, Page: i})
+		}
+	}
+	if pages == 0 || chars < pdfMinCharsPerPage*pages {
+		return nil, errors.New("PDF中没有可提取的文字，可能是扫描件，请上传文字版PDF或使用图片导入")
+	}
+
+	return buildWorksheetQuestions(segmentWorksheet(lines), autoDetect, defaultGrade, defaultSubject), nil
+}
+
+// readPDFLines 提取一页的文字行，按从上到下的顺序返回
+// 纵坐标相近的字归为一行，行内按横坐标排列，字间距明显大于正常间距时补一个空格
+func readPDFLines(page pdf.Page) (lines []string, err error) {
+	defer func() {
+		// PDF内容流格式错误时解析库会 panic
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	texts := page.Content().Text
+	sort.SliceStable(texts, func(i, j int) bool { return texts[i].Y > texts[j].Y })
+
+	var rows [][]pdf.Text
+	for _, text := range texts {
+		if text.S == "" {
+			continue
+		}
+		if n := len(rows); n > 0 {
+			first := rows[n-1][0]
+			if math.Abs(first.Y-text.Y) <= math.Max(first.FontSize, text.FontSize)*0.5 {
+				rows[n-1] = append(rows[n-1], text)
+				continue
+			}
+		}
+		rows = append(rows, []pdf.Text{text})
+	}
+
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool { return row[i].X < row[j].X })
+		var b strings.Builder
+		for i, text := range row {
+			if i > 0 {
+				prev := row[i-1]
+				gap := text.X - (prev.X + prev.W)
+				if gap > math.Max(prev.FontSize, 1)*0.25 && !endsWithSpace(b.String()) && strings.TrimSpace(text.S) != "" {
+					b.WriteString(" ")
+				}
+			}
+			b.WriteString(text.S)
+		}
+		if line := strings.TrimSpace(b.String()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func endsWithSpace(s string) bool {
+	if s == "" {
+		return true
+	}
+	r := []rune(s)
+	return unicode.IsSpace(r[len(r)-1])
+}
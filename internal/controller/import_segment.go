@@ -0,0 +1,398 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+)
+
+// 练习卷分段：把文档中按顺序排列的文字行和图片切分为题目，Word和PDF导入共用。
+// 识别的结构：
+//   - 大题标题，如 "一、选择题"，用于推断其下题目的题型
+//   - 题号，如 "1." "1、" "(1)" "第1题"
+//   - 选项，如 "A. 6  B. 7  C. 8"，可以一行多个
+//   - 答案和解析，如 "答案：B" "【解析】..."
+//   - 文末的答案汇总，如 "参考答案" 之后的 "1. B  2. 对  3. 7"，可以按大题分组
+
+// 自动编号（Word列表）的类型
+const (
+	listQuestion = "question" // 数字编号，视为题号
+	listOption   = "option"   // 字母编号，视为选项
+	listSection  = "section"  // 中文数字编号，视为大题标题
+)
+
+// 每个分段问题使置信度降低的比例
+const segmentWarningPenalty = 0.8
+
+// worksheetLine 文档中的一行文字及行内的图片
+type worksheetLine struct {
+	Text   string
+	Images []string // 行内图片的访问地址
+	List   string   // 自动编号的类型，没有自动编号时为空
+	Page   int      // 所在页码，没有分页信息时为0
+}
+
+// worksheetQuestion 分段得到的一道题
+type worksheetQuestion struct {
+	Number      int
+	Page        int                 // 题号所在的页码
+	Section     entity.QuestionType // 所在大题对应的题型，无法判断时为空
+	Part        int                 // 所在大题的序号，从1开始，第一个大题之前为0
+	Title       []string
+	Options     []string
+	Answer      string
+	Explanation []string
+	Images      []string
+	Warnings    []string
+}
+
+var (
+	// questionNumberPattern 行首的题号
+	questionNumberPattern = regexp.MustCompile(`^(?:第\s*(\d{1,3})\s*题\s*[:：.．、]?|(\d{1,3})\s*[.．、)）]|[(（](\d{1,3})[)）])\s*`)
+	// sectionPattern 大题标题
+	sectionPattern = regexp.MustCompile(`^[一二三四五六七八九十]{1,3}\s*[、.．]\s*(.*)$`)
+	// optionMarkPattern 选项字母，行首或空白之后
+	optionMarkPattern = regexp.MustCompile(`(?:^|\s)[(（]?([A-H])\s*[.．、:：)）]`)
+	// answerPattern 答案行
+	answerPattern = regexp.MustCompile(`(?i)^(?:【\s*(?:参考答案|正确答案|答案)\s*】|(?:参考答案|正确答案|答案|answer)\s*(?:[:：]|$))\s*(.*)$`)
+	// explanationPattern 解析行
+	explanationPattern = regexp.MustCompile(`(?i)^(?:【\s*(?:解析|分析|详解)\s*】|(?:解析|分析|详解|explanation)\s*(?:[:：]|$))\s*(.*)$`)
+	// answerKeyEntryPattern 答案汇总中的一项，如 "3. B"
+	answerKeyEntryPattern = regexp.MustCompile(`(\d{1,3})\s*[.．、:：)）]\s*(\S+)`)
+)
+
+// sectionTypeKeywords 大题标题中的关键字对应的题型，按顺序匹配
+var sectionTypeKeywords = []struct {
+	keyword string
+	qType   entity.QuestionType
+}{
+	{"多选", entity.TypeMultiChoice},
+	{"选择", entity.TypeChoice},
+	{"判断", entity.TypeJudge},
+	{"填空", entity.TypeFillIn},
+	{"计算", entity.TypeMath},
+	{"口算", entity.TypeMath},
+	{"比较", entity.TypeComparison},
+	{"找规律", entity.TypeReasoning},
+	{"推理", entity.TypeReasoning},
+	{"圈", entity.TypeCircleSelect},
+}
+
+// segmentWorksheet 将文档的行切分为题目，第一道题之前的内容（如试卷标题、说明）被忽略
+func segmentWorksheet(lines []worksheetLine) []*worksheetQuestion {
+	var (
+		questions     []*worksheetQuestion
+		current       *worksheetQuestion
+		section       entity.QuestionType
+		part          int             // 已出现的大题数
+		pendingImages []string        // 第一道题之前或大题标题之后的图片，归入下一道题
+		listNumber    int             // 自动编号的题号
+		inExplanation bool            // 后续文字属于解析
+		awaitAnswer   bool            // 上一行是单独的 "答案：" 标题
+		answerKey     *answerKeyState // 已进入文末的答案汇总
+	)
+
+	startQuestion := func(number, page int, text string) {
+		current = &worksheetQuestion{Number: number, Page: page, Section: section, Part: part, Images: pendingImages}
+		pendingImages = nil
+		if len(questions) > 0 {
+			if last := questions[len(questions)-1].Number; number != last+1 && number != 1 {
+				current.Warnings = append(current.Warnings, fmt.Sprintf("题号不连续，上一题为第%d题", last))
+			}
+		}
+		if text != "" {
+			current.Title = append(current.Title, text)
+		}
+		questions = append(questions, current)
+		inExplanation, awaitAnswer = false, false
+	}
+
+	for _, line := range lines {
+		text := strings.TrimSpace(line.Text)
+
+		if answerKey != nil {
+			answerKey.apply(line, text)
+			continue
+		}
+		if awaitAnswer && text != "" {
+			awaitAnswer = false
+			entries := answerKeyEntryPattern.FindAllStringSubmatch(text, -1)
+			// 单独的答案标题之后是大题标题或多项答案时，进入文末的答案汇总
+			if len(entries) >= 2 || len(entries) == 1 && (current == nil || current.Answer != "") || isSectionLine(line, text) {
+				answerKey = newAnswerKeyState(questions)
+				answerKey.apply(line, text)
+				continue
+			}
+			if current != nil {
+				current.Answer = text
+				continue
+			}
+		}
+
+		switch {
+		case text == "":
+		case line.List == listSection:
+			section = sectionType(text)
+			current = nil
+			part++
+		case line.List == listQuestion:
+			listNumber++
+			startQuestion(listNumber, line.Page, text)
+		case line.List == listOption && current != nil:
+			current.Options = append(current.Options, text)
+		case sectionPattern.MatchString(text):
+			section = sectionType(sectionPattern.FindStringSubmatch(text)[1])
+			current, listNumber = nil, 0
+			part++
+		case answerPattern.MatchString(text):
+			answer := strings.TrimSpace(answerPattern.FindStringSubmatch(text)[1])
+			if answer == "" {
+				awaitAnswer = true
+			} else if current != nil {
+				current.Answer = answer
+			}
+			inExplanation = false
+		case explanationPattern.MatchString(text) && current != nil:
+			if explanation := strings.TrimSpace(explanationPattern.FindStringSubmatch(text)[1]); explanation != "" {
+				current.Explanation = append(current.Explanation, explanation)
+			}
+			inExplanation = true
+		default:
+			if number, rest, ok := matchQuestionNumber(text); ok {
+				startQuestion(number, line.Page, rest)
+				break
+			}
+			if current == nil {
+				break
+			}
+			if inExplanation {
+				current.Explanation = append(current.Explanation, text)
+				break
+			}
+			if options, ok := splitOptionLine(text, len(current.Options)); ok {
+				current.Options = append(current.Options, options...)
+				break
+			}
+			if len(current.Options) > 0 {
+				current.Warnings = append(current.Warnings, "选项之后出现了无法识别的文字，已并入题目")
+			}
+			current.Title = append(current.Title, text)
+		}
+
+		if len(line.Images) > 0 {
+			if current != nil {
+				current.Images = append(current.Images, line.Images...)
+			} else {
+				pendingImages = append(pendingImages, line.Images...)
+			}
+		}
+	}
+	return questions
+}
+
+// matchQuestionNumber 识别行首的题号，返回题号和题号之后的文字
+// "3.5 + 2" 这样小数点后紧跟数字的不是题号
+func matchQuestionNumber(text string) (int, string, bool) {
+	match := questionNumberPattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return 0, "", false
+	}
+	rest := text[match[1]:]
+	var digits string
+	for i := 2; i < len(match); i += 2 {
+		if match[i] >= 0 {
+			digits = text[match[i]:match[i+1]]
+			break
+		}
+	}
+	if rest != "" && rest[0] >= '0' && rest[0] <= '9' && text[match[1]-1] != ' ' {
+		return 0, "", false
+	}
+	number, _ := strconv.Atoi(digits)
+	return number, strings.TrimSpace(rest), true
+}
+
+// splitOptionLine 将以选项字母开头的一行拆分为选项，count 为已识别的选项数
+// 选项字母必须从下一个字母开始连续，不连续的部分并入前一个选项
+func splitOptionLine(text string, count int) ([]string, bool) {
+	marks := optionMarkPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(marks) == 0 || strings.TrimSpace(text[:marks[0][0]]) != "" || int(text[marks[0][2]]-'A') != count {
+		return nil, false
+	}
+
+	var options []string
+	start := marks[0][1]
+	next := count + 1
+	for _, mark := range marks[1:] {
+		if int(text[mark[2]]-'A') != next {
+			continue
+		}
+		options = append(options, strings.TrimSpace(text[start:mark[0]]))
+		start = mark[1]
+		next++
+	}
+	options = append(options, strings.TrimSpace(text[start:]))
+	return options, true
+}
+
+// answerKeyState 文末答案汇总的分配状态
+// 各大题的题号可能重新从1开始，部分题目也可能已经在题目下方写了答案，
+// 因此答案按文档顺序逐个大题分配：遇到大题标题时转到对应的大题，没有标题时题号不大于上一项即视为进入下一个大题
+type answerKeyState struct {
+	questions []*worksheetQuestion
+	parts     []int // 有题目的大题序号，按文档顺序
+	cursor    int   // 当前分配到的大题在 parts 中的下标
+	last      int   // 当前大题中上一项的题号，0 表示尚未分配
+	started   bool  // 已经确定第一个大题
+}
+
+func newAnswerKeyState(questions []*worksheetQuestion) *answerKeyState {
+	s := &answerKeyState{questions: questions}
+	for _, question := range questions {
+		if len(s.parts) == 0 || s.parts[len(s.parts)-1] != question.Part {
+			s.parts = append(s.parts, question.Part)
+		}
+	}
+	return s
+}
+
+// apply 处理答案汇总中的一行：大题标题切换当前大题，其余内容按 "题号. 答案" 分配
+func (s *answerKeyState) apply(line worksheetLine, text string) {
+	if isSectionLine(line, text) {
+		title := text
+		if match := sectionPattern.FindStringSubmatch(text); match != nil {
+			title = match[1]
+		}
+		s.enterSection(sectionType(title))
+		text = title
+	}
+	for _, entry := range answerKeyEntryPattern.FindAllStringSubmatch(text, -1) {
+		number, _ := strconv.Atoi(entry[1])
+		if !s.started {
+			s.started = true
+		} else if number <= s.last && s.cursor+1 < len(s.parts) {
+			s.cursor++
+		}
+		s.last = number
+		s.assign(number, entry[2])
+	}
+}
+
+// enterSection 转到下一个大题，题型已知时转到之后第一个题型相同的大题
+func (s *answerKeyState) enterSection(qType entity.QuestionType) {
+	next := 0
+	if s.started {
+		next = s.cursor + 1
+	}
+	if qType != "" {
+		for i := next; i < len(s.parts); i++ {
+			if s.partType(s.parts[i]) == qType {
+				next = i
+				break
+			}
+		}
+	}
+	if next < len(s.parts) {
+		s.cursor = next
+	}
+	s.started, s.last = true, 0
+}
+
+// partType 大题对应的题型
+func (s *answerKeyState) partType(part int) entity.QuestionType {
+	for _, question := range s.questions {
+		if question.Part == part {
+			return question.Section
+		}
+	}
+	return ""
+}
+
+// assign 将答案分配给当前大题中对应题号的题目，题目下方已有答案时保留原答案
+// 当前大题中没有该题号时，分配给该题号中第一道还没有答案的题目
+func (s *answerKeyState) assign(number int, answer string) {
+	if len(s.parts) == 0 {
+		return
+	}
+	for _, question := range s.questions {
+		if question.Part == s.parts[s.cursor] && question.Number == number {
+			if question.Answer == "" {
+				question.Answer = answer
+			}
+			return
+		}
+	}
+	for _, question := range s.questions {
+		if question.Number == number && question.Answer == "" {
+			question.Answer = answer
+			return
+		}
+	}
+}
+
+// isSectionLine 判断一行是否为大题标题
+func isSectionLine(line worksheetLine, text string) bool {
+	return line.List == listSection || sectionPattern.MatchString(text)
+}
+
+// sectionType 根据大题标题推断题型
+func sectionType(title string) entity.QuestionType {
+	for _, item := range sectionTypeKeywords {
+		if strings.Contains(title, item.keyword) {
+			return item.qType
+		}
+	}
+	return ""
+}
+
+// buildWorksheetQuestions 将分段结果转换为导入预览的题目
+// 题型优先使用大题标题推断的题型，否则按题目结构自动识别；分段中的问题会降低置信度
+func buildWorksheetQuestions(segments []*worksheetQuestion, autoDetect bool, defaultGrade, defaultSubject string) []ImportQuestionResponse {
+	questions := make([]ImportQuestionResponse, 0, len(segments))
+	for i, segment := range segments {
+		question := ImportQuestionResponse{
+			Title:         strings.Join(segment.Title, "\n"),
+			Options:       segment.Options,
+			Answer:        segment.Answer,
+			MediaURLs:     segment.Images,
+			OriginalIndex: i + 1,
+			Difficulty:    1,
+			Grade:         defaultGrade,
+			Subject:       defaultSubject,
+			Explanation:   strings.Join(segment.Explanation, "\n"),
+			Warnings:      segment.Warnings,
+			Page:          segment.Page,
+		}
+		if question.Options == nil {
+			question.Options = []string{}
+		}
+		if question.MediaURLs == nil {
+			question.MediaURLs = []string{}
+		}
+
+		var qType entity.QuestionType
+		confidence := 0.0
+		switch {
+		case segment.Section != "":
+			qType, confidence = segment.Section, 0.95
+			if qType == entity.TypeChoice && len(grading.ParseChoiceSet(segment.Answer, segment.Options)) > 1 {
+				qType = entity.TypeMultiChoice
+			}
+		case autoDetect:
+			qType, confidence = detectQuestionType(question.Title, question.Options, question.Answer)
+		}
+		question.Type = string(qType)
+		question.DetectedType = string(qType)
+		for range segment.Warnings {
+			confidence *= segmentWarningPenalty
+		}
+
+		finalizeImportQuestion(&question, confidence, nil)
+		questions = append(questions, question)
+	}
+	return questions
+}
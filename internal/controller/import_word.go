@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"testogo/internal/dedup"
+)
+
+// wordNumberFormats Word自动编号的格式对应的编号类型
+var wordNumberFormats = map[string]string{
+	"decimal":                 listQuestion,
+	"decimalZero":             listQuestion,
+	"decimalEnclosedCircle":   listQuestion,
+	"decimalEnclosedParen":    listQuestion,
+	"decimalEnclosedFullstop": listQuestion,
+	"upperLetter":             listOption,
+	"lowerLetter":             listOption,
+	"chineseCounting":         listSection,
+	"chineseCountingThousand": listSection,
+	"chineseLegalSimplified":  listSection,
+	"ideographTraditional":    listSection,
+	"japaneseCounting":        listSection,
+}
+
+// 解析Word文件：提取文字和图片，按题号、选项和答案切分为题目，图片保存到上传目录
+func parseWordFile(filePath string, autoDetect bool, defaultGrade, defaultSubject string) ([]ImportQuestionResponse, error) {
+	if strings.ToLower(filepath.Ext(filePath)) == ".doc" {
+		return nil, errors.New("暂不支持.doc格式，请另存为.docx后上传")
+	}
+
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开Word文件: %v", err)
+	}
+	defer r.Close()
+
+	doc := &wordDocument{files: make(map[string]*zip.File)}
+	for _, f := range r.File {
+		doc.files[f.Name] = f
+	}
+	if doc.files["word/document.xml"] == nil {
+		return nil, errors.New("无法打开Word文件: 缺少 word/document.xml")
+	}
+	if doc.relations, err = doc.readRelations(); err != nil {
+		return nil, err
+	}
+	if doc.numbering, err = doc.readNumbering(); err != nil {
+		return nil, err
+	}
+
+	lines, err := doc.readLines()
+	if err != nil {
+		return nil, err
+	}
+	return buildWorksheetQuestions(segmentWorksheet(lines), autoDetect, defaultGrade, defaultSubject), nil
+}
+
+// wordDocument 打开的docx文件
+type wordDocument struct {
+	files     map[string]*zip.File
+	relations map[string]string            // 关系ID -> 包内文件路径
+	numbering map[string]map[string]string // 编号ID -> 级别 -> 编号类型
+	images    map[string]string            // 包内图片路径 -> 保存后的访问地址
+}
+
+// readLines 按段落读取正文，段落内的换行拆分为多行，图片归入所在段落的最后一行
+func (d *wordDocument) readLines() ([]worksheetLine, error) {
+	rc, err := d.files["word/document.xml"].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		lines   []worksheetLine
+		text    strings.Builder
+		images  []string
+		numID   string
+		level   string
+		inPara  bool
+		decoder = xml.NewDecoder(rc)
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("无法解析Word文件: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				inPara = true
+				text.Reset()
+				images, numID, level = nil, "", "0"
+			case "t":
+				var s string
+				if err := decoder.DecodeElement(&s, &t); err != nil {
+					return nil, fmt.Errorf("无法解析Word文件: %v", err)
+				}
+				text.WriteString(s)
+			case "tab":
+				text.WriteString(" ")
+			case "br", "cr":
+				text.WriteString("\n")
+			case "numId":
+				numID = xmlAttr(t, "val")
+			case "ilvl":
+				level = xmlAttr(t, "val")
+			case "blip", "imagedata":
+				id := xmlAttr(t, "embed")
+				if id == "" {
+					id = xmlAttr(t, "id")
+				}
+				if url := d.saveImage(id); url != "" {
+					images = append(images, url)
+				}
+			case "Fallback", "delText", "instrText":
+				// 兼容内容中的图片与 Choice 中的重复；删除的修订和域代码不是正文
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("无法解析Word文件: %v", err)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "p" || !inPara {
+				continue
+			}
+			inPara = false
+			paragraph := strings.Split(text.String(), "\n")
+			for i, s := range paragraph {
+				line := worksheetLine{Text: s}
+				if i == 0 {
+					line.List = d.numbering[numID][level]
+				}
+				if i == len(paragraph)-1 {
+					line.Images = images
+				}
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// readRelations 读取正文的关系表，用于查找图片文件
+func (d *wordDocument) readRelations() (map[string]string, error) {
+	relations := make(map[string]string)
+	f := d.files["word/_rels/document.xml.rels"]
+	if f == nil {
+		return relations, nil
+	}
+	var rels struct {
+		Relationships []struct {
+			ID         string `xml:"Id,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(f, &rels); err != nil {
+		return nil, err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.TargetMode == "External" {
+			continue
+		}
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(rel.Target, "/") {
+			target = path.Join("word", rel.Target)
+		}
+		relations[rel.ID] = target
+	}
+	return relations, nil
+}
+
+// readNumbering 读取自动编号的定义，得到每个编号ID各级别的编号类型
+func (d *wordDocument) readNumbering() (map[string]map[string]string, error) {
+	numbering := make(map[string]map[string]string)
+	f := d.files["word/numbering.xml"]
+	if f == nil {
+		return numbering, nil
+	}
+	type valAttr struct {
+		Val string `xml:"val,attr"`
+	}
+	var defs struct {
+		AbstractNums []struct {
+			ID     string `xml:"abstractNumId,attr"`
+			Levels []struct {
+				Level  string  `xml:"ilvl,attr"`
+				Format valAttr `xml:"numFmt"`
+			} `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID       string  `xml:"numId,attr"`
+			Abstract valAttr `xml:"abstractNumId"`
+		} `xml:"num"`
+	}
+	if err := decodeZipXML(f, &defs); err != nil {
+		return nil, err
+	}
+
+	abstract := make(map[string]map[string]string)
+	for _, def := range defs.AbstractNums {
+		levels := make(map[string]string)
+		for _, lvl := range def.Levels {
+			levels[lvl.Level] = wordNumberFormats[lvl.Format.Val]
+		}
+		abstract[def.ID] = levels
+	}
+	for _, num := range defs.Nums {
+		numbering[num.ID] = abstract[num.Abstract.Val]
+	}
+	return numbering, nil
+}
+
+// saveImage 将包内的图片保存到上传目录，返回访问地址；同一张图片只保存一次，不支持的格式返回空
+func (d *wordDocument) saveImage(relID string) string {
+	name := d.relations[relID]
+	if url, ok := d.images[name]; ok {
+		return url
+	}
+	if d.images == nil {
+		d.images = make(map[string]string)
+	}
+	d.images[name] = ""
+
+	f := d.files[name]
+	if f == nil || f.UncompressedSize64 > MaxFileSize {
+		return ""
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return ""
+	}
+
+	url, err := saveImportImage(name, data)
+	if err != nil {
+		return ""
+	}
+	d.images[name] = url
+	return url
+}
+
+// saveImportImage 将导入文件中的图片保存到上传目录，返回访问地址
+// 文件名使用内容哈希，重复导入同一份文件不会产生重复的图片文件
+func saveImportImage(name string, data []byte) (string, error) {
+	ext := strings.ToLower(path.Ext(name))
+	if !allowedExtensions[ext] {
+		return "", fmt.Errorf("不支持的图片格式: %s", ext)
+	}
+	if len(data) > MaxFileSize {
+		return "", errors.New("图片超过最大大小限制")
+	}
+	if err := os.MkdirAll(UploadDir, 0755); err != nil {
+		return "", err
+	}
+	filename := fmt.Sprintf("import_%s%s", dedup.HashBytes(data)[:16], ext)
+	if err := os.WriteFile(filepath.Join(UploadDir, filename), data, 0644); err != nil {
+		return "", err
+	}
+	return "/media/" + filename, nil
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("无法解析Word文件 %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// xmlAttr 按本地名称读取属性，忽略命名空间
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -7,8 +7,10 @@ import (
 	"strings"
 	"time"
 
+	"testogo/internal/grading"
 	"testogo/internal/model/entity"
 	"testogo/internal/model/request"
+	"testogo/internal/model/response"
 	"testogo/pkg/database"
 
 	"github.com/gin-gonic/gin"
@@ -54,6 +56,9 @@ func CreatePaper(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "包含不存在的题目"})
 		return
 	}
+	if !checkPublishedQuestions(c, req.QuestionIDs) {
+		return
+	}
 
 	// 将题目ID列表转换为JSON字符串
 	questionIDs, err := json.Marshal(req.QuestionIDs)
@@ -74,6 +79,8 @@ func CreatePaper(c *gin.Context) {
 		Questions:   string(questionIDs),
 		StartTime:   req.StartTime,
 		EndTime:     req.EndTime,
+
+		ScoringPolicy: req.ScoringPolicy,
 	}
 
 	if err := database.DB.Create(&paper).Error; err != nil {
@@ -91,6 +98,9 @@ func GetPaper(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
 		return
 	}
+	if isTeacher(c) && !requireAccess(c, paperResource(&paper), accessView) {
+		return
+	}
 
 	// 解析题目ID列表
 	var questionIDs []uint
@@ -105,6 +115,7 @@ func GetPaper(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取题目详情失败"})
 		return
 	}
+	hideHints(c, questions)
 
 	c.JSON(http.StatusOK, gin.H{
 		"id":          paper.ID,
@@ -118,6 +129,7 @@ func GetPaper(c *gin.Context) {
 		"total_questions": len(questions),
 		"start_time":  paper.StartTime,
 		"end_time":    paper.EndTime,
+		"scoring_policy": paper.ScoringPolicy,
 		"questions":   questions,
 		"created_at":  paper.CreatedAt,
 		"updated_at":  paper.UpdatedAt,
@@ -134,6 +146,7 @@ func GetPaper(c *gin.Context) {
 // @Param request body request.CreatePaperRequest true "更新试卷请求参数"
 // @Success 200 {object} map[string]interface{} "更新成功"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "没有修改权限"
 // @Failure 404 {object} map[string]interface{} "试卷不存在"
 // @Failure 500 {object} map[string]interface{} "服务器内部错误"
 // @Router /api/v1/papers/{id} [put]
@@ -144,6 +157,9 @@ func UpdatePaper(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
 		return
 	}
+	if !requireAccess(c, paperResource(&paper), accessEdit) {
+		return
+	}
 
 	var req request.CreatePaperRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -159,6 +175,9 @@ func UpdatePaper(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "包含不存在的题目"})
 			return
 		}
+		if !checkPublishedQuestions(c, req.QuestionIDs) {
+			return
+		}
 
 		// 将题目ID列表转换为JSON字符串
 		questionIDs, err := json.Marshal(req.QuestionIDs)
@@ -180,6 +199,7 @@ func UpdatePaper(c *gin.Context) {
 	// TotalScore is calculated based on correct answers, not stored
 	paper.StartTime = req.StartTime
 	paper.EndTime = req.EndTime
+	paper.ScoringPolicy = req.ScoringPolicy
 
 	if err := database.DB.Save(&paper).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新试卷失败"})
@@ -203,6 +223,14 @@ func SubmitPaper(c *gin.Context) {
 		return
 	}
 
+	var paper entity.Paper
+	if err := database.DB.First(&paper, paperID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
+		return
+	}
+	// 题目未单独配置计分规则时使用试卷的规则
+	defaults := entity.GradingConfig{ScoringPolicy: paper.ScoringPolicy}
+
 	userID := c.GetUint("userID")
 
 	// 开启事务
@@ -213,6 +241,7 @@ func SubmitPaper(c *gin.Context) {
 		}
 	}()
 
+	results := make([]response.GradedAnswerResponse, 0, len(answers))
 	for _, answer := range answers {
 		// 获取题目信息
 		var question entity.Question
@@ -223,23 +252,41 @@ func SubmitPaper(c *gin.Context) {
 		}
 
 		// 判断答案是否正确
-		isCorrect := checkAnswer(question.Type, question.Options, question.Answer, answer.Answer)
+		answerText := submittedAnswer(answer.Answer, answer.Blanks)
+		result := grading.GradeWithDefaults(&question, answerText, defaults)
 
 		// 保存答题记录
 		userAnswer := entity.UserAnswer{
 			UserID:     userID,
 			PaperID:    uint(paperID),
 			QuestionID: answer.QuestionID,
-			Answer:     answer.Answer,
-			IsCorrect:  isCorrect,
+			Answer:     answerText,
+			IsCorrect:  result.IsCorrect,
+			Score:      result.Points,
 			AnswerType: "paper",
+
+			QuestionRevision: question.Revision,
 		}
 
-		if err := tx.Create(&userAnswer).Error; err != nil {
+		// 作答前查看的提示关联到本次答题记录
+		hints := hintScope{UserID: userID, QuestionID: question.ID, Context: entity.HintContextPaper, ContextID: uint(paperID)}
+		used, err := hints.hintsUsed(tx)
+		if err == nil {
+			userAnswer.HintsUsed = used
+			err = tx.Create(&userAnswer).Error
+		}
+		if err == nil {
+			err = hints.attach(tx, userAnswer.ID)
+		}
+		if err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存答题记录失败"})
 			return
 		}
+
+		graded := convertToGradedAnswerResponse(answer.QuestionID, result)
+		graded.HintsUsed = used
+		results = append(results, graded)
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -249,6 +296,7 @@ func SubmitPaper(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "提交成功",
+		"results": results,
 	})
 }
 
@@ -263,16 +311,19 @@ func GetPaperResult(c *gin.Context) {
 	}
 
 	correctCount := 0
+	totalScore := 0.0
 	for _, answer := range answers {
 		if answer.IsCorrect {
 			correctCount++
 		}
+		totalScore += answer.Score
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"answers":       answers,
 		"correct_count": correctCount,
 		"total_count":   len(answers),
+		"total_score":   totalScore,
 	})
 }
 
@@ -321,6 +372,9 @@ func ListPapers(c *gin.Context) {
 	// 构建查询条件
 	query := database.DB.Model(&entity.Paper{}).Preload("Creator")
 
+	// 教师只能看到自己的、共享给自己的和全校可见的试卷
+	query = scopeVisible(c, query, entity.SharePaper)
+
 	// 关键词搜索（搜索标题和描述）
 	if keyword != "" {
 		query = query.Where("title LIKE ? OR description LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
@@ -439,3 +493,26 @@ func ListPapers(c *gin.Context) {
 		"pageSize": pageSize,
 	})
 }
+
+// checkPublishedQuestions 检查试卷中的题目是否都已发布且当前教师有权使用，未通过时直接返回错误
+func checkPublishedQuestions(c *gin.Context, ids []uint) bool {
+	unpublished, err := unpublishedQuestionIDs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查题目状态失败"})
+		return false
+	}
+	if len(unpublished) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只能选用已发布的题目", "question_ids": unpublished})
+		return false
+	}
+	denied, err := inaccessibleIDs(c, &entity.Question{}, entity.ShareQuestion, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查权限失败"})
+		return false
+	}
+	if len(denied) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "包含没有使用权限的题目", "question_ids": denied})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 教师对题目、试卷、作业和强化设置的权限，后一级包含前一级的全部权限
+// 学生不经过这里，仍按发布状态和作业布置访问
+type accessLevel int
+
+const (
+	accessNone  accessLevel = iota
+	accessView              // 查看和使用：组卷、布置作业、复制
+	accessEdit              // 修改内容
+	accessOwner             // 删除和修改共享设置，创建者和管理员
+)
+
+func (a accessLevel) String() string {
+	switch a {
+	case accessView:
+		return "view"
+	case accessEdit:
+		return "edit"
+	case accessOwner:
+		return "owner"
+	}
+	return "none"
+}
+
+// sharedResource 需要检查权限的资源的归属和共享范围
+type sharedResource struct {
+	Type       entity.ShareResourceType
+	ID         uint
+	CreatorID  uint
+	Visibility entity.Visibility
+}
+
+func questionResource(question *entity.Question) sharedResource {
+	return sharedResource{entity.ShareQuestion, question.ID, question.CreatorID, question.Visibility}
+}
+
+func paperResource(paper *entity.Paper) sharedResource {
+	return sharedResource{entity.SharePaper, paper.ID, paper.CreatorID, paper.Visibility}
+}
+
+func homeworkResource(homework *entity.Homework) sharedResource {
+	return sharedResource{entity.ShareHomework, homework.ID, homework.CreatorID, homework.Visibility}
+}
+
+func reinforcementSettingResource(setting *entity.ReinforcementSetting) sharedResource {
+	return sharedResource{entity.ShareReinforcementSetting, setting.ID, setting.CreatorID, setting.Visibility}
+}
+
+// shareTables 各类资源的表名，用于按共享范围过滤列表
+var shareTables = map[entity.ShareResourceType]string{
+	entity.ShareQuestion:             "question",
+	entity.SharePaper:                "paper",
+	entity.ShareHomework:             "homework",
+	entity.ShareReinforcementSetting: "reinforcement_setting",
+}
+
+// resourceAccess 当前用户对资源的权限
+// 管理员和创建者拥有全部权限；共享成员按角色查看或修改；全校可见的资源所有教师都可以查看；私有资源只有创建者可以访问
+func resourceAccess(c *gin.Context, resource sharedResource) (accessLevel, error) {
+	userID := c.GetUint("userID")
+	if c.GetString("role") == string(entity.RoleAdmin) || resource.CreatorID == userID {
+		return accessOwner, nil
+	}
+
+	level := accessNone
+	switch resource.Visibility {
+	case entity.VisibilityPrivate:
+		return accessNone, nil
+	case entity.VisibilitySchool:
+		level = accessView
+	}
+
+	var share entity.ResourceShare
+	err := database.DB.Where("resource_type = ? AND resource_id = ? AND user_id = ?", resource.Type, resource.ID, userID).
+		Take(&share).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return level, nil
+	}
+	if err != nil {
+		return accessNone, err
+	}
+	if share.Role == entity.ShareCoEditor {
+		return accessEdit, nil
+	}
+	return accessView, nil
+}
+
+// hasAccess 当前用户对资源的权限是否达到 level
+func hasAccess(c *gin.Context, resource sharedResource, level accessLevel) (bool, error) {
+	access, err := resourceAccess(c, resource)
+	if err != nil {
+		return false, err
+	}
+	return access >= level, nil
+}
+
+// requireAccess 权限不足时直接返回403
+func requireAccess(c *gin.Context, resource sharedResource, level accessLevel) bool {
+	ok, err := hasAccess(c, resource, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查权限失败"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": accessDeniedMessage(level)})
+		return false
+	}
+	return true
+}
+
+func accessDeniedMessage(level accessLevel) string {
+	switch level {
+	case accessOwner:
+		return "只有创建者和管理员可以执行该操作"
+	case accessEdit:
+		return "没有修改权限，请联系创建者添加为协作编辑者"
+	}
+	return "没有查看权限"
+}
+
+// isTeacher 教师访问他人的资源时需要检查共享权限，管理员不受限制，学生按发布状态和作业布置访问
+func isTeacher(c *gin.Context) bool {
+	return c.GetString("role") == string(entity.RoleTeacher)
+}
+
+// isStaff 教师或管理员，可以查看未发布的题目和模板
+func isStaff(c *gin.Context) bool {
+	role := c.GetString("role")
+	return role == string(entity.RoleTeacher) || role == string(entity.RoleAdmin)
+}
+
+// scopeVisible 教师只能列出自己创建的、共享给自己的和全校可见的资源，管理员和学生不受限制
+func scopeVisible(c *gin.Context, query *gorm.DB, resourceType entity.ShareResourceType) *gorm.DB {
+	if !isTeacher(c) {
+		return query
+	}
+	userID := c.GetUint("userID")
+	shared := database.DB.Model(&entity.ResourceShare{}).
+		Select("resource_id").
+		Where("resource_type = ? AND user_id = ?", resourceType, userID)
+	return query.Where(fmt.Sprintf("(%[1]s.creator_id = ? OR %[1]s.visibility = ? OR (%[1]s.visibility = ? AND %[1]s.id IN (?)))",
+		shareTables[resourceType]), userID, entity.VisibilitySchool, entity.VisibilityShared, shared)
+}
+
+// inaccessibleIDs 返回列表中当前教师无权查看和使用的资源ID，管理员和学生返回空
+func inaccessibleIDs(c *gin.Context, model interface{}, resourceType entity.ShareResourceType, ids []uint) ([]uint, error) {
+	if !isTeacher(c) || len(ids) == 0 {
+		return nil, nil
+	}
+	var visible []uint
+	if err := scopeVisible(c, database.DB.Model(model), resourceType).
+		Where("id IN ?", ids).Pluck("id", &visible).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[uint]bool, len(visible))
+	for _, id := range visible {
+		found[id] = true
+	}
+	var missing []uint
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+			found[id] = true
+		}
+	}
+	return missing, nil
+}
+
+// checkAccess 同 requireAccess，错误按作业和强化设置接口的格式返回
+func checkAccess(c *gin.Context, resource sharedResource, level accessLevel) bool {
+	ok, err := hasAccess(c, resource, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: "Failed to check permissions",
+		})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, response.ErrorResponse{
+			Error: "Access denied",
+		})
+		return false
+	}
+	return true
+}
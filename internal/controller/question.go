@@ -4,20 +4,27 @@ import (
 	"encoding/json"
 	"math/rand"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	"testogo/internal/dedup"
+	"testogo/internal/grading"
 	"testogo/internal/model/entity"
 	"testogo/internal/model/request"
 	"testogo/internal/model/response"
+	"testogo/internal/payload"
+	"testogo/internal/search"
 	"testogo/pkg/database"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // @Summary 创建题目
-// @Description 创建新的题目
+// @Description 创建新的题目；选项、媒体地址和元素数据按题型校验，选项统一保存为 QuestionOption 数组，校验失败时 fields 中返回字段级错误
 // @Tags 题目
 // @Accept json
 // @Produce json
@@ -33,6 +40,21 @@ func CreateQuestion(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := grading.ValidateGradingConfig(req.GradingConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, ok := normalizeQuestionPayload(c, payload.Payload{
+		Type:        entity.QuestionType(req.Type),
+		Options:     req.Options,
+		Answer:      req.Answer,
+		MediaURLs:   req.MediaURLs,
+		ElementData: req.ElementData,
+		Hints:       req.Hints,
+	})
+	if !ok {
+		return
+	}
 
 	userID := c.GetUint("userID")
 
@@ -92,17 +114,26 @@ func CreateQuestion(c *gin.Context) {
 		TopicID:     topicID,
 		Subject:     subjectCode, // 保持向后兼容
 		Topic:       topicCode,   // 保持向后兼容
-		Options:     req.Options,
-		Answer:      req.Answer,
+		Options:     fields.Options,
+		Answer:      deriveAnswerKey(entity.QuestionType(req.Type), fields.ElementData, req.Answer),
 		Explanation: req.Explanation,
 		CreatorID:   userID,
-		MediaURLs:   req.MediaURLs,
+		MediaURLs:   fields.MediaURLs,
 		LayoutType:  req.LayoutType,
-		ElementData: req.ElementData,
+		ElementData: fields.ElementData,
 		Tags:        req.Tags,
+
+		GradingConfig: encodeGradingConfig(req.GradingConfig),
+		Status:        entity.QuestionStatusDraft,
+		Hints:         fields.Hints,
 	}
 
-	if err := database.DB.Create(&question).Error; err != nil {
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&question).Error; err != nil {
+			return err
+		}
+		return recordQuestionRevision(tx, &question, userID, "创建题目")
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建题目失败"})
 		return
 	}
@@ -131,73 +162,34 @@ func CreateQuestion(c *gin.Context) {
 // @Param grade query string false "年级"
 // @Param subject query string false "科目"
 // @Param topic query string false "主题"
+// @Param q query string false "全文检索关键词，匹配标题、选项、解析和标签，结果按相关度排序"
+// @Param tags query string false "标签过滤，多个标签用逗号分隔"
+// @Param tag_match query string false "多个标签的匹配方式：or（任一，默认）或 and（全部）"
 // @Success 200 {array} entity.Question "题目列表"
 // @Failure 500 {object} map[string]interface{} "服务器内部错误"
 // @Router /api/v1/questions [get]
 func ListQuestions(c *gin.Context) {
 	var questions []entity.Question
-	query := database.DB.Preload("SubjectRef").Preload("TopicRef").Order("id desc")
-
-	// 支持按类型、难度、年级过滤
-	if qType := c.Query("type"); qType != "" {
-		query = query.Where("type = ?", qType)
-	}
-	if difficulty := c.Query("difficulty"); difficulty != "" {
-		// 将字符串难度转换为数字
-		var difficultyInt int
-		switch difficulty {
-		case "easy":
-			difficultyInt = 1
-		case "medium":
-			difficultyInt = 2
-		case "hard":
-			difficultyInt = 3
-		default:
-			// 如果是数字字符串，直接转换
-			if d, err := strconv.Atoi(difficulty); err == nil {
-				difficultyInt = d
-			} else {
-				difficultyInt = 1 // 默认简单
-			}
-		}
-		query = query.Where("difficulty = ?", difficultyInt)
-	}
-	if grade := c.Query("grade"); grade != "" {
-		query = query.Where("grade = ?", grade)
-	}
-
-	// 支持按科目过滤 - 优先使用ID，兼容字符串
-	if subjectID := c.Query("subject_id"); subjectID != "" {
-		// 将字符串ID转换为数字
-		if subjectIDNum, err := strconv.Atoi(subjectID); err == nil && subjectIDNum > 0 {
-			query = query.Where("subject_id = ?", subjectIDNum)
-		}
-	} else if subject := c.Query("subject"); subject != "" {
-		// 兼容字符串查询 - 查找对应的科目ID
-		var subjectEntity entity.Subject
-		if err := database.DB.Where("code = ? AND is_active = ?", subject, true).First(&subjectEntity).Error; err == nil {
-			query = query.Where("subject_id = ?", subjectEntity.ID)
-		} else {
-			// 如果找不到科目ID，则使用旧的字符串匹配
-			query = query.Where("subject = ?", subject)
-		}
+	keyword := strings.TrimSpace(c.Query("q"))
+	query, hits, err := filterQuestions(c, database.DB.Preload("SubjectRef").Preload("TopicRef"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索题目失败"})
+		return
 	}
 
-	// 支持按主题过滤 - 优先使用ID，兼容字符串
-	if topicID := c.Query("topic_id"); topicID != "" {
-		// 将字符串ID转换为数字
-		if topicIDNum, err := strconv.Atoi(topicID); err == nil && topicIDNum > 0 {
-			query = query.Where("topic_id = ?", topicIDNum)
-		}
-	} else if topic := c.Query("topic"); topic != "" {
-		// 兼容字符串查询 - 查找对应的主题ID
-		var topicEntity entity.Topic
-		if err := database.DB.Where("code = ? AND is_active = ?", topic, true).First(&topicEntity).Error; err == nil {
-			query = query.Where("topic_id = ?", topicEntity.ID)
-		} else {
-			// 如果找不到主题ID，则使用旧的字符串匹配
-			query = query.Where("topic = ?", topic)
+	// 按关键词搜索时按相关度排序，否则按ID倒序
+	scores := make(map[uint]float64, len(hits))
+	if len(hits) > 0 {
+		ids := make([]uint, len(hits))
+		for i, hit := range hits {
+			ids[i] = hit.QuestionID
+			scores[hit.QuestionID] = hit.Score
 		}
+		query = query.Clauses(clause.OrderBy{
+			Expression: clause.Expr{SQL: "FIELD(id, ?)", Vars: []interface{}{ids}, WithoutParentheses: true},
+		})
+	} else {
+		query = query.Order("id desc")
 	}
 
 	// 分页处理 - 支持limit参数和count参数
@@ -230,8 +222,6 @@ func ListQuestions(c *gin.Context) {
 	// 检查是否为专项练习模式（有count参数）
 	isRandomMode := c.Query("count") != ""
 
-	var err error
-
 	// 根据模式选择排序方式
 	if isRandomMode {
 		// 专项练习模式：使用随机排序
@@ -262,6 +252,7 @@ func ListQuestions(c *gin.Context) {
 	// 根据模式选择响应格式
 	if isRandomMode {
 		// 专项练习模式：返回简化的题目数组（与原GetRandomQuestions保持一致）
+		hideHints(c, questions)
 		c.JSON(http.StatusOK, questions)
 	} else {
 		// 普通模式：返回带统计数据的响应
@@ -299,6 +290,13 @@ func ListQuestions(c *gin.Context) {
 				UpdatedAt:   question.UpdatedAt,
 				UsageCount:  totalAttempts,
 				CorrectRate: correctRate,
+
+				GradingConfig: question.GradingConfig,
+				Status:        string(question.Status),
+			}
+			if keyword != "" {
+				questionWithStats.SearchScore = scores[question.ID]
+				questionWithStats.Highlights = search.Highlights(&question, keyword)
 			}
 			questionsWithStats = append(questionsWithStats, questionWithStats)
 		}
@@ -315,6 +313,141 @@ func ListQuestions(c *gin.Context) {
 	}
 }
 
+// filterQuestions 按 ListQuestions 的查询参数过滤题目，题目列表和导出共用
+// 带有关键词 q 时只保留全文检索命中的题目，并按相关度从高到低返回命中结果
+// 先按其他条件过滤命中的题目再截取前 maxSearchHits 条，避免相关度高但被过滤掉的题目挤掉符合条件的题目
+func filterQuestions(c *gin.Context, query *gorm.DB) (*gorm.DB, []search.Hit, error) {
+	query = applyQuestionFilters(c, query)
+	keyword := strings.TrimSpace(c.Query("q"))
+	if keyword == "" {
+		return query, nil, nil
+	}
+
+	hits, err := search.Search(database.DB, keyword, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.QuestionID
+	}
+	var matched []uint
+	if len(ids) > 0 {
+		if err := applyQuestionFilters(c, database.DB.Model(&entity.Question{})).
+			Where("id IN ?", ids).Pluck("id", &matched).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	found := make(map[uint]bool, len(matched))
+	for _, id := range matched {
+		found[id] = true
+	}
+
+	filtered := make([]search.Hit, 0, len(matched))
+	ids = ids[:0]
+	for _, hit := range hits {
+		if found[hit.QuestionID] && len(filtered) < maxSearchHits {
+			filtered = append(filtered, hit)
+			ids = append(ids, hit.QuestionID)
+		}
+	}
+	return query.Where("id IN ?", ids), filtered, nil
+}
+
+// applyQuestionFilters 按类型、难度、年级、状态、标签、科目、主题等查询参数过滤题目，不包括关键词
+func applyQuestionFilters(c *gin.Context, query *gorm.DB) *gorm.DB {
+	// 支持按类型、难度、年级过滤
+	if qType := c.Query("type"); qType != "" {
+		query = query.Where("type = ?", qType)
+	}
+	if difficulty := c.Query("difficulty"); difficulty != "" {
+		// 将字符串难度转换为数字
+		var difficultyInt int
+		switch difficulty {
+		case "easy":
+			difficultyInt = 1
+		case "medium":
+			difficultyInt = 2
+		case "hard":
+			difficultyInt = 3
+		default:
+			// 如果是数字字符串，直接转换
+			if d, err := strconv.Atoi(difficulty); err == nil {
+				difficultyInt = d
+			} else {
+				difficultyInt = 1 // 默认简单
+			}
+		}
+		query = query.Where("difficulty = ?", difficultyInt)
+	}
+	if grade := c.Query("grade"); grade != "" {
+		query = query.Where("grade = ?", grade)
+	}
+
+	// 默认只列出已发布的题目；教师和管理员可按状态查看草稿、待审核和已停用的题目，status=all 查看全部
+	status := c.Query("status")
+	if role := c.GetString("role"); role != "teacher" && role != "admin" {
+		status = ""
+	}
+	switch status {
+	case "all":
+	case "":
+		query = query.Where("status = ?", entity.QuestionStatusPublished)
+	default:
+		query = query.Where("status = ?", status)
+	}
+
+	// 教师只能看到自己的、共享给自己的和全校可见的题目
+	query = scopeVisible(c, query, entity.ShareQuestion)
+
+	// 模板生成的题目默认不出现在题库列表中，可按模板查看
+	if templateID := c.Query("template_id"); templateID != "" {
+		query = query.Where("template_id = ?", templateID)
+	} else {
+		query = query.Where("template_id IS NULL")
+	}
+
+	// 按标签过滤，tag_match=and 时要求同时带有全部标签
+	if tagNames := entity.ParseTagNames(c.Query("tags")); len(tagNames) > 0 {
+		query = query.Where("id IN (?)", taggedQuestionIDs(tagNames, c.Query("tag_match") == "and"))
+	}
+
+	// 支持按科目过滤 - 优先使用ID，兼容字符串
+	if subjectID := c.Query("subject_id"); subjectID != "" {
+		// 将字符串ID转换为数字
+		if subjectIDNum, err := strconv.Atoi(subjectID); err == nil && subjectIDNum > 0 {
+			query = query.Where("subject_id = ?", subjectIDNum)
+		}
+	} else if subject := c.Query("subject"); subject != "" {
+		// 兼容字符串查询 - 查找对应的科目ID
+		var subjectEntity entity.Subject
+		if err := database.DB.Where("code = ? AND is_active = ?", subject, true).First(&subjectEntity).Error; err == nil {
+			query = query.Where("subject_id = ?", subjectEntity.ID)
+		} else {
+			// 如果找不到科目ID，则使用旧的字符串匹配
+			query = query.Where("subject = ?", subject)
+		}
+	}
+
+	// 支持按主题过滤 - 优先使用ID，兼容字符串
+	if topicID := c.Query("topic_id"); topicID != "" {
+		// 将字符串ID转换为数字
+		if topicIDNum, err := strconv.Atoi(topicID); err == nil && topicIDNum > 0 {
+			query = query.Where("topic_id = ?", topicIDNum)
+		}
+	} else if topic := c.Query("topic"); topic != "" {
+		// 兼容字符串查询 - 查找对应的主题ID
+		var topicEntity entity.Topic
+		if err := database.DB.Where("code = ? AND is_active = ?", topic, true).First(&topicEntity).Error; err == nil {
+			query = query.Where("topic_id = ?", topicEntity.ID)
+		} else {
+			// 如果找不到主题ID，则使用旧的字符串匹配
+			query = query.Where("topic = ?", topic)
+		}
+	}
+	return query
+}
+
 func GetQuestion(c *gin.Context) {
 	id := c.Param("id")
 	var question entity.Question
@@ -322,7 +455,17 @@ func GetQuestion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
 		return
 	}
-	c.JSON(http.StatusOK, question)
+	// 学生与题目列表一致，只能查看已发布的题目
+	if role := c.GetString("role"); role != "teacher" && role != "admin" && question.Status != entity.QuestionStatusPublished {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if isTeacher(c) && !requireAccess(c, questionResource(&question), accessView) {
+		return
+	}
+	questions := []entity.Question{question}
+	hideHints(c, questions)
+	c.JSON(http.StatusOK, questions[0])
 }
 
 func UpdateQuestion(c *gin.Context) {
@@ -332,12 +475,34 @@ func UpdateQuestion(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := grading.ValidateGradingConfig(req.GradingConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	var question entity.Question
 	if err := database.DB.First(&question, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
 		return
 	}
+	if !requireAccess(c, questionResource(&question), accessEdit) {
+		return
+	}
+	hints := question.Hints
+	if req.Hints != nil {
+		hints = *req.Hints
+	}
+	fields, ok := normalizeQuestionPayload(c, payload.Payload{
+		Type:        question.Type,
+		Options:     req.Options,
+		Answer:      req.Answer,
+		MediaURLs:   req.MediaURLs,
+		ElementData: req.ElementData,
+		Hints:       hints,
+	})
+	if !ok {
+		return
+	}
 
 	// 更新题目信息
 	updates := map[string]interface{}{
@@ -346,26 +511,78 @@ func UpdateQuestion(c *gin.Context) {
 		"grade":        req.Grade,
 		"subject":      req.Subject,
 		"topic":        req.Topic,
-		"options":      req.Options,
-		"answer":       req.Answer,
+		"options":      fields.Options,
+		"answer":       deriveAnswerKey(question.Type, fields.ElementData, req.Answer),
 		"explanation":  req.Explanation,
-		"media_urls":   req.MediaURLs,
+		"media_urls":   fields.MediaURLs,
 		"layout_type":  req.LayoutType,
-		"element_data": req.ElementData,
+		"element_data": fields.ElementData,
 		"tags":         req.Tags,
+		"hints":        fields.Hints,
+	}
+	if req.GradingConfig != nil {
+		updates["grading_config"] = encodeGradingConfig(req.GradingConfig)
 	}
 
-	if err := database.DB.Model(&question).Updates(updates).Error; err != nil {
+	// 修改和保存新版本在同一事务中完成，内容没有变化时不生成新版本
+	before := question
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureBaselineRevision(tx, &question); err != nil {
+			return err
+		}
+		if err := tx.Model(&question).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&question, question.ID).Error; err != nil {
+			return err
+		}
+		if reflect.DeepEqual(snapshotOf(&before), snapshotOf(&question)) {
+			return nil
+		}
+		if err := recordQuestionRevision(tx, &question, c.GetUint("userID"), req.RevisionComment); err != nil {
+			return err
+		}
+		return revertToDraft(tx, &question, c.GetUint("userID"))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新题目失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	// 标准答案变化时按需在后台重新判分
+	if req.Regrade && answerKeyChanged(&before, &question) {
+		job, err := enqueueRegrade(question.ID, c.GetUint("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "题目已更新，但创建重新判分任务失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功", "revision": question.Revision, "status": question.Status, "regrade_job": job})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "更新成功", "revision": question.Revision, "status": question.Status})
 }
 
 func DeleteQuestion(c *gin.Context) {
-	id := c.Param("id")
-	if err := database.DB.Delete(&entity.Question{}, id).Error; err != nil {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的题目ID"})
+		return
+	}
+	var question entity.Question
+	if err := database.DB.First(&question, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if !requireAccess(c, questionResource(&question), accessOwner) {
+		return
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&entity.Question{}, id).Error; err != nil {
+			return err
+		}
+		return search.RemoveQuestion(tx, uint(id))
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除题目失败"})
 		return
 	}
@@ -401,21 +618,41 @@ func AnswerQuestion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
 		return
 	}
+	if question.Status != entity.QuestionStatusPublished {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目未发布，不能作答"})
+		return
+	}
 
 	// 判断答案是否正确
-	isCorrect := checkAnswer(question.Type, question.Options, question.Answer, req.Answer)
+	answer := submittedAnswer(req.Answer, req.Blanks)
+	result := grading.Grade(&question, answer)
 
 	// 保存答题记录
 	userAnswer := entity.UserAnswer{
 		UserID:     userID,
 		QuestionID: uint(mustParseInt(questionID)),
-		Answer:     req.Answer,
-		IsCorrect:  isCorrect,
+		Answer:     answer,
+		IsCorrect:  result.IsCorrect,
+		Score:      result.Points,
 		AnswerType: "single",
 		PaperID:    0, // 单题答题不关联试卷
+
+		QuestionRevision: question.Revision,
 	}
 
-	if err := database.DB.Create(&userAnswer).Error; err != nil {
+	// 作答前查看的提示关联到本次答题记录
+	hints := hintScope{UserID: userID, QuestionID: question.ID, Context: entity.HintContextPractice}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		used, err := hints.hintsUsed(tx)
+		if err != nil {
+			return err
+		}
+		userAnswer.HintsUsed = used
+		if err := tx.Create(&userAnswer).Error; err != nil {
+			return err
+		}
+		return hints.attach(tx, userAnswer.ID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存答题记录失败"})
 		return
 	}
@@ -425,8 +662,16 @@ func AnswerQuestion(c *gin.Context) {
 		QuestionID:  userAnswer.QuestionID,
 		UserAnswer:  userAnswer.Answer,
 		IsCorrect:   userAnswer.IsCorrect,
+		Score:       result.Score,
+		Points:      userAnswer.Score,
+		Parts:       convertToAnswerPartResponses(result.Parts),
 		Explanation: question.Explanation,
 		AnsweredAt:  userAnswer.CreatedAt,
+
+		MatchedRule:   result.MatchedRule,
+		MatchedAnswer: result.MatchedAnswer,
+
+		HintsUsed: userAnswer.HintsUsed,
 	}
 
 	c.JSON(http.StatusOK, resp)
@@ -459,6 +704,10 @@ func GetQuestionStatistics(c *gin.Context) {
 	database.DB.Model(&entity.UserAnswer{}).Where("question_id = ?", questionID).Count(&totalAttempts)
 	database.DB.Model(&entity.UserAnswer{}).Where("question_id = ? AND is_correct = ?", questionID, true).Count(&correctCount)
 
+	// 答对的记录中查看过提示的数量
+	var hintedCorrectCount int64
+	database.DB.Model(&entity.UserAnswer{}).Where("question_id = ? AND is_correct = ? AND hints_used > 0", questionID, true).Count(&hintedCorrectCount)
+
 	// 计算正确率
 	var accuracyRate float64
 	if totalAttempts > 0 {
@@ -471,117 +720,90 @@ func GetQuestionStatistics(c *gin.Context) {
 		TotalAttempts: totalAttempts,
 		CorrectCount:  correctCount,
 		AccuracyRate:  accuracyRate,
+
+		UnaidedCorrectCount: correctCount - hintedCorrectCount,
+		HintedCorrectCount:  hintedCorrectCount,
 	}
 
 	c.JSON(http.StatusOK, resp)
 }
 
-// 辅助函数：检查答案是否正确
-func checkAnswer(questionType entity.QuestionType, options, correctAnswer, userAnswer string) bool {
-	// 去除前后空格
-	correct := strings.TrimSpace(correctAnswer)
-	user := strings.TrimSpace(userAnswer)
-
-	// 最简单的情况：直接比较（忽略大小写）
-	if strings.EqualFold(correct, user) {
-		return true
-	}
-
-	// 特殊处理判断题
-	if questionType == entity.TypeJudge {
-		correctNorm := normalizeJudgeAnswer(correct)
-		userNorm := normalizeJudgeAnswer(user)
-		if correctNorm == userNorm {
-			return true
-		}
-	}
-
-	// 对于选择题，处理字母和选项内容的映射
-	if questionType == entity.TypeChoice || questionType == entity.TypeMultiChoice {
-		if options != "" {
-			var optionsList []string
-			if err := json.Unmarshal([]byte(options), &optionsList); err == nil && len(optionsList) > 0 {
-
-				// 检查是否都是单个字符的字母答案
-				correctIsLetter := len(correct) == 1 && ((correct >= "A" && correct <= "Z") || (correct >= "a" && correct <= "z"))
-				userIsLetter := len(user) == 1 && ((user >= "A" && user <= "Z") || (user >= "a" && user <= "z"))
-
-				if correctIsLetter && userIsLetter {
-					// 两者都是字母，转换为索引比较
-					correctIndex := -1
-					userIndex := -1
-
-					if correct >= "A" && correct <= "Z" {
-						correctIndex = int(correct[0] - 'A')
-					} else if correct >= "a" && correct <= "z" {
-						correctIndex = int(correct[0] - 'a')
-					}
-
-					if user >= "A" && user <= "Z" {
-						userIndex = int(user[0] - 'A')
-					} else if user >= "a" && user <= "z" {
-						userIndex = int(user[0] - 'a')
-					}
-
-					if correctIndex == userIndex && correctIndex >= 0 && correctIndex < len(optionsList) {
-						return true
-					}
-				} else if correctIsLetter && !userIsLetter {
-					// 正确答案是字母，用户答案是选项内容
-					var correctIndex int
-					if correct >= "A" && correct <= "Z" {
-						correctIndex = int(correct[0] - 'A')
-					} else {
-						correctIndex = int(correct[0] - 'a')
-					}
-
-					if correctIndex >= 0 && correctIndex < len(optionsList) {
-						correctOption := strings.TrimSpace(optionsList[correctIndex])
-						if strings.EqualFold(user, correctOption) {
-							return true
-						}
-					}
-				} else if !correctIsLetter && userIsLetter {
-					// 正确答案是选项内容，用户答案是字母
-					var userIndex int
-					if user >= "A" && user <= "Z" {
-						userIndex = int(user[0] - 'A')
-					} else {
-						userIndex = int(user[0] - 'a')
-					}
-
-					if userIndex >= 0 && userIndex < len(optionsList) {
-						userOption := strings.TrimSpace(optionsList[userIndex])
-						if strings.EqualFold(correct, userOption) {
-							return true
-						}
-					}
-				} else {
-					// 两者都是选项内容，直接比较
-					for _, option := range optionsList {
-						option = strings.TrimSpace(option)
-						if strings.EqualFold(correct, option) && strings.EqualFold(user, option) {
-							return true
-						}
-					}
-				}
-			}
+// 辅助函数：将判分配置序列化为JSON字符串，未提供时返回空字符串
+func encodeGradingConfig(config *entity.GradingConfig) string {
+	if config == nil {
+		return ""
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// 辅助函数：按题型校验并规范化选项、媒体地址和元素数据，校验失败时返回字段级错误
+func normalizeQuestionPayload(c *gin.Context, p payload.Payload) (payload.Payload, bool) {
+	fields, errs := payload.Normalize(p)
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目数据格式错误", "fields": errs})
+		return p, false
+	}
+	return fields, true
+}
+
+// 辅助函数：比较题和数字序列题的答案由题目数据推导，避免答案与数据不一致
+func deriveAnswerKey(questionType entity.QuestionType, elementData, answer string) string {
+	switch questionType {
+	case entity.TypeComparison:
+		if data, ok := grading.ParseComparisonData(elementData); ok {
+			return grading.ComparisonAnswerText(data)
+		}
+	case entity.TypeReasoning:
+		if data, ok := grading.ParseSequenceData(elementData); ok {
+			return grading.SequenceAnswerText(data)
 		}
 	}
+	return answer
+}
 
-	return false
+// 辅助函数：按填空ID提交的答案优先，编码后作为答案文本保存
+func submittedAnswer(answer string, blanks map[string]string) string {
+	if len(blanks) > 0 {
+		return grading.EncodeBlankAnswers(blanks)
+	}
+	return answer
 }
 
-// 标准化判断题答案
-func normalizeJudgeAnswer(answer string) string {
-	answer = strings.ToLower(strings.TrimSpace(answer))
-	switch answer {
-	case "true", "正确", "对", "是", "√", "1", "t":
-		return "true"
-	case "false", "错误", "错", "否", "×", "0", "f":
-		return "false"
-	default:
-		return answer
+// 辅助函数：将分项判分结果转换为响应结构
+func convertToAnswerPartResponses(parts []grading.PartResult) []response.AnswerPartResponse {
+	if len(parts) == 0 {
+		return nil
+	}
+	items := make([]response.AnswerPartResponse, len(parts))
+	for i, part := range parts {
+		items[i] = response.AnswerPartResponse{
+			ID:        part.ID,
+			Answer:    part.Answer,
+			IsCorrect: part.IsCorrect,
+			Score:     part.Score,
+			Feedback:  part.Feedback,
+
+			MatchedRule: part.MatchedRule,
+		}
+	}
+	return items
+}
+
+// 辅助函数：构造单题判分结果响应
+func convertToGradedAnswerResponse(questionID uint, result grading.Result) response.GradedAnswerResponse {
+	return response.GradedAnswerResponse{
+		QuestionID: questionID,
+		IsCorrect:  result.IsCorrect,
+		Score:      result.Score,
+		Points:     result.Points,
+		Parts:      convertToAnswerPartResponses(result.Parts),
+
+		MatchedRule:   result.MatchedRule,
+		MatchedAnswer: result.MatchedAnswer,
 	}
 }
 
@@ -600,6 +822,7 @@ func mustParseInt(s string) int {
 // @Param request body request.BatchUpdateQuestionsRequest true "批量编辑请求参数"
 // @Success 200 {object} map[string]interface{} "返回修改成功的题目数量"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "包含没有修改权限的题目"
 // @Failure 500 {object} map[string]interface{} "服务器内部错误"
 // @Router /api/v1/questions/batch [put]
 func BatchUpdateQuestions(c *gin.Context) {
@@ -643,20 +866,117 @@ func BatchUpdateQuestions(c *gin.Context) {
 		updates["topic"] = req.Updates.Topic
 	}
 	if req.Updates.Difficulty != "" {
-		updates["difficulty"] = req.Updates.Difficulty
+		difficulty, err := strconv.Atoi(req.Updates.Difficulty)
+		if err != nil || difficulty < 1 || difficulty > 5 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "请求参数错误",
+				"fields":  payload.Errors{{Field: "updates.difficulty", Message: "必须为1-5的整数"}},
+			})
+			return
+		}
+		updates["difficulty"] = difficulty
 	}
 	updates["updated_at"] = time.Now()
 
-	// Reason: Batch update questions in database
-	result := database.DB.Model(&entity.Question{}).
-		Where("id IN ?", req.IDs).
-		Updates(updates)
+	// Reason: Every selected question must be editable by the current user
+	var selected []entity.Question
+	if err := database.DB.Where("id IN ?", req.IDs).Find(&selected).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "批量修改失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	var denied []uint
+	for i := range selected {
+		ok, err := hasAccess(c, questionResource(&selected[i]), accessEdit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "检查权限失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if !ok {
+			denied = append(denied, selected[i].ID)
+		}
+	}
+	if len(denied) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":         403,
+			"message":      accessDeniedMessage(accessEdit),
+			"question_ids": denied,
+		})
+		return
+	}
+
+	// Reason: Batch update questions and record a revision for each changed one in one transaction;
+	// questions whose fields already equal the update keep their revision and status
+	var updatedCount int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var selected []entity.Question
+		if err := tx.Where("id IN ?", req.IDs).Find(&selected).Error; err != nil {
+			return err
+		}
+		var questions []entity.Question
+		var changedIDs []uint
+		for i := range selected {
+			after := selected[i]
+			if grade, ok := updates["grade"].(string); ok {
+				after.Grade = grade
+			}
+			if subject, ok := updates["subject"].(string); ok {
+				after.Subject = subject
+			}
+			if topic, ok := updates["topic"].(string); ok {
+				after.Topic = topic
+			}
+			if difficulty, ok := updates["difficulty"].(int); ok {
+				after.Difficulty = difficulty
+			}
+			if reflect.DeepEqual(snapshotOf(&selected[i]), snapshotOf(&after)) {
+				continue
+			}
+			if err := ensureBaselineRevision(tx, &selected[i]); err != nil {
+				return err
+			}
+			questions = append(questions, selected[i])
+			changedIDs = append(changedIDs, selected[i].ID)
+		}
+		if len(changedIDs) == 0 {
+			return nil
+		}
+
+		result := tx.Model(&entity.Question{}).
+			Where("id IN ?", changedIDs).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		updatedCount = result.RowsAffected
+
+		for _, question := range questions {
+			if err := tx.First(&question, question.ID).Error; err != nil {
+				return err
+			}
+			if err := recordQuestionRevision(tx, &question, c.GetUint("userID"), "批量修改"); err != nil {
+				return err
+			}
+			if err := revertToDraft(tx, &question, c.GetUint("userID")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 
-	if result.Error != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "批量修改失败",
-			"error":   result.Error.Error(),
+			"error":   err.Error(),
 		})
 		return
 	}
@@ -665,18 +985,19 @@ func BatchUpdateQuestions(c *gin.Context) {
 		"code":    200,
 		"message": "批量修改成功",
 		"data": gin.H{
-			"updated_count": result.RowsAffected,
+			"updated_count": updatedCount,
 		},
 	})
 }
 
 // @Summary 导入题目（JSON格式）
-// @Description 从JSON文件导入题目，直接创建
+// @Description 从JSON文件导入题目，直接创建；返回与题库中已有题目疑似重复的题目
 // @Tags 题目
 // @Accept multipart/form-data
 // @Produce json
 // @Security BasicAuth
 // @Param file formData file true "题目文件（JSON格式）"
+// @Param skip_duplicates formData boolean false "跳过疑似重复的题目"
 // @Success 200 {object} map[string]interface{} "返回导入成功的题目数量"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
 // @Failure 500 {object} map[string]interface{} "服务器内部错误"
@@ -737,10 +1058,25 @@ func ImportQuestionsJSON(c *gin.Context) {
 		return
 	}
 
+	// Reason: Load fingerprints of the question bank to flag duplicates, rows imported earlier in the file are added as we go
+	prints, bank, err := loadQuestionFingerprints()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查找重复题目失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	duplicateIndex := dedup.NewIndex(prints)
+	skipDuplicates := c.PostForm("skip_duplicates") == "true"
+
 	// Reason: Import questions one by one with validation
 	successCount := 0
 	failedCount := 0
+	skippedCount := 0
 	var errors []string
+	var duplicates []gin.H
 
 	for i, req := range importQuestions {
 		// 基本验证
@@ -760,6 +1096,7 @@ func ImportQuestionsJSON(c *gin.Context) {
 			Difficulty: req.Difficulty,
 			Answer:     req.Answer,
 			CreatorID:  userID,
+			Status:     entity.QuestionStatusDraft,
 		}
 
 		// 处理选项（req.Options是string类型）
@@ -772,12 +1109,72 @@ func ImportQuestionsJSON(c *gin.Context) {
 			question.Explanation = req.Explanation
 		}
 
+		// 媒体、布局和标签，与导出的字段保持一致，导出文件可以原样导入
+		question.MediaURLs = req.MediaURLs
+		question.LayoutType = req.LayoutType
+		question.ElementData = req.ElementData
+		question.Tags = req.Tags
+
+		// 按题型校验选项、媒体地址和元素数据
+		fields, fieldErrs := payload.Normalize(payload.Payload{
+			Type:        question.Type,
+			Options:     question.Options,
+			Answer:      question.Answer,
+			MediaURLs:   question.MediaURLs,
+			ElementData: question.ElementData,
+			Hints:       req.Hints,
+		})
+		if len(fieldErrs) > 0 {
+			errors = append(errors, "第"+strconv.Itoa(i+1)+"题："+fieldErrs.Error())
+			failedCount++
+			continue
+		}
+		question.Options = fields.Options
+		question.MediaURLs = fields.MediaURLs
+		question.ElementData = fields.ElementData
+		question.Hints = fields.Hints
+
+		// 按科目和主题代码关联本环境中的科目和主题
+		var subject entity.Subject
+		if req.Subject != "" && database.DB.Where("code = ? AND is_active = ?", req.Subject, true).First(&subject).Error == nil {
+			question.SubjectID = &subject.ID
+			var topic entity.Topic
+			if req.Topic != "" && database.DB.Where("code = ? AND subject_id = ? AND is_active = ?", req.Topic, subject.ID, true).First(&topic).Error == nil {
+				question.TopicID = &topic.ID
+			}
+		}
+
+		// 处理判分配置
+		if err := grading.ValidateGradingConfig(req.GradingConfig); err != nil {
+			errors = append(errors, "第"+strconv.Itoa(i+1)+"题："+err.Error())
+			failedCount++
+			continue
+		}
+		question.GradingConfig = encodeGradingConfig(req.GradingConfig)
+
+		// 查重
+		if matches := findDuplicateMatches(duplicateIndex, dedup.NewFingerprint(&question, questionMediaHash(&question)), bank); len(matches) > 0 {
+			duplicates = append(duplicates, gin.H{"index": i + 1, "title": req.Title, "matches": matches, "skipped": skipDuplicates})
+			if skipDuplicates {
+				skippedCount++
+				continue
+			}
+		}
+
 		// 保存到数据库
-		if err := database.DB.Create(&question).Error; err != nil {
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&question).Error; err != nil {
+				return err
+			}
+			return recordQuestionRevision(tx, &question, userID, "导入题目")
+		}); err != nil {
 			errors = append(errors, "第"+strconv.Itoa(i+1)+"题："+err.Error())
 			failedCount++
 			continue
 		}
+		imported := question
+		bank[question.ID] = &imported
+		duplicateIndex.Add(dedup.NewFingerprint(&question, question.MediaHash))
 
 		successCount++
 	}
@@ -786,11 +1183,15 @@ func ImportQuestionsJSON(c *gin.Context) {
 		"total":          len(importQuestions),
 		"success_count":  successCount,
 		"failed_count":   failedCount,
+		"skipped_count":  skippedCount,
 	}
 
 	if len(errors) > 0 {
 		responseData["errors"] = errors
 	}
+	if len(duplicates) > 0 {
+		responseData["duplicates"] = duplicates
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"testogo/internal/analysis"
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 题目分析
+// @Description 按经典测量理论分析题目质量：难度（答对比例）、点二列相关和高低分组区分度、选择题各选项的选择情况；
+// @Description 区分度按同一份试卷或同一次作业提交的总分计算，试卷每名学生只计首次作答；作答数达到 min_responses 后标记负区分度和无效干扰项
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param paper_id query int false "只分析该试卷中的作答"
+// @Param homework_id query int false "只分析该作业中的作答"
+// @Param min_responses query int false "给出问题标记所需的最少作答数，默认20"
+// @Success 200 {object} response.QuestionAnalysisResponse "题目分析结果"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/{id}/analysis [get]
+func GetQuestionAnalysis(c *gin.Context) {
+	var question entity.Question
+	if err := database.DB.First(&question, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if !requireAccess(c, questionResource(&question), accessView) {
+		return
+	}
+	paperID, _ := strconv.ParseUint(c.Query("paper_id"), 10, 64)
+	homeworkID, _ := strconv.ParseUint(c.Query("homework_id"), 10, 64)
+	minResponses, _ := strconv.Atoi(c.Query("min_responses"))
+
+	var paperResponses, homeworkResponses []analysis.Response
+	var err error
+	if homeworkID == 0 {
+		if paperResponses, err = paperItemResponses(&question, uint(paperID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询试卷作答记录失败"})
+			return
+		}
+	}
+	if paperID == 0 {
+		if homeworkResponses, err = homeworkItemResponses(&question, uint(homeworkID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询作业作答记录失败"})
+			return
+		}
+	}
+
+	result := analysis.Analyze(append(paperResponses, homeworkResponses...), analysis.CorrectOptions(&question),
+		analysis.Config{MinResponses: minResponses})
+
+	resp := response.QuestionAnalysisResponse{
+		QuestionID:        question.ID,
+		Title:             question.Title,
+		Type:              string(question.Type),
+		Responses:         result.Responses,
+		PaperResponses:    len(paperResponses),
+		HomeworkResponses: len(homeworkResponses),
+		PValue:            result.PValue,
+		PointBiserial:     result.PointBiserial,
+		Discrimination:    result.Discrimination,
+		UpperCount:        result.UpperCount,
+		LowerCount:        result.LowerCount,
+		Flags:             result.Flags,
+		Sufficient:        result.Sufficient,
+	}
+	if resp.Flags == nil {
+		resp.Flags = []string{}
+	}
+	texts := grading.ParseOptionTexts(question.Options)
+	for _, option := range result.Options {
+		resp.Options = append(resp.Options, response.OptionAnalysisResponse{
+			Letter:         string(rune('A' + option.Index)),
+			Text:           texts[option.Index],
+			Correct:        option.Correct,
+			Count:          option.Count,
+			Rate:           option.Rate,
+			UpperRate:      option.UpperRate,
+			LowerRate:      option.LowerRate,
+			NonFunctioning: option.NonFunctioning,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// paperItemResponses 试卷中对该题的作答，每名学生每份试卷只取首次作答，总分为该学生这份试卷各题首次作答的得分之和
+func paperItemResponses(question *entity.Question, paperID uint) ([]analysis.Response, error) {
+	query := database.DB.Where("question_id = ? AND paper_id > 0", question.ID)
+	if paperID > 0 {
+		query = query.Where("paper_id = ?", paperID)
+	}
+	var answers []entity.UserAnswer
+	if err := query.Order("id").Find(&answers).Error; err != nil {
+		return nil, err
+	}
+
+	type attempt struct{ userID, paperID uint }
+	first := make(map[attempt]entity.UserAnswer)
+	var order []attempt
+	paperIDs, userIDs := map[uint]bool{}, map[uint]bool{}
+	for _, answer := range answers {
+		key := attempt{answer.UserID, answer.PaperID}
+		if _, ok := first[key]; !ok {
+			first[key] = answer
+			order = append(order, key)
+			paperIDs[answer.PaperID] = true
+			userIDs[answer.UserID] = true
+		}
+	}
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	// 这些学生在这些试卷中的全部作答，每道题只计首次作答
+	var rows []struct {
+		UserID     uint
+		PaperID    uint
+		QuestionID uint
+		Score      float64
+	}
+	if err := database.DB.Model(&entity.UserAnswer{}).
+		Select("user_id, paper_id, question_id, score").
+		Where("paper_id IN ? AND user_id IN ?", uintKeys(paperIDs), uintKeys(userIDs)).
+		Order("id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	type item struct {
+		attempt
+		questionID uint
+	}
+	counted := make(map[item]bool)
+	totals := make(map[attempt]float64)
+	for _, row := range rows {
+		key := item{attempt{row.UserID, row.PaperID}, row.QuestionID}
+		if !counted[key] {
+			counted[key] = true
+			totals[key.attempt] += row.Score
+		}
+	}
+
+	responses := make([]analysis.Response, 0, len(order))
+	for _, key := range order {
+		answer := first[key]
+		responses = append(responses, analysis.Response{
+			Form:      fmt.Sprintf("paper:%d", key.paperID),
+			IsCorrect: answer.IsCorrect,
+			Score:     answer.Score,
+			Total:     totals[key],
+			Choices:   analysis.ChoiceIndexes(question, answer.Answer),
+		})
+	}
+	return responses, nil
+}
+
+// homeworkItemResponses 作业中对该题的作答，总分为同一次提交中各题的得分之和
+func homeworkItemResponses(question *entity.Question, homeworkID uint) ([]analysis.Response, error) {
+	var answers []struct {
+		SubmissionID uint
+		HomeworkID   uint
+		Answer       string
+		IsCorrect    bool
+		Score        float64
+	}
+	query := database.DB.Model(&entity.HomeworkQuestionAnswer{}).
+		Select("homework_question_answer.submission_id, homework_submission.homework_id, homework_question_answer.answer, "+
+			"homework_question_answer.is_correct, homework_question_answer.score").
+		Joins("JOIN homework_submission ON homework_submission.id = homework_question_answer.submission_id").
+		Where("homework_question_answer.question_id = ?", question.ID)
+	if homeworkID > 0 {
+		query = query.Where("homework_submission.homework_id = ?", homeworkID)
+	}
+	if err := query.Order("homework_question_answer.id").Scan(&answers).Error; err != nil {
+		return nil, err
+	}
+	if len(answers) == 0 {
+		return nil, nil
+	}
+
+	submissionIDs := make(map[uint]bool)
+	for _, answer := range answers {
+		submissionIDs[answer.SubmissionID] = true
+	}
+	var rows []struct {
+		SubmissionID uint
+		Total        float64
+	}
+	if err := database.DB.Model(&entity.HomeworkQuestionAnswer{}).
+		Select("submission_id, SUM(score) AS total").
+		Where("submission_id IN ?", uintKeys(submissionIDs)).
+		Group("submission_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		totals[row.SubmissionID] = row.Total
+	}
+
+	responses := make([]analysis.Response, 0, len(answers))
+	for _, answer := range answers {
+		responses = append(responses, analysis.Response{
+			Form:      fmt.Sprintf("homework:%d", answer.HomeworkID),
+			IsCorrect: answer.IsCorrect,
+			Score:     answer.Score,
+			Total:     totals[answer.SubmissionID],
+			Choices:   analysis.ChoiceIndexes(question, answer.Answer),
+		})
+	}
+	return responses, nil
+}
+
+func uintKeys(set map[uint]bool) []uint {
+	keys := make([]uint, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
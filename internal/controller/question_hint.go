@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/internal/payload"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// hintScope 一次作答中查看提示的范围：同一学生、同一题目、同一场景下尚未关联答题记录的查看记录
+type hintScope struct {
+	UserID     uint
+	QuestionID uint
+	Context    string
+	ContextID  uint
+}
+
+// pending 该范围内尚未关联答题记录的查看记录
+func (s hintScope) pending(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&entity.HintReveal{}).
+		Where("user_id = ? AND question_id = ? AND context = ? AND context_id = ? AND answer_id IS NULL",
+			s.UserID, s.QuestionID, s.Context, s.ContextID)
+}
+
+// hintsUsed 本次作答已查看的提示条数
+func (s hintScope) hintsUsed(tx *gorm.DB) (int, error) {
+	var count int64
+	if err := s.pending(tx).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// attach 将本次作答查看的提示关联到答题记录，下次作答重新从第一条提示开始
+func (s hintScope) attach(tx *gorm.DB, answerID uint) error {
+	return s.pending(tx).Update("answer_id", answerID).Error
+}
+
+// @Summary 查看下一条提示
+// @Description 作答过程中按顺序显示题目的下一条提示并记录，提交答案时记录的提示条数保存在答题记录的 hints_used 中；
+// @Description 在试卷或作业中作答时提供 paper_id 或 homework_id，考试试卷和关闭提示的作业不能查看提示
+// @Tags 题目
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param request body request.RevealHintRequest false "作答场景"
+// @Success 200 {object} response.HintResponse "提示内容"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "当前场景不允许查看提示"
+// @Failure 404 {object} map[string]interface{} "题目不存在或没有更多提示"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/{id}/hints/next [post]
+func RevealQuestionHint(c *gin.Context) {
+	var req request.RevealHintRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.PaperID > 0 && req.HomeworkID > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "paper_id 和 homework_id 只能提供一个"})
+		return
+	}
+
+	var question entity.Question
+	if err := database.DB.First(&question, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if question.Status != entity.QuestionStatusPublished {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目未发布，不能作答"})
+		return
+	}
+
+	scope := hintScope{UserID: c.GetUint("userID"), QuestionID: question.ID, Context: entity.HintContextPractice}
+	switch {
+	case req.PaperID > 0:
+		scope.Context, scope.ContextID = entity.HintContextPaper, req.PaperID
+		if !checkPaperHints(c, req.PaperID, question.ID) {
+			return
+		}
+	case req.HomeworkID > 0:
+		scope.Context, scope.ContextID = entity.HintContextHomework, req.HomeworkID
+		if !checkHomeworkHints(c, req.HomeworkID, scope.UserID, question.ID) {
+			return
+		}
+	}
+
+	hints := payload.ParseHints(question.Hints)
+	var reveal entity.HintReveal
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		used, err := scope.hintsUsed(tx)
+		if err != nil {
+			return err
+		}
+		if used >= len(hints) {
+			return gorm.ErrRecordNotFound
+		}
+		reveal = entity.HintReveal{
+			UserID:     scope.UserID,
+			QuestionID: scope.QuestionID,
+			Context:    scope.Context,
+			ContextID:  scope.ContextID,
+			Level:      used + 1,
+		}
+		return tx.Create(&reveal).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有更多提示"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "记录提示失败"})
+		return
+	}
+
+	hint := hints[reveal.Level-1]
+	c.JSON(http.StatusOK, response.HintResponse{
+		QuestionID: question.ID,
+		Level:      reveal.Level,
+		Total:      len(hints),
+		Type:       hint.Type,
+		Content:    hint.Content,
+	})
+}
+
+// hideHints 学生只能通过查看提示接口逐条获取提示，返回题目时去掉提示内容，只保留条数
+func hideHints(c *gin.Context, questions []entity.Question) {
+	if role := c.GetString("role"); role == "teacher" || role == "admin" {
+		return
+	}
+	for i := range questions {
+		questions[i].HintCount = len(payload.ParseHints(questions[i].Hints))
+		questions[i].Hints = ""
+	}
+}
+
+// checkPaperHints 试卷必须包含该题，考试试卷不能查看提示
+func checkPaperHints(c *gin.Context, paperID, questionID uint) bool {
+	var paper entity.Paper
+	if err := database.DB.First(&paper, paperID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
+		return false
+	}
+	var questionIDs []uint
+	json.Unmarshal([]byte(paper.Questions), &questionIDs)
+	if !containsUint(questionIDs, questionID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "试卷中没有该题目"})
+		return false
+	}
+	if paper.Type == "exam" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "考试中不能查看提示"})
+		return false
+	}
+	return true
+}
+
+// checkHomeworkHints 学生必须被布置了该作业，作业必须包含该题并允许查看提示
+func checkHomeworkHints(c *gin.Context, homeworkID, userID, questionID uint) bool {
+	var homework entity.Homework
+	if err := database.DB.First(&homework, homeworkID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "作业不存在"})
+		return false
+	}
+	var count int64
+	database.DB.Model(&entity.HomeworkAssignment{}).
+		Where("homework_id = ? AND student_id = ?", homeworkID, userID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有被布置该作业"})
+		return false
+	}
+	database.DB.Model(&entity.HomeworkQuestion{}).
+		Where("homework_id = ? AND question_id = ?", homeworkID, questionID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "作业中没有该题目"})
+		return false
+	}
+	if !homework.ShowHints {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该作业不允许查看提示"})
+		return false
+	}
+	return true
+}
+
+func containsUint(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reviewTransitions 各审核操作允许的起始状态和操作后的状态，状态不变的操作目标状态为空
+var reviewTransitions = map[string]struct {
+	from []entity.QuestionStatus
+	to   entity.QuestionStatus
+}{
+	entity.ReviewActionSubmit:  {[]entity.QuestionStatus{entity.QuestionStatusDraft, entity.QuestionStatusRetired}, entity.QuestionStatusInReview},
+	entity.ReviewActionAssign:  {[]entity.QuestionStatus{entity.QuestionStatusInReview}, ""},
+	entity.ReviewActionComment: {nil, ""},
+	entity.ReviewActionApprove: {[]entity.QuestionStatus{entity.QuestionStatusInReview}, entity.QuestionStatusPublished},
+	entity.ReviewActionReject:  {[]entity.QuestionStatus{entity.QuestionStatusInReview}, entity.QuestionStatusDraft},
+	entity.ReviewActionRetire:  {[]entity.QuestionStatus{entity.QuestionStatusPublished}, entity.QuestionStatusRetired},
+}
+
+// @Summary 题目审核操作
+// @Description 执行审核流程操作：submit 提交审核、assign 指定审核人、comment 添加审核意见、approve 通过并发布、reject 驳回为草稿、retire 停用
+// @Description 通过和驳回只能由指定的审核人或管理员操作，且作者不能审核自己的题目（管理员除外）
+// @Description 提交审核和停用需要修改权限，指定审核人只能由创建者或管理员操作
+// @Tags 题目
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param action path string true "审核操作" Enums(submit, assign, comment, approve, reject, retire)
+// @Param request body request.QuestionReviewRequest false "审核人和审核意见"
+// @Success 200 {object} map[string]interface{} "操作成功"
+// @Failure 400 {object} map[string]interface{} "当前状态不允许该操作"
+// @Failure 403 {object} map[string]interface{} "无权执行该操作"
+// @Failure 409 {object} map[string]interface{} "题目在提交审核后已被修改"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Router /api/v1/questions/{id}/review/{action} [post]
+func ReviewQuestion(c *gin.Context) {
+	action := c.Param("action")
+	transition, ok := reviewTransitions[action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的审核操作"})
+		return
+	}
+
+	var req request.QuestionReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+
+	var question entity.Question
+	if err := database.DB.First(&question, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+
+	if transition.from != nil && !containsStatus(transition.from, question.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目当前状态为 " + string(question.Status) + "，不能执行该操作"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	isAdmin := c.GetString("role") == "admin"
+	switch action {
+	case entity.ReviewActionApprove, entity.ReviewActionReject:
+		if !isAdmin && (question.ReviewerID == nil || *question.ReviewerID != userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只有指定的审核人或管理员可以审核该题目"})
+			return
+		}
+		if !isAdmin && question.CreatorID == userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "不能审核自己创建的题目"})
+			return
+		}
+		if action == entity.ReviewActionReject && req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "驳回时必须填写审核意见"})
+			return
+		}
+		// 只能通过提交审核时的版本
+		if action == entity.ReviewActionApprove {
+			var submitted entity.QuestionReview
+			if err := database.DB.Where("question_id = ? AND action = ?", question.ID, entity.ReviewActionSubmit).
+				Order("id desc").First(&submitted).Error; err == nil && submitted.Revision != question.Revision {
+				c.JSON(http.StatusConflict, gin.H{"error": "题目在提交审核后已被修改，请重新提交审核"})
+				return
+			}
+		}
+	case entity.ReviewActionSubmit, entity.ReviewActionRetire:
+		if !requireAccess(c, questionResource(&question), accessEdit) {
+			return
+		}
+	case entity.ReviewActionAssign:
+		// 只有创建者和管理员可以指定审核人，避免教师把自己指定为他人题目的审核人
+		if !requireAccess(c, questionResource(&question), accessOwner) {
+			return
+		}
+		if req.ReviewerID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请指定审核人"})
+			return
+		}
+	case entity.ReviewActionComment:
+		if !requireReviewAccess(c, &question) {
+			return
+		}
+		if req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "审核意见不能为空"})
+			return
+		}
+	}
+
+	if req.ReviewerID != nil && (action == entity.ReviewActionSubmit || action == entity.ReviewActionAssign) {
+		var reviewer entity.User
+		if err := database.DB.First(&reviewer, *req.ReviewerID).Error; err != nil ||
+			(reviewer.Role != entity.RoleTeacher && reviewer.Role != entity.RoleAdmin) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "审核人必须是教师或管理员"})
+			return
+		}
+	}
+
+	review := entity.QuestionReview{
+		QuestionID: question.ID,
+		UserID:     userID,
+		Action:     action,
+		FromStatus: question.Status,
+		ToStatus:   question.Status,
+		ReviewerID: req.ReviewerID,
+		Comment:    req.Comment,
+		Revision:   question.Revision,
+	}
+	updates := map[string]interface{}{}
+	if transition.to != "" {
+		review.ToStatus = transition.to
+		updates["status"] = transition.to
+	}
+	if req.ReviewerID != nil && (action == entity.ReviewActionSubmit || action == entity.ReviewActionAssign) {
+		updates["reviewer_id"] = *req.ReviewerID
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&question).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&review).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "审核操作失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "操作成功",
+		"status":      review.ToStatus,
+		"reviewer_id": question.ReviewerID,
+	})
+}
+
+// @Summary 获取题目审核记录
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Success 200 {object} map[string]interface{} "当前状态和审核记录"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Router /api/v1/questions/{id}/reviews [get]
+func ListQuestionReviews(c *gin.Context) {
+	var question entity.Question
+	if err := database.DB.First(&question, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if !requireReviewAccess(c, &question) {
+		return
+	}
+
+	var reviews []entity.QuestionReview
+	if err := database.DB.Preload("User").Where("question_id = ?", question.ID).
+		Order("id asc").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审核记录失败"})
+		return
+	}
+
+	items := make([]response.QuestionReviewResponse, len(reviews))
+	for i, review := range reviews {
+		items[i] = response.QuestionReviewResponse{
+			ID:         review.ID,
+			QuestionID: review.QuestionID,
+			UserID:     review.UserID,
+			UserName:   review.User.Username,
+			Action:     review.Action,
+			FromStatus: string(review.FromStatus),
+			ToStatus:   string(review.ToStatus),
+			ReviewerID: review.ReviewerID,
+			Comment:    review.Comment,
+			Revision:   review.Revision,
+			CreatedAt:  review.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      question.Status,
+		"reviewer_id": question.ReviewerID,
+		"items":       items,
+	})
+}
+
+// @Summary 获取待我审核的题目
+// @Description 返回指定给当前用户、处于待审核状态的题目；管理员可通过 all=true 查看全部待审核题目
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param all query bool false "查看全部待审核题目（仅管理员）"
+// @Success 200 {object} map[string]interface{} "待审核题目"
+// @Router /api/v1/questions/review-queue [get]
+func ListReviewQueue(c *gin.Context) {
+	query := database.DB.Where("status = ?", entity.QuestionStatusInReview).Order("updated_at asc")
+	if !(c.GetString("role") == "admin" && c.Query("all") == "true") {
+		query = query.Where("reviewer_id = ?", c.GetUint("userID"))
+	}
+
+	var questions []entity.Question
+	if err := query.Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取待审核题目失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": len(questions),
+		"items": questions,
+	})
+}
+
+// revertToDraft 已发布或待审核的题目内容修改后退回草稿，需要重新审核才能发布，并记录一条审核记录
+// 调用前题目应已保存为新版本
+func revertToDraft(tx *gorm.DB, question *entity.Question, userID uint) error {
+	if question.Status != entity.QuestionStatusPublished && question.Status != entity.QuestionStatusInReview {
+		return nil
+	}
+	review := entity.QuestionReview{
+		QuestionID: question.ID,
+		UserID:     userID,
+		Action:     entity.ReviewActionRevise,
+		FromStatus: question.Status,
+		ToStatus:   entity.QuestionStatusDraft,
+		Revision:   question.Revision,
+	}
+	if err := tx.Model(question).Update("status", entity.QuestionStatusDraft).Error; err != nil {
+		return err
+	}
+	return tx.Create(&review).Error
+}
+
+// requireReviewAccess 指定的审核人即使没有共享权限也可以查看审核记录和添加审核意见，其他人需要查看权限
+func requireReviewAccess(c *gin.Context, question *entity.Question) bool {
+	if question.ReviewerID != nil && *question.ReviewerID == c.GetUint("userID") {
+		return true
+	}
+	return requireAccess(c, questionResource(question), accessView)
+}
+
+// unpublishedQuestionIDs 返回列表中不存在或未发布的题目ID，只有已发布的题目可以加入试卷和作业
+func unpublishedQuestionIDs(ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var published []uint
+	if err := database.DB.Model(&entity.Question{}).
+		Where("id IN ? AND status = ?", ids, entity.QuestionStatusPublished).
+		Pluck("id", &published).Error; err != nil {
+		return nil, err
+	}
+
+	publishedSet := make(map[uint]bool, len(published))
+	for _, id := range published {
+		publishedSet[id] = true
+	}
+	var missing []uint
+	for _, id := range ids {
+		if !publishedSet[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func containsStatus(statuses []entity.QuestionStatus, status entity.QuestionStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/model/response"
+	"testogo/internal/payload"
+	"testogo/internal/search"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary 获取题目修订历史
+// @Description 按版本号倒序返回题目的全部修订记录
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Success 200 {object} map[string]interface{} "修订记录列表"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Router /api/v1/questions/{id}/revisions [get]
+func ListQuestionRevisions(c *gin.Context) {
+	question, ok := findViewableQuestion(c)
+	if !ok {
+		return
+	}
+
+	var revisions []entity.QuestionRevision
+	if err := database.DB.Preload("Editor").Where("question_id = ?", question.ID).
+		Order("revision desc").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取修订记录失败"})
+		return
+	}
+
+	items := make([]response.QuestionRevisionResponse, len(revisions))
+	for i := range revisions {
+		items[i] = convertToQuestionRevisionResponse(&revisions[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current": question.Revision,
+		"items":   items,
+	})
+}
+
+// @Summary 获取题目的指定版本
+// @Description 返回指定版本的完整快照
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param revision path int true "版本号"
+// @Success 200 {object} response.QuestionRevisionResponse "版本内容"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "版本不存在"
+// @Router /api/v1/questions/{id}/revisions/{revision} [get]
+func GetQuestionRevision(c *gin.Context) {
+	if _, ok := findViewableQuestion(c); !ok {
+		return
+	}
+	revision, ok := findQuestionRevision(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, convertToQuestionRevisionResponse(revision))
+}
+
+// @Summary 比较题目的两个版本
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param from query int true "起始版本号"
+// @Param to query int false "目标版本号，默认为当前版本"
+// @Success 200 {object} response.QuestionRevisionDiffResponse "版本差异"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "版本不存在"
+// @Router /api/v1/questions/{id}/revisions/diff [get]
+func DiffQuestionRevisions(c *gin.Context) {
+	question, ok := findViewableQuestion(c)
+	if !ok {
+		return
+	}
+	to := c.Query("to")
+	if to == "" {
+		to = strconv.Itoa(question.Revision)
+	}
+
+	from, ok := findQuestionRevision(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	target, ok := findQuestionRevision(c, to)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, response.QuestionRevisionDiffResponse{
+		QuestionID: from.QuestionID,
+		From:       from.Revision,
+		To:         target.Revision,
+		Changes:    diffSnapshots(normalizedSnapshot(from.Snapshot), normalizedSnapshot(target.Snapshot)),
+	})
+}
+
+// @Summary 恢复题目的历史版本
+// @Description 将题目内容恢复为指定版本，并生成一个新版本；可选重新判定历史答题记录
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param revision path int true "要恢复的版本号"
+// @Param regrade query bool false "标准答案变化时在后台重新判分"
+// @Success 200 {object} map[string]interface{} "恢复成功"
+// @Failure 403 {object} map[string]interface{} "没有修改权限"
+// @Failure 404 {object} map[string]interface{} "版本不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/{id}/revisions/{revision}/restore [post]
+func RestoreQuestionRevision(c *gin.Context) {
+	revision, ok := findQuestionRevision(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+
+	var question entity.Question
+	if err := database.DB.First(&question, revision.QuestionID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if !requireAccess(c, questionResource(&question), accessEdit) {
+		return
+	}
+	before := question
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		applySnapshot(&question, normalizedSnapshot(revision.Snapshot))
+		if err := tx.Save(&question).Error; err != nil {
+			return err
+		}
+		if err := recordQuestionRevision(tx, &question, c.GetUint("userID"), fmt.Sprintf("恢复到第%d版", revision.Revision)); err != nil {
+			return err
+		}
+		return revertToDraft(tx, &question, c.GetUint("userID"))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复题目版本失败"})
+		return
+	}
+
+	result := gin.H{"message": "恢复成功", "revision": question.Revision}
+	if regrade, _ := strconv.ParseBool(c.Query("regrade")); regrade && answerKeyChanged(&before, &question) {
+		job, err := enqueueRegrade(question.ID, c.GetUint("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "题目已恢复，但创建重新判分任务失败"})
+			return
+		}
+		result["regrade_job"] = job
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// findViewableQuestion 查找路径中的题目并检查查看权限，失败时直接返回错误
+func findViewableQuestion(c *gin.Context) (*entity.Question, bool) {
+	var question entity.Question
+	if err := database.DB.First(&question, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return nil, false
+	}
+	if !requireAccess(c, questionResource(&question), accessView) {
+		return nil, false
+	}
+	return &question, true
+}
+
+// findQuestionRevision 查找路径中题目的指定版本，找不到时直接返回404
+func findQuestionRevision(c *gin.Context, number string) (*entity.QuestionRevision, bool) {
+	var revision entity.QuestionRevision
+	if err := database.DB.Preload("Editor").Where("question_id = ? AND revision = ?", c.Param("id"), number).
+		First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目版本不存在"})
+		return nil, false
+	}
+	return &revision, true
+}
+
+// recordQuestionRevision 保存题目当前内容为新版本，并更新题目的当前版本号
+// 题目还没有任何版本时先补一个初始版本，保证修改前的内容也能找回
+// 题目内容的每次变更都会经过这里，因此同时在此同步题目的标签关联、查重指纹和搜索索引
+func recordQuestionRevision(tx *gorm.DB, question *entity.Question, editorID uint, comment string) error {
+	if err := syncQuestionTags(tx, question); err != nil {
+		return err
+	}
+	if _, err := updateQuestionFingerprint(tx, question); err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(snapshotOf(question))
+	if err != nil {
+		return err
+	}
+
+	revision := entity.QuestionRevision{
+		QuestionID: question.ID,
+		Revision:   question.Revision + 1,
+		Snapshot:   string(snapshot),
+		EditorID:   editorID,
+		Comment:    comment,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+	question.Revision = revision.Revision
+	if err := tx.Model(&entity.Question{}).Where("id = ?", question.ID).UpdateColumn("revision", revision.Revision).Error; err != nil {
+		return err
+	}
+	return search.IndexQuestion(tx, question)
+}
+
+// ensureBaselineRevision 为尚无版本记录的旧题目补充初始版本，并将此前的答题记录关联到该版本
+func ensureBaselineRevision(tx *gorm.DB, question *entity.Question) error {
+	if question.Revision > 0 {
+		return nil
+	}
+	if err := recordQuestionRevision(tx, question, question.CreatorID, "初始版本"); err != nil {
+		return err
+	}
+	if err := tx.Model(&entity.UserAnswer{}).
+		Where("question_id = ? AND question_revision = 0", question.ID).
+		UpdateColumn("question_revision", question.Revision).Error; err != nil {
+		return err
+	}
+	return tx.Model(&entity.HomeworkQuestionAnswer{}).
+		Where("question_id = ? AND question_revision = 0", question.ID).
+		UpdateColumn("question_revision", question.Revision).Error
+}
+
+// snapshotOf 提取题目内容快照
+func snapshotOf(question *entity.Question) entity.QuestionSnapshot {
+	return entity.QuestionSnapshot{
+		Title:         question.Title,
+		Type:          question.Type,
+		Difficulty:    question.Difficulty,
+		Grade:         question.Grade,
+		SubjectID:     question.SubjectID,
+		TopicID:       question.TopicID,
+		Subject:       question.Subject,
+		Topic:         question.Topic,
+		Options:       question.Options,
+		Answer:        question.Answer,
+		Explanation:   question.Explanation,
+		MediaURL:      question.MediaURL,
+		MediaURLs:     question.MediaURLs,
+		LayoutType:    question.LayoutType,
+		ElementData:   question.ElementData,
+		Tags:          question.Tags,
+		GradingConfig: question.GradingConfig,
+		Hints:         question.Hints,
+	}
+}
+
+// applySnapshot 将快照内容写回题目
+func applySnapshot(question *entity.Question, snapshot entity.QuestionSnapshot) {
+	question.Title = snapshot.Title
+	question.Type = snapshot.Type
+	question.Difficulty = snapshot.Difficulty
+	question.Grade = snapshot.Grade
+	question.SubjectID = snapshot.SubjectID
+	question.TopicID = snapshot.TopicID
+	question.Subject = snapshot.Subject
+	question.Topic = snapshot.Topic
+	question.Options = snapshot.Options
+	question.Answer = snapshot.Answer
+	question.Explanation = snapshot.Explanation
+	question.MediaURL = snapshot.MediaURL
+	question.MediaURLs = snapshot.MediaURLs
+	question.LayoutType = snapshot.LayoutType
+	question.ElementData = snapshot.ElementData
+	question.Tags = snapshot.Tags
+	question.GradingConfig = snapshot.GradingConfig
+	question.Hints = snapshot.Hints
+}
+
+func parseSnapshot(raw string) entity.QuestionSnapshot {
+	var snapshot entity.QuestionSnapshot
+	json.Unmarshal([]byte(raw), &snapshot)
+	return snapshot
+}
+
+// normalizedSnapshot 解析快照，并将早期版本中旧格式的选项和媒体地址转换为规范的JSON格式
+// 修订历史保持原样不做迁移，恢复和比较版本时按当前格式处理，避免把旧格式带回题目或显示为内容变化
+func normalizedSnapshot(raw string) entity.QuestionSnapshot {
+	snapshot := parseSnapshot(raw)
+	fields, errs := payload.NormalizeLegacy(payload.Payload{
+		Type:        snapshot.Type,
+		Options:     snapshot.Options,
+		Answer:      snapshot.Answer,
+		MediaURLs:   snapshot.MediaURLs,
+		ElementData: snapshot.ElementData,
+	})
+	if len(errs) == 0 {
+		snapshot.Options, snapshot.MediaURLs = fields.Options, fields.MediaURLs
+	}
+	return snapshot
+}
+
+// diffSnapshots 按字段比较两个快照，字段名使用 JSON 名称并按字母排序
+func diffSnapshots(from, to entity.QuestionSnapshot) []response.FieldChangeResponse {
+	var before, after map[string]interface{}
+	fromJSON, _ := json.Marshal(from)
+	toJSON, _ := json.Marshal(to)
+	json.Unmarshal(fromJSON, &before)
+	json.Unmarshal(toJSON, &after)
+
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	changes := []response.FieldChangeResponse{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, response.FieldChangeResponse{
+				Field: field,
+				From:  before[field],
+				To:    after[field],
+			})
+		}
+	}
+	return changes
+}
+
+// 辅助函数：修订记录转换为响应
+func convertToQuestionRevisionResponse(revision *entity.QuestionRevision) response.QuestionRevisionResponse {
+	return response.QuestionRevisionResponse{
+		ID:         revision.ID,
+		QuestionID: revision.QuestionID,
+		Revision:   revision.Revision,
+		EditorID:   revision.EditorID,
+		EditorName: revision.Editor.Username,
+		Comment:    revision.Comment,
+		Snapshot:   parseSnapshot(revision.Snapshot),
+		CreatedAt:  revision.CreatedAt,
+	}
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"net/http"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/search"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSearchHits 全文检索按其他条件过滤后最多返回的题目数，超出部分按相关度截断
+const maxSearchHits = 1000
+
+// @Summary 重建题目搜索索引
+// @Description 为题库中的全部题目重新生成全文检索索引，用于索引与题目不一致时；服务启动时会自动为没有索引的题目建立索引
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} map[string]interface{} "重建的题目数量"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/search/reindex [post]
+func RebuildSearchIndex(c *gin.Context) {
+	var indexed int
+	var questions []entity.Question
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 清理已删除题目残留的索引
+		if err := tx.Where("question_id NOT IN (?)", tx.Model(&entity.Question{}).Select("id")).
+			Delete(&entity.QuestionSearchTerm{}).Error; err != nil {
+			return err
+		}
+		return tx.FindInBatches(&questions, 200, func(_ *gorm.DB, _ int) error {
+			for i := range questions {
+				if err := search.IndexQuestion(tx, &questions[i]); err != nil {
+					return err
+				}
+			}
+			indexed += len(questions)
+			return nil
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重建搜索索引失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "重建完成", "indexed": indexed})
+}
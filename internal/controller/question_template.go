@@ -0,0 +1,478 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"testogo/internal/generator"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary 创建题目模板
+// @Description 创建参数化数学题模板，变量在给定范围内取值并满足约束条件；新模板为草稿，审核通过后才能用于学生练习
+// @Tags 题目模板
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param request body request.QuestionTemplateRequest true "题目模板"
+// @Success 200 {object} response.QuestionTemplateResponse "创建的模板"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/question-templates [post]
+func CreateQuestionTemplate(c *gin.Context) {
+	var req request.QuestionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := entity.QuestionTemplate{CreatorID: c.GetUint("userID"), Status: entity.QuestionStatusDraft}
+	applyTemplateRequest(&template, &req)
+	if _, err := generator.Parse(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建题目模板失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertToQuestionTemplateResponse(&template))
+}
+
+// @Summary 获取题目模板列表
+// @Tags 题目模板
+// @Produce json
+// @Security BasicAuth
+// @Param grade query string false "年级"
+// @Param subject query string false "科目"
+// @Param topic query string false "主题"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "模板列表"
+// @Router /api/v1/question-templates [get]
+func ListQuestionTemplates(c *gin.Context) {
+	query := database.DB.Model(&entity.QuestionTemplate{}).Order("id desc")
+	// 学生只能看到审核通过的模板
+	if !isStaff(c) {
+		query = query.Where("status = ?", entity.QuestionStatusPublished)
+	}
+	if grade := c.Query("grade"); grade != "" {
+		query = query.Where("grade = ?", grade)
+	}
+	if subject := c.Query("subject"); subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	if topic := c.Query("topic"); topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var templates []entity.QuestionTemplate
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取题目模板列表失败"})
+		return
+	}
+
+	items := make([]response.QuestionTemplateResponse, len(templates))
+	for i := range templates {
+		items[i] = convertToQuestionTemplateResponse(&templates[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"items": items,
+	})
+}
+
+func GetQuestionTemplate(c *gin.Context) {
+	var template entity.QuestionTemplate
+	if err := database.DB.First(&template, c.Param("id")).Error; err != nil ||
+		(!isStaff(c) && template.Status != entity.QuestionStatusPublished) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目模板不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, convertToQuestionTemplateResponse(&template))
+}
+
+// @Summary 更新题目模板
+// @Description 更新模板定义，已生成的题目保持不变；已发布或待审核的模板修改后退回草稿，需要重新审核
+// @Tags 题目模板
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "模板ID"
+// @Param request body request.QuestionTemplateRequest true "题目模板"
+// @Success 200 {object} response.QuestionTemplateResponse "更新后的模板"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "只有创建者和管理员可以修改"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /api/v1/question-templates/{id} [put]
+func UpdateQuestionTemplate(c *gin.Context) {
+	var template entity.QuestionTemplate
+	if err := database.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目模板不存在"})
+		return
+	}
+	if !requireTemplateOwner(c, &template) {
+		return
+	}
+
+	var req request.QuestionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := template
+	applyTemplateRequest(&template, &req)
+	if _, err := generator.Parse(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if template != before && (template.Status == entity.QuestionStatusPublished || template.Status == entity.QuestionStatusInReview) {
+		template.Status = entity.QuestionStatusDraft
+	}
+
+	if err := database.DB.Save(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新题目模板失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertToQuestionTemplateResponse(&template))
+}
+
+func DeleteQuestionTemplate(c *gin.Context) {
+	var template entity.QuestionTemplate
+	if err := database.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目模板不存在"})
+		return
+	}
+	if !requireTemplateOwner(c, &template) {
+		return
+	}
+	if err := database.DB.Delete(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除题目模板失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// @Summary 预览模板生成的题目
+// @Description 按种子生成题目但不保存，同一种子总是得到相同的题目
+// @Tags 题目模板
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "模板ID"
+// @Param seed query int false "起始种子，为空时随机"
+// @Param count query int false "生成数量，默认5，最多50"
+// @Success 200 {object} map[string]interface{} "生成的题目"
+// @Failure 400 {object} map[string]interface{} "模板无法生成题目"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /api/v1/question-templates/{id}/preview [get]
+func PreviewQuestionTemplate(c *gin.Context) {
+	var template entity.QuestionTemplate
+	if err := database.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目模板不存在"})
+		return
+	}
+	spec, err := generator.Parse(&template)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	if s, err := strconv.ParseInt(c.Query("seed"), 10, 64); err == nil {
+		seed = s
+	}
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if count < 1 || count > 50 {
+		count = 5
+	}
+
+	items := make([]response.TemplateVariantResponse, 0, count)
+	for i := 0; i < count; i++ {
+		variant, err := spec.Generate(seed + int64(i))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		items = append(items, convertToTemplateVariantResponse(variant, 0))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// @Summary 按模板生成题目
+// @Description 按种子生成数学题并保存。审核通过的模板生成的题目直接发布，可用于练习、试卷和作业，学生练习时也可以生成；
+// @Description 未审核的模板只有教师可以生成，题目保存为当前教师的草稿。相同模板和种子会复用已生成的题目
+// @Tags 题目模板
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "模板ID"
+// @Param request body request.InstantiateTemplateRequest true "生成参数"
+// @Success 200 {object} map[string]interface{} "生成的题目"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/question-templates/{id}/instantiate [post]
+func InstantiateQuestionTemplate(c *gin.Context) {
+	var template entity.QuestionTemplate
+	if err := database.DB.First(&template, c.Param("id")).Error; err != nil ||
+		(!isStaff(c) && template.Status != entity.QuestionStatusPublished) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目模板不存在"})
+		return
+	}
+
+	var req request.InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+
+	spec, err := generator.Parse(&template)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]response.TemplateVariantResponse, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		variant, err := spec.Generate(seed + int64(i))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		question, err := materializeVariant(&template, variant, c.GetUint("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存生成的题目失败"})
+			return
+		}
+		items = append(items, convertToTemplateVariantResponse(variant, question.ID))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// materializeVariant 将生成的题目保存为数学题
+// 审核通过的模板生成的题目以模板创建者的名义直接发布；未审核的模板生成的题目保存为 requesterID 的草稿
+// 模板、种子和内容都相同的已发布题目（或该教师自己的草稿）直接复用；
+// 模板修改后同一种子生成的内容可能变化，此时会保存为新题目，已作答的旧题目保持不变
+func materializeVariant(template *entity.QuestionTemplate, variant *generator.Variant, requesterID uint) (*entity.Question, error) {
+	published := template.Status == entity.QuestionStatusPublished
+	creatorID, status := requesterID, entity.QuestionStatusDraft
+	if published {
+		creatorID, status = template.CreatorID, entity.QuestionStatusPublished
+	}
+
+	var question entity.Question
+	query := database.DB.Where("template_id = ? AND template_seed = ? AND title = ? AND answer = ?",
+		template.ID, variant.Seed, variant.Stem, variant.Answer)
+	if published {
+		query = query.Where("status = ?", entity.QuestionStatusPublished)
+	} else {
+		query = query.Where("creator_id = ? OR status = ?", requesterID, entity.QuestionStatusPublished)
+	}
+	if err := query.First(&question).Error; err == nil {
+		return &question, nil
+	}
+
+	question = entity.Question{
+		Title:        variant.Stem,
+		Type:         entity.TypeMath,
+		Difficulty:   template.Difficulty,
+		Grade:        template.Grade,
+		Subject:      template.Subject,
+		Topic:        template.Topic,
+		Answer:       variant.Answer,
+		CreatorID:    creatorID,
+		TemplateID:   &template.ID,
+		TemplateSeed: variant.Seed,
+		Status:       status,
+	}
+	if question.Difficulty == 0 {
+		question.Difficulty = 1
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&question).Error; err != nil {
+			return err
+		}
+		return recordQuestionRevision(tx, &question, requesterID, fmt.Sprintf("由模板 %d 生成", template.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// @Summary 题目模板审核操作
+// @Description 模板只需审核一次：submit 提交审核、approve 通过并发布、reject 驳回为草稿、retire 停用
+// @Description 提交审核和停用只能由创建者或管理员操作；通过和驳回只能由管理员操作，驳回时必须填写审核意见
+// @Description 已发布的模板生成的题目直接发布，停用后不能再生成新题目，已生成的题目不受影响
+// @Tags 题目模板
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "模板ID"
+// @Param action path string true "审核操作" Enums(submit, approve, reject, retire)
+// @Param request body request.QuestionReviewRequest false "审核意见"
+// @Success 200 {object} response.QuestionTemplateResponse "操作后的模板"
+// @Failure 400 {object} map[string]interface{} "当前状态不允许该操作"
+// @Failure 403 {object} map[string]interface{} "无权执行该操作"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /api/v1/question-templates/{id}/review/{action} [post]
+func ReviewQuestionTemplate(c *gin.Context) {
+	action := c.Param("action")
+	transition, ok := reviewTransitions[action]
+	if !ok || transition.to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的审核操作"})
+		return
+	}
+
+	var req request.QuestionReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+
+	var template entity.QuestionTemplate
+	if err := database.DB.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目模板不存在"})
+		return
+	}
+	if !containsStatus(transition.from, template.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板当前状态为 " + string(template.Status) + "，不能执行该操作"})
+		return
+	}
+
+	switch action {
+	case entity.ReviewActionApprove, entity.ReviewActionReject:
+		if c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员可以审核模板"})
+			return
+		}
+		if action == entity.ReviewActionReject && req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "驳回时必须填写审核意见"})
+			return
+		}
+	default:
+		if !requireTemplateOwner(c, &template) {
+			return
+		}
+	}
+
+	// 只在状态仍与检查时一致时更新，审核期间模板被修改会退回草稿，不会被直接发布
+	result := database.DB.Model(&entity.QuestionTemplate{}).
+		Where("id = ? AND status = ?", template.ID, template.Status).
+		Updates(map[string]interface{}{"status": transition.to, "review_comment": req.Comment})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "审核操作失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "模板状态已变化，请刷新后重试"})
+		return
+	}
+
+	database.DB.First(&template, template.ID)
+	c.JSON(http.StatusOK, convertToQuestionTemplateResponse(&template))
+}
+
+// requireTemplateOwner 只有模板的创建者和管理员可以修改和删除模板，否则直接返回403
+func requireTemplateOwner(c *gin.Context, template *entity.QuestionTemplate) bool {
+	if c.GetString("role") == "admin" || template.CreatorID == c.GetUint("userID") {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": accessDeniedMessage(accessOwner)})
+	return false
+}
+
+// applyTemplateRequest 将请求内容写入模板
+func applyTemplateRequest(template *entity.QuestionTemplate, req *request.QuestionTemplateRequest) {
+	variables, _ := json.Marshal(req.Variables)
+	constraints, _ := json.Marshal(req.Constraints)
+	if req.Constraints == nil {
+		constraints = []byte("[]")
+	}
+
+	template.Title = req.Title
+	template.Stem = req.Stem
+	template.Expression = req.Expression
+	template.Variables = string(variables)
+	template.Constraints = string(constraints)
+	template.Grade = req.Grade
+	template.Subject = req.Subject
+	template.Topic = req.Topic
+	template.Difficulty = req.Difficulty
+}
+
+// 辅助函数：模板实体转换为响应
+func convertToQuestionTemplateResponse(template *entity.QuestionTemplate) response.QuestionTemplateResponse {
+	resp := response.QuestionTemplateResponse{
+		ID:          template.ID,
+		Title:       template.Title,
+		Stem:        template.Stem,
+		Expression:  template.Expression,
+		Variables:   []entity.TemplateVariable{},
+		Constraints: []string{},
+		Grade:       template.Grade,
+		Subject:     template.Subject,
+		Topic:       template.Topic,
+		Difficulty:  template.Difficulty,
+		CreatorID:   template.CreatorID,
+		CreatedAt:   template.CreatedAt,
+		UpdatedAt:   template.UpdatedAt,
+
+		Status:        string(template.Status),
+		ReviewComment: template.ReviewComment,
+	}
+	json.Unmarshal([]byte(template.Variables), &resp.Variables)
+	json.Unmarshal([]byte(template.Constraints), &resp.Constraints)
+	return resp
+}
+
+// 辅助函数：生成的题目转换为响应
+func convertToTemplateVariantResponse(variant *generator.Variant, questionID uint) response.TemplateVariantResponse {
+	return response.TemplateVariantResponse{
+		QuestionID: questionID,
+		Seed:       variant.Seed,
+		Values:     variant.Values,
+		Title:      variant.Stem,
+		Answer:     variant.Answer,
+	}
+}
@@ -0,0 +1,400 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary 重新判分
+// @Description 在后台按题目当前的标准答案和判分配置重新判定所有历史答题记录，并重新计算作业成绩和学习表现统计；
+// @Description 同一道题已有排队中的任务时返回该任务。dry_run 时直接返回预览结果，不创建任务
+// @Tags 题目
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param dry_run query bool false "仅预览变化，不写入数据库"
+// @Success 200 {object} response.RegradeSummaryResponse "预览结果"
+// @Success 202 {object} map[string]interface{} "重新判分任务"
+// @Failure 403 {object} map[string]interface{} "没有修改权限"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/questions/{id}/regrade [post]
+func RegradeQuestion(c *gin.Context) {
+	var question entity.Question
+	if err := database.DB.First(&question, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return
+	}
+	if !requireAccess(c, questionResource(&question), accessEdit) {
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		summary, err := runRegrade(&question, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "重新判分失败"})
+			return
+		}
+		c.JSON(http.StatusOK, summary)
+		return
+	}
+
+	job, err := enqueueRegrade(question.ID, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建重新判分任务失败"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "重新判分已开始",
+		"job":     job,
+	})
+}
+
+// @Summary 重新判分任务列表
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Success 200 {array} entity.RegradeJob "重新判分任务，按创建时间倒序"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "题目不存在"
+// @Router /api/v1/questions/{id}/regrade/jobs [get]
+func ListRegradeJobs(c *gin.Context) {
+	question, ok := findViewableQuestion(c)
+	if !ok {
+		return
+	}
+	var jobs []entity.RegradeJob
+	if err := database.DB.Where("question_id = ?", question.ID).Order("id desc").Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取重新判分任务失败"})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// @Summary 重新判分任务详情
+// @Description 返回任务状态，任务完成后返回重新判分结果
+// @Tags 题目
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "题目ID"
+// @Param jobId path int true "任务ID"
+// @Success 200 {object} map[string]interface{} "重新判分任务和结果"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/v1/questions/{id}/regrade/jobs/{jobId} [get]
+func GetRegradeJob(c *gin.Context) {
+	question, ok := findViewableQuestion(c)
+	if !ok {
+		return
+	}
+	var job entity.RegradeJob
+	if err := database.DB.Where("question_id = ?", question.ID).First(&job, c.Param("jobId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "重新判分任务不存在"})
+		return
+	}
+
+	result := gin.H{"job": job}
+	if job.Result != "" {
+		var summary response.RegradeSummaryResponse
+		if err := json.Unmarshal([]byte(job.Result), &summary); err == nil {
+			result["result"] = summary
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// regradeSlot 同一时间只运行一个重新判分任务，避免同时重新计算同一份作业提交
+var regradeSlot = make(chan struct{}, 1)
+
+// enqueueRegrade 为题目创建重新判分任务并在后台运行
+// 题目已有排队中的任务时直接返回该任务，它开始运行时才读取题目，会使用最新的标准答案
+func enqueueRegrade(questionID, creatorID uint) (entity.RegradeJob, error) {
+	var job entity.RegradeJob
+	err := database.DB.Where("question_id = ? AND status = ?", questionID, entity.RegradeJobQueued).
+		Order("id").First(&job).Error
+	if err == nil {
+		return job, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return job, err
+	}
+
+	job = entity.RegradeJob{
+		QuestionID: questionID,
+		CreatorID:  creatorID,
+		Status:     entity.RegradeJobQueued,
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		return job, err
+	}
+	startRegradeJob(job.ID)
+	return job, nil
+}
+
+// startRegradeJob 在后台运行重新判分
+func startRegradeJob(jobID uint) {
+	go runRegradeJob(jobID)
+}
+
+// runRegradeJob 按题目当前的标准答案重新判分，只处理排队中的任务
+func runRegradeJob(jobID uint) {
+	regradeSlot <- struct{}{}
+	defer func() { <-regradeSlot }()
+	defer func() {
+		if r := recover(); r != nil {
+			failRegradeJob(jobID, fmt.Sprintf("重新判分失败: %v", r))
+		}
+	}()
+
+	result := database.DB.Model(&entity.RegradeJob{}).
+		Where("id = ? AND status = ?", jobID, entity.RegradeJobQueued).
+		Update("status", entity.RegradeJobRunning)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	var job entity.RegradeJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		failRegradeJob(jobID, fmt.Sprintf("读取任务失败: %v", err))
+		return
+	}
+	var question entity.Question
+	if err := database.DB.First(&question, job.QuestionID).Error; err != nil {
+		failRegradeJob(jobID, fmt.Sprintf("读取题目失败: %v", err))
+		return
+	}
+	summary, err := runRegrade(&question, false)
+	if err != nil {
+		failRegradeJob(jobID, fmt.Sprintf("重新判分失败: %v", err))
+		return
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		failRegradeJob(jobID, fmt.Sprintf("保存重新判分结果失败: %v", err))
+		return
+	}
+
+	now := time.Now()
+	database.DB.Model(&entity.RegradeJob{}).
+		Where("id = ? AND status = ?", jobID, entity.RegradeJobRunning).
+		Updates(map[string]interface{}{
+			"status":          entity.RegradeJobCompleted,
+			"answers_checked": summary.UserAnswersChecked + summary.HomeworkAnswersChecked,
+			"answers_changed": summary.UserAnswersChanged + summary.HomeworkAnswersChanged,
+			"result":          string(data),
+			"completed_at":    &now,
+		})
+}
+
+// failRegradeJob 将任务标记为失败
+func failRegradeJob(jobID uint, message string) {
+	database.DB.Model(&entity.RegradeJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":        entity.RegradeJobFailed,
+		"error_message": message,
+	})
+}
+
+// ResumeRegradeJobs 服务启动时重新运行上次未完成的重新判分任务
+// 重新判分在一个事务中完成，中断的任务没有写入任何结果，可以直接重新运行
+func ResumeRegradeJobs() {
+	if database.DB == nil {
+		return
+	}
+	if err := database.DB.Model(&entity.RegradeJob{}).Where("status = ?", entity.RegradeJobRunning).
+		Update("status", entity.RegradeJobQueued).Error; err != nil {
+		log.Printf("恢复重新判分任务失败: %v", err)
+		return
+	}
+	var queued []uint
+	if err := database.DB.Model(&entity.RegradeJob{}).Where("status = ?", entity.RegradeJobQueued).
+		Order("id").Pluck("id", &queued).Error; err != nil {
+		log.Printf("恢复重新判分任务失败: %v", err)
+		return
+	}
+	for _, id := range queued {
+		startRegradeJob(id)
+	}
+}
+
+// answerKeyChanged 判断影响判分的字段是否发生变化
+func answerKeyChanged(before, after *entity.Question) bool {
+	return before.Answer != after.Answer ||
+		before.Options != after.Options ||
+		before.ElementData != after.ElementData ||
+		before.GradingConfig != after.GradingConfig
+}
+
+// runRegrade 在事务中重新判分，预览模式下回滚事务
+func runRegrade(question *entity.Question, dryRun bool) (response.RegradeSummaryResponse, error) {
+	var summary response.RegradeSummaryResponse
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = regradeQuestion(tx, question)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err == errDryRun {
+		err = nil
+	}
+	summary.DryRun = dryRun
+	return summary, err
+}
+
+// errDryRun 预览模式下用于回滚事务
+var errDryRun = errors.New("dry run")
+
+// regradeQuestion 重新判定题目的全部答题记录
+// 练习和试卷记录直接更新对错和得分；作业记录更新后重新计算所在提交的成绩，并修正学生当天的学习表现统计
+func regradeQuestion(tx *gorm.DB, question *entity.Question) (response.RegradeSummaryResponse, error) {
+	summary := response.RegradeSummaryResponse{
+		QuestionID: question.ID,
+		Changes:    []response.RegradeChangeResponse{},
+	}
+
+	// 练习和试卷答题记录，试卷记录使用试卷的计分规则
+	var userAnswers []entity.UserAnswer
+	if err := tx.Where("question_id = ?", question.ID).Find(&userAnswers).Error; err != nil {
+		return summary, err
+	}
+	paperDefaults := map[uint]entity.GradingConfig{}
+	for _, answer := range userAnswers {
+		summary.UserAnswersChecked++
+
+		defaults, ok := paperDefaults[answer.PaperID]
+		if !ok && answer.PaperID > 0 {
+			var paper entity.Paper
+			if err := tx.Unscoped().First(&paper, answer.PaperID).Error; err == nil {
+				defaults.ScoringPolicy = paper.ScoringPolicy
+			}
+			paperDefaults[answer.PaperID] = defaults
+		}
+
+		result := grading.GradeWithDefaults(question, answer.Answer, defaults)
+		if result.IsCorrect == answer.IsCorrect && result.Points == answer.Score {
+			continue
+		}
+		if err := tx.Model(&answer).Updates(map[string]interface{}{
+			"is_correct": result.IsCorrect,
+			"score":      result.Points,
+		}).Error; err != nil {
+			return summary, err
+		}
+		summary.UserAnswersChanged++
+		summary.Changes = append(summary.Changes, response.RegradeChangeResponse{
+			Source:     "user_answer",
+			ID:         answer.ID,
+			UserID:     answer.UserID,
+			WasCorrect: answer.IsCorrect,
+			IsCorrect:  result.IsCorrect,
+			OldScore:   answer.Score,
+			NewScore:   result.Points,
+		})
+	}
+
+	// 作业答题记录
+	var homeworkAnswers []entity.HomeworkQuestionAnswer
+	if err := tx.Preload("Submission").Where("question_id = ?", question.ID).Find(&homeworkAnswers).Error; err != nil {
+		return summary, err
+	}
+	changedSubmissions := map[uint]bool{}
+	for _, answer := range homeworkAnswers {
+		summary.HomeworkAnswersChecked++
+
+		result := grading.Grade(question, answer.Answer)
+		if result.IsCorrect == answer.IsCorrect && result.Points == answer.Score {
+			continue
+		}
+		if err := tx.Model(&answer).Updates(map[string]interface{}{
+			"is_correct": result.IsCorrect,
+			"score":      result.Points,
+		}).Error; err != nil {
+			return summary, err
+		}
+		summary.HomeworkAnswersChanged++
+		summary.Changes = append(summary.Changes, response.RegradeChangeResponse{
+			Source:       "homework_answer",
+			ID:           answer.ID,
+			UserID:       answer.Submission.StudentID,
+			SubmissionID: answer.SubmissionID,
+			WasCorrect:   answer.IsCorrect,
+			IsCorrect:    result.IsCorrect,
+			OldScore:     answer.Score,
+			NewScore:     result.Points,
+		})
+		changedSubmissions[answer.SubmissionID] = true
+	}
+
+	for submissionID := range changedSubmissions {
+		performanceUpdated, err := recomputeSubmission(tx, submissionID)
+		if err != nil {
+			return summary, err
+		}
+		summary.SubmissionsUpdated++
+		if performanceUpdated {
+			summary.PerformancesUpdated++
+		}
+	}
+
+	return summary, nil
+}
+
+// recomputeSubmission 按作业答题记录重新计算提交的答对数和成绩
+// 答对数变化时同步修正提交当天的学习表现统计，返回统计是否被修改
+func recomputeSubmission(tx *gorm.DB, submissionID uint) (bool, error) {
+	var submission entity.HomeworkSubmission
+	if err := tx.Preload("QuestionAnswers.Question").First(&submission, submissionID).Error; err != nil {
+		return false, err
+	}
+
+	// 与提交作业时的算法一致：部分得分按得分率计入成绩
+	correct := 0
+	earnedScore := 0.0
+	for _, answer := range submission.QuestionAnswers {
+		if answer.IsCorrect {
+			correct++
+		}
+		config := grading.ParseGradingConfig(answer.Question.GradingConfig)
+		earnedScore += answer.Score / grading.QuestionPoints(config)
+	}
+	score := 0
+	if submission.QuestionsTotal > 0 {
+		score = int(earnedScore / float64(submission.QuestionsTotal) * 100)
+	}
+
+	if err := tx.Model(&submission).Updates(map[string]interface{}{
+		"questions_correct": correct,
+		"score":             score,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	delta := correct - submission.QuestionsCorrect
+	if delta == 0 {
+		return false, nil
+	}
+	date := submission.CreatedAt.Format("2006-01-02")
+	result := tx.Model(&entity.UserPerformance{}).
+		Where("user_id = ? AND date = ?", submission.StudentID, date).
+		Update("questions_correct", gorm.Expr("questions_correct + ?", delta))
+	return result.RowsAffected > 0, result.Error
+}
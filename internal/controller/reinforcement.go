@@ -132,25 +132,17 @@ func ListReinforcementSettings(c *gin.Context) {
 		req.PageSize = 10
 	}
 
-	// Get current user info
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
 	// Build query
 	query := database.DB.Model(&entity.ReinforcementSetting{}).
 		Preload("Creator").
 		Preload("ReinforcementItems")
 
-	// Apply role-based filtering
-	if userRole == "teacher" {
-		if req.CreatorID == 0 {
-			query = query.Where("creator_id = ?", userID)
-		}
-	}
+	// Apply role-based filtering, teachers see their own settings and settings shared with them or the whole school
+	query = scopeVisible(c, query, entity.ShareReinforcementSetting)
 	// Admin can see all settings
 
 	// Apply filters
-	if req.CreatorID != 0 && userRole != "teacher" {
+	if req.CreatorID != 0 {
 		query = query.Where("creator_id = ?", req.CreatorID)
 	}
 	if req.Mode != "" {
@@ -220,13 +212,7 @@ func GetReinforcementSetting(c *gin.Context) {
 	}
 
 	// Check access permissions
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
-	if userRole == "teacher" && setting.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if isTeacher(c) && !checkAccess(c, reinforcementSettingResource(&setting), accessView) {
 		return
 	}
 
@@ -251,10 +237,6 @@ func UpdateReinforcementSetting(c *gin.Context) {
 		return
 	}
 
-	// Get current user
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
 	// Find setting
 	var setting entity.ReinforcementSetting
 	if err := database.DB.First(&setting, settingID).Error; err != nil {
@@ -271,10 +253,7 @@ func UpdateReinforcementSetting(c *gin.Context) {
 	}
 
 	// Check permissions
-	if userRole == "teacher" && setting.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if !checkAccess(c, reinforcementSettingResource(&setting), accessEdit) {
 		return
 	}
 
@@ -389,10 +368,6 @@ func DeleteReinforcementSetting(c *gin.Context) {
 		return
 	}
 
-	// Get current user
-	userID := c.GetUint("userID")
-	userRole := c.GetString("user_role")
-
 	// Find setting
 	var setting entity.ReinforcementSetting
 	if err := database.DB.First(&setting, settingID).Error; err != nil {
@@ -409,10 +384,7 @@ func DeleteReinforcementSetting(c *gin.Context) {
 	}
 
 	// Check permissions
-	if userRole == "teacher" && setting.CreatorID != userID {
-		c.JSON(http.StatusForbidden, response.ErrorResponse{
-			Error: "Access denied",
-		})
+	if !checkAccess(c, reinforcementSettingResource(&setting), accessOwner) {
 		return
 	}
 
@@ -458,6 +430,11 @@ func CopyReinforcementSetting(c *gin.Context) {
 		return
 	}
 
+	// Check permissions, copying requires view access to the source setting
+	if !checkAccess(c, reinforcementSettingResource(&sourceSetting), accessView) {
+		return
+	}
+
 	// Start transaction
 	tx := database.DB.Begin()
 	defer func() {
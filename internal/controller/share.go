@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// shareTargets 共享接口路径中的资源名
+var shareTargets = map[string]entity.ShareResourceType{
+	"questions":              entity.ShareQuestion,
+	"papers":                 entity.SharePaper,
+	"homework":               entity.ShareHomework,
+	"reinforcement-settings": entity.ShareReinforcementSetting,
+}
+
+// @Summary 查看共享设置
+// @Description 查看题目、试卷、作业或强化设置的共享范围和共享成员，需要查看权限
+// @Tags 共享
+// @Produce json
+// @Security BasicAuth
+// @Param resource path string true "资源类型" Enums(questions, papers, homework, reinforcement-settings)
+// @Param id path int true "资源ID"
+// @Success 200 {object} response.SharingResponse "共享设置"
+// @Failure 403 {object} map[string]interface{} "没有查看权限"
+// @Failure 404 {object} map[string]interface{} "资源不存在"
+// @Router /api/v1/shares/{resource}/{id} [get]
+func GetSharing(c *gin.Context) {
+	resource, ok := findSharedResource(c)
+	if !ok {
+		return
+	}
+	access, err := resourceAccess(c, resource)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查权限失败"})
+		return
+	}
+	if access < accessView {
+		c.JSON(http.StatusForbidden, gin.H{"error": accessDeniedMessage(accessView)})
+		return
+	}
+	respondSharing(c, resource, access)
+}
+
+// @Summary 修改共享设置
+// @Description 修改资源的共享范围并整体替换共享成员，只有创建者和管理员可以操作
+// @Description private 仅创建者；shared 创建者和共享成员；school 全校教师可查看，共享成员中的协作编辑者（co_editor）可修改
+// @Tags 共享
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param resource path string true "资源类型" Enums(questions, papers, homework, reinforcement-settings)
+// @Param id path int true "资源ID"
+// @Param request body request.UpdateSharingRequest true "共享范围和共享成员"
+// @Success 200 {object} response.SharingResponse "修改后的共享设置"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "只有创建者和管理员可以修改"
+// @Failure 404 {object} map[string]interface{} "资源不存在"
+// @Router /api/v1/shares/{resource}/{id} [put]
+func UpdateSharing(c *gin.Context) {
+	var req request.UpdateSharingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resource, ok := findSharedResource(c)
+	if !ok || !requireAccess(c, resource, accessOwner) {
+		return
+	}
+
+	visibility := entity.Visibility(req.Visibility)
+	if visibility == entity.VisibilityPrivate && len(req.Shares) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "私有资源不能设置共享成员"})
+		return
+	}
+
+	// 同一成员只保留最后一次设置的角色
+	roles := make(map[uint]entity.ShareRole)
+	var userIDs []uint
+	for _, member := range req.Shares {
+		if member.UserID == resource.CreatorID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能把创建者添加为共享成员"})
+			return
+		}
+		if _, ok := roles[member.UserID]; !ok {
+			userIDs = append(userIDs, member.UserID)
+		}
+		roles[member.UserID] = entity.ShareViewer
+		if member.Role != "" {
+			roles[member.UserID] = entity.ShareRole(member.Role)
+		}
+	}
+	if len(userIDs) > 0 {
+		var count int64
+		if err := database.DB.Model(&entity.User{}).
+			Where("id IN ? AND role IN ?", userIDs, []entity.Role{entity.RoleTeacher, entity.RoleAdmin}).
+			Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户失败"})
+			return
+		}
+		if int(count) != len(userIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "共享成员必须是已存在的教师"})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(shareModel(resource.Type)).Where("id = ?", resource.ID).
+			Update("visibility", visibility).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("resource_type = ? AND resource_id = ?", resource.Type, resource.ID).
+			Delete(&entity.ResourceShare{}).Error; err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			share := entity.ResourceShare{
+				ResourceType: resource.Type,
+				ResourceID:   resource.ID,
+				UserID:       userID,
+				Role:         roles[userID],
+				CreatedBy:    c.GetUint("userID"),
+			}
+			if err := tx.Create(&share).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改共享设置失败"})
+		return
+	}
+
+	resource.Visibility = visibility
+	respondSharing(c, resource, accessOwner)
+}
+
+// findSharedResource 查找路径中的资源，找不到时直接返回错误
+func findSharedResource(c *gin.Context) (sharedResource, bool) {
+	resourceType, ok := shareTargets[c.Param("resource")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持共享的资源类型"})
+		return sharedResource{}, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的资源ID"})
+		return sharedResource{}, false
+	}
+
+	var resource sharedResource
+	result := database.DB.Model(shareModel(resourceType)).
+		Select("id, creator_id, visibility").
+		Where("id = ?", id).
+		Limit(1).Scan(&resource)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资源失败"})
+		return sharedResource{}, false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return sharedResource{}, false
+	}
+	resource.Type = resourceType
+	return resource, true
+}
+
+func shareModel(resourceType entity.ShareResourceType) interface{} {
+	switch resourceType {
+	case entity.SharePaper:
+		return &entity.Paper{}
+	case entity.ShareHomework:
+		return &entity.Homework{}
+	case entity.ShareReinforcementSetting:
+		return &entity.ReinforcementSetting{}
+	}
+	return &entity.Question{}
+}
+
+func respondSharing(c *gin.Context, resource sharedResource, access accessLevel) {
+	var shares []entity.ResourceShare
+	if err := database.DB.Preload("User").
+		Where("resource_type = ? AND resource_id = ?", resource.Type, resource.ID).
+		Order("id").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询共享成员失败"})
+		return
+	}
+
+	resp := response.SharingResponse{
+		ResourceType: string(resource.Type),
+		ResourceID:   resource.ID,
+		CreatorID:    resource.CreatorID,
+		Visibility:   string(resource.Visibility),
+		Access:       access.String(),
+		Shares:       make([]response.ShareMemberResponse, 0, len(shares)),
+	}
+	for _, share := range shares {
+		resp.Shares = append(resp.Shares, response.ShareMemberResponse{
+			UserID:    share.UserID,
+			Username:  share.User.Username,
+			Role:      string(share.Role),
+			CreatedBy: share.CreatedBy,
+			CreatedAt: share.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
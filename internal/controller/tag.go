@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"testogo/internal/model/entity"
+	"testogo/internal/model/request"
+	"testogo/internal/model/response"
+	"testogo/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary 获取标签列表
+// @Description 获取全部标签及每个标签下的题目数量，可按名称搜索
+// @Tags 标签
+// @Produce json
+// @Security BasicAuth
+// @Param keyword query string false "标签名称关键词"
+// @Success 200 {array} response.TagResponse "标签列表"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/tags [get]
+func ListTags(c *gin.Context) {
+	query := database.DB.Table("tag").
+		Select("tag.id, tag.name, tag.created_at, COUNT(question.id) AS question_count").
+		Joins("LEFT JOIN question_tag ON question_tag.tag_id = tag.id").
+		Joins("LEFT JOIN question ON question.id = question_tag.question_id AND question.deleted_at IS NULL").
+		Group("tag.id, tag.name, tag.created_at").
+		Order("tag.name")
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		query = query.Where("tag.name LIKE ?", "%"+keyword+"%")
+	}
+
+	tags := []response.TagResponse{}
+	if err := query.Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// @Summary 创建标签
+// @Tags 标签
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param request body request.TagRequest true "标签名称"
+// @Success 200 {object} entity.Tag "创建的标签"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 409 {object} map[string]interface{} "标签已存在"
+// @Router /api/v1/tags [post]
+func CreateTag(c *gin.Context) {
+	name, ok := bindTagName(c)
+	if !ok {
+		return
+	}
+
+	var existing entity.Tag
+	if err := database.DB.Where("name = ?", name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "标签已存在"})
+		return
+	}
+
+	tag := entity.Tag{Name: name}
+	if err := database.DB.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary 重命名标签
+// @Description 重命名标签并同步修改所有使用该标签的题目，新名称已被其他标签使用时请改用合并；仅管理员
+// @Tags 标签
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "标签ID"
+// @Param request body request.TagRequest true "新的标签名称"
+// @Success 200 {object} map[string]interface{} "重命名结果"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "标签不存在"
+// @Failure 409 {object} map[string]interface{} "标签已存在"
+// @Router /api/v1/tags/{id} [put]
+func RenameTag(c *gin.Context) {
+	var tag entity.Tag
+	if err := database.DB.First(&tag, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+	name, ok := bindTagName(c)
+	if !ok {
+		return
+	}
+
+	var existing entity.Tag
+	if err := database.DB.Where("name = ? AND id <> ?", name, tag.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "标签已存在，请使用合并标签"})
+		return
+	}
+
+	oldName := tag.Name
+	var updated int
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = retagQuestions(tx, []uint{tag.ID}, c.GetUint("userID"), fmt.Sprintf("标签「%s」重命名为「%s」", oldName, name),
+			func(tx *gorm.DB) error {
+				return tx.Model(&tag).Update("name", name).Error
+			},
+			func(tagName string) string {
+				if strings.EqualFold(tagName, oldName) {
+					return name
+				}
+				return tagName
+			})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重命名标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "重命名成功", "tag": tag, "questions_updated": updated})
+}
+
+// @Summary 合并标签
+// @Description 将源标签下的题目全部改为目标标签，然后删除源标签；仅管理员
+// @Tags 标签
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param request body request.MergeTagsRequest true "源标签和目标标签"
+// @Success 200 {object} map[string]interface{} "合并结果"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "标签不存在"
+// @Router /api/v1/tags/merge [post]
+func MergeTags(c *gin.Context) {
+	var req request.MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, id := range req.SourceIDs {
+		if id == req.TargetID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "目标标签不能同时作为源标签"})
+			return
+		}
+	}
+
+	var target entity.Tag
+	if err := database.DB.First(&target, req.TargetID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "目标标签不存在"})
+		return
+	}
+	var sources []entity.Tag
+	if err := database.DB.Where("id IN ?", req.SourceIDs).Find(&sources).Error; err != nil || len(sources) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "源标签不存在"})
+		return
+	}
+
+	sourceIDs := make([]uint, len(sources))
+	sourceNames := make([]string, len(sources))
+	for i, source := range sources {
+		sourceIDs[i] = source.ID
+		sourceNames[i] = source.Name
+	}
+
+	var updated int
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = retagQuestions(tx, sourceIDs, c.GetUint("userID"),
+			fmt.Sprintf("标签「%s」合并到「%s」", strings.Join(sourceNames, "、"), target.Name),
+			func(tx *gorm.DB) error {
+				return deleteTags(tx, sourceIDs)
+			},
+			func(tagName string) string {
+				for _, source := range sourceNames {
+					if strings.EqualFold(tagName, source) {
+						return target.Name
+					}
+				}
+				return tagName
+			})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "合并标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "合并成功", "target": target, "merged": len(sources), "questions_updated": updated})
+}
+
+// @Summary 删除标签
+// @Description 删除标签，并从所有使用该标签的题目中移除；仅管理员
+// @Tags 标签
+// @Produce json
+// @Security BasicAuth
+// @Param id path int true "标签ID"
+// @Success 200 {object} map[string]interface{} "删除结果"
+// @Failure 404 {object} map[string]interface{} "标签不存在"
+// @Router /api/v1/tags/{id} [delete]
+func DeleteTag(c *gin.Context) {
+	var tag entity.Tag
+	if err := database.DB.First(&tag, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	var updated int
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = retagQuestions(tx, []uint{tag.ID}, c.GetUint("userID"), fmt.Sprintf("删除标签「%s」", tag.Name),
+			func(tx *gorm.DB) error {
+				return deleteTags(tx, []uint{tag.ID})
+			},
+			func(tagName string) string {
+				if strings.EqualFold(tagName, tag.Name) {
+					return ""
+				}
+				return tagName
+			})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功", "questions_updated": updated})
+}
+
+// bindTagName 解析请求中的标签名称，名称中不能包含标签分隔符
+func bindTagName(c *gin.Context) (string, bool) {
+	var req request.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	name := strings.TrimSpace(req.Name)
+	if names := entity.ParseTagNames(name); len(names) != 1 || names[0] != name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名称不能为空，且不能包含逗号、顿号或分号"})
+		return "", false
+	}
+	return name, true
+}
+
+// retagQuestions 修改使用指定标签的全部题目
+// 先为旧题目补充初始版本，再执行 change 修改标签表，最后用 rename 改写每道题目的标签（返回空字符串表示移除），
+// 并为每道题目记录一个新版本，返回修改的题目数量
+func retagQuestions(tx *gorm.DB, tagIDs []uint, editorID uint, comment string, change func(tx *gorm.DB) error, rename func(string) string) (int, error) {
+	var questions []entity.Question
+	if err := tx.Where("id IN (?)", tx.Table("question_tag").Select("question_id").Where("tag_id IN ?", tagIDs)).
+		Find(&questions).Error; err != nil {
+		return 0, err
+	}
+	for i := range questions {
+		if err := ensureBaselineRevision(tx, &questions[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := change(tx); err != nil {
+		return 0, err
+	}
+
+	for i := range questions {
+		var names []string
+		for _, name := range entity.ParseTagNames(questions[i].Tags) {
+			if name = rename(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		questions[i].Tags = entity.JoinTagNames(names)
+		if err := tx.Model(&questions[i]).UpdateColumn("tags", questions[i].Tags).Error; err != nil {
+			return 0, err
+		}
+		if err := recordQuestionRevision(tx, &questions[i], editorID, comment); err != nil {
+			return 0, err
+		}
+	}
+	return len(questions), nil
+}
+
+// deleteTags 删除标签及其与题目的关联（包括已删除题目的关联）
+func deleteTags(tx *gorm.DB, tagIDs []uint) error {
+	if err := tx.Exec("DELETE FROM question_tag WHERE tag_id IN ?", tagIDs).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&entity.Tag{}, tagIDs).Error
+}
+
+// syncQuestionTags 按题目的 Tags 字符串更新题目与标签的关联，不存在的标签自动创建
+// Tags 字符串会被规范为去重后的标签名，并使用标签表中已有的名称写法
+func syncQuestionTags(tx *gorm.DB, question *entity.Question) error {
+	names := entity.ParseTagNames(question.Tags)
+	tags := make([]entity.Tag, len(names))
+	for i, name := range names {
+		if err := tx.Where("name = ?", name).Attrs(entity.Tag{Name: name}).FirstOrCreate(&tags[i]).Error; err != nil {
+			return err
+		}
+		names[i] = tags[i].Name
+	}
+
+	association := tx.Model(question).Association("TagRefs")
+	var err error
+	if len(tags) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(tags)
+	}
+	if err != nil {
+		return err
+	}
+
+	if tags := entity.JoinTagNames(names); tags != question.Tags {
+		question.Tags = tags
+		return tx.Model(question).UpdateColumn("tags", tags).Error
+	}
+	return nil
+}
+
+// taggedQuestionIDs 返回带有指定标签的题目ID子查询
+// matchAll 为 true 时要求题目同时带有全部标签，否则带有任一标签即可
+func taggedQuestionIDs(names []string, matchAll bool) *gorm.DB {
+	query := database.DB.Table("question_tag").
+		Select("question_tag.question_id").
+		Joins("JOIN tag ON tag.id = question_tag.tag_id").
+		Where("tag.name IN ?", names)
+	if matchAll {
+		query = query.Group("question_tag.question_id").Having("COUNT(DISTINCT tag.id) = ?", len(names))
+	}
+	return query
+}
@@ -0,0 +1,279 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+)
+
+// DefaultThreshold 默认的相似度阈值，达到阈值的两道题视为疑似重复
+const DefaultThreshold = 0.8
+
+// 相似度中各部分的权重，题目没有选项时选项权重并入题干
+const (
+	titleWeight   = 0.6
+	optionsWeight = 0.25
+	answerWeight  = 0.15
+)
+
+// Fingerprint 题目查重指纹
+type Fingerprint struct {
+	QuestionID  uint
+	Type        entity.QuestionType
+	ContentHash string // 规范化后的题干、选项和答案的哈希，相同即为完全重复
+	MediaHash   string // 媒体文件内容的哈希，没有媒体时为空
+
+	title   map[string]bool
+	options map[string]bool
+	answer  string
+}
+
+// NewFingerprint 计算题目的查重指纹，mediaHash 由调用方根据媒体文件内容计算
+func NewFingerprint(question *entity.Question, mediaHash string) *Fingerprint {
+	fp := &Fingerprint{
+		QuestionID: question.ID,
+		Type:       question.Type,
+		MediaHash:  mediaHash,
+		title:      shingles(Normalize(question.Title)),
+		options:    make(map[string]bool),
+		answer:     Normalize(question.Answer),
+	}
+
+	options := grading.ParseOptionTexts(question.Options)
+	normalized := make([]string, 0, len(options))
+	for _, option := range options {
+		if option = Normalize(option); option != "" {
+			fp.options[option] = true
+			normalized = append(normalized, option)
+		}
+	}
+	sort.Strings(normalized)
+
+	fp.ContentHash = hashStrings(string(question.Type), Normalize(question.Title), strings.Join(normalized, "\x1f"), fp.answer)
+	return fp
+}
+
+// Normalize 规范化文本用于比较：全角转半角、转小写，去掉空白和标点，保留运算符号
+func Normalize(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '　':
+			continue
+		case r >= '！' && r <= '～':
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) && !strings.ContainsRune("%.-*/", r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// MediaHash 将多个媒体文件的内容哈希合并为一个，与媒体顺序无关
+func MediaHash(hashes []string) string {
+	if len(hashes) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+	return hashStrings(sorted...)
+}
+
+// HashBytes 计算内容的哈希
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Similarity 计算两道题的相似度，取值0-1
+// 题型不同视为不重复；题干、选项和答案完全相同且媒体一致时为1；
+// 文字相同但配图不同的题目（如看图数数）往往是不同的题，因此媒体不一致时相似度打折
+func Similarity(a, b *Fingerprint) float64 {
+	if a.Type != b.Type {
+		return 0
+	}
+	if a.ContentHash == b.ContentHash && a.MediaHash == b.MediaHash {
+		return 1
+	}
+
+	titleW, optionsW := titleWeight, optionsWeight
+	if len(a.options) == 0 && len(b.options) == 0 {
+		titleW, optionsW = titleWeight+optionsWeight, 0
+	}
+	score := titleW * jaccard(a.title, b.title)
+	if optionsW > 0 {
+		score += optionsW * jaccard(a.options, b.options)
+	}
+	if a.answer == b.answer {
+		score += answerWeight
+	}
+
+	switch {
+	case a.MediaHash == b.MediaHash:
+	case a.MediaHash != "" && b.MediaHash != "":
+		score *= 0.5
+	default:
+		score *= 0.7
+	}
+	return score
+}
+
+// Match 一条疑似重复的匹配
+type Match struct {
+	QuestionID uint
+	Similarity float64
+	Exact      bool // 内容和媒体完全相同
+}
+
+// Index 查重索引，按题干片段建立倒排表，只对有共同片段的题目计算相似度
+type Index struct {
+	prints    []*Fingerprint
+	byShingle map[string][]int
+}
+
+// NewIndex 创建查重索引
+func NewIndex(prints []*Fingerprint) *Index {
+	ix := &Index{byShingle: make(map[string][]int)}
+	for _, fp := range prints {
+		ix.Add(fp)
+	}
+	return ix
+}
+
+// Add 向索引中加入一道题
+func (ix *Index) Add(fp *Fingerprint) {
+	n := len(ix.prints)
+	ix.prints = append(ix.prints, fp)
+	for shingle := range fp.title {
+		ix.byShingle[shingle] = append(ix.byShingle[shingle], n)
+	}
+}
+
+// Find 查找与指纹相似度不低于 threshold 的题目，按相似度从高到低排列，不包含指纹对应的题目本身
+func (ix *Index) Find(fp *Fingerprint, threshold float64) []Match {
+	candidates := make(map[int]bool)
+	for shingle := range fp.title {
+		for _, i := range ix.byShingle[shingle] {
+			candidates[i] = true
+		}
+	}
+	// 题干为空的题目没有片段，只能按完全相同匹配
+	if len(fp.title) == 0 {
+		for i, other := range ix.prints {
+			if other.ContentHash == fp.ContentHash {
+				candidates[i] = true
+			}
+		}
+	}
+
+	var matches []Match
+	for i := range candidates {
+		other := ix.prints[i]
+		if fp.QuestionID != 0 && other.QuestionID == fp.QuestionID {
+			continue
+		}
+		if sim := Similarity(fp, other); sim >= threshold {
+			matches = append(matches, Match{
+				QuestionID: other.QuestionID,
+				Similarity: round(sim),
+				Exact:      sim == 1,
+			})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].QuestionID < matches[j].QuestionID
+	})
+	return matches
+}
+
+// Clusters 将索引中相互疑似重复的题目分组（相似关系具有传递性），只返回两道题以上的分组
+// 每组内题目ID升序，组间按最小题目ID排序
+func (ix *Index) Clusters(threshold float64) [][]uint {
+	parent := make(map[uint]uint)
+	var find func(uint) uint
+	find = func(id uint) uint {
+		if p, ok := parent[id]; ok && p != id {
+			root := find(p)
+			parent[id] = root
+			return root
+		}
+		return id
+	}
+
+	for _, fp := range ix.prints {
+		for _, match := range ix.Find(fp, threshold) {
+			a, b := find(fp.QuestionID), find(match.QuestionID)
+			if a == b {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			parent[a] = a
+			parent[b] = a
+		}
+	}
+
+	groups := make(map[uint][]uint)
+	for id := range parent {
+		root := find(id)
+		groups[root] = append(groups[root], id)
+	}
+	var clusters [][]uint
+	for _, ids := range groups {
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		clusters = append(clusters, ids)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0] < clusters[j][0] })
+	return clusters
+}
+
+// shingles 将规范化文本切分为相邻两字的片段，不足两字时整段作为一个片段
+func shingles(text string) map[string]bool {
+	runes := []rune(text)
+	set := make(map[string]bool)
+	if len(runes) < 2 {
+		if len(runes) == 1 {
+			set[text] = true
+		}
+		return set
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		set[string(runes[i:i+2])] = true
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for key := range a {
+		if b[key] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+func hashStrings(parts ...string) string {
+	return HashBytes([]byte(strings.Join(parts, "\x1e")))
+}
+
+func round(v float64) float64 {
+	return float64(int(v*1000+0.5)) / 1000
+}
@@ -0,0 +1,293 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+)
+
+// maxAttempts 单个种子下寻找满足约束的变量取值的最大尝试次数
+const maxAttempts = 1000
+
+// ErrUnsatisfiable 变量范围内找不到满足约束的取值
+var ErrUnsatisfiable = errors.New("无法生成满足约束条件的题目，请检查变量范围和约束条件")
+
+var (
+	identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	comparisonPattern = regexp.MustCompile(`^(.+?)(<=|>=|!=|==|<|>|=)(.+)$`)
+)
+
+// Spec 解析后的模板定义
+type Spec struct {
+	Stem        string
+	Expression  string
+	Variables   []entity.TemplateVariable
+	Constraints []string
+}
+
+// Variant 按种子生成的一道具体题目
+type Variant struct {
+	Seed   int64          `json:"seed"`
+	Values map[string]int `json:"values"`
+	Stem   string         `json:"stem"`
+	Answer string         `json:"answer"`
+}
+
+// Parse 解析并校验模板：变量名合法且不重复、范围有效，算式和约束只引用已定义的变量
+func Parse(template *entity.QuestionTemplate) (*Spec, error) {
+	spec := &Spec{Stem: template.Stem, Expression: template.Expression}
+	if strings.TrimSpace(template.Variables) != "" {
+		if err := json.Unmarshal([]byte(template.Variables), &spec.Variables); err != nil {
+			return nil, fmt.Errorf("变量定义格式错误: %v", err)
+		}
+	}
+	if strings.TrimSpace(template.Constraints) != "" {
+		if err := json.Unmarshal([]byte(template.Constraints), &spec.Constraints); err != nil {
+			return nil, fmt.Errorf("约束条件格式错误: %v", err)
+		}
+	}
+	return spec, spec.Validate()
+}
+
+// Validate 校验模板定义
+func (s *Spec) Validate() error {
+	if len(s.Variables) == 0 {
+		return errors.New("至少需要定义一个变量")
+	}
+
+	seen := map[string]bool{}
+	values := map[string]int{}
+	for _, v := range s.Variables {
+		if !identifierPattern.MatchString(v.Name) || identifierPattern.FindString(v.Name) != v.Name {
+			return fmt.Errorf("无效的变量名: %s", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("变量名重复: %s", v.Name)
+		}
+		if v.Min > v.Max {
+			return fmt.Errorf("变量 %s 的最小值大于最大值", v.Name)
+		}
+		if v.Step < 0 {
+			return fmt.Errorf("变量 %s 的步长不能为负数", v.Name)
+		}
+		seen[v.Name] = true
+		values[v.Name] = v.Min
+	}
+
+	if _, err := substitute(s.Expression, values); err != nil {
+		return fmt.Errorf("答案算式错误: %v", err)
+	}
+	for _, constraint := range s.Constraints {
+		switch constraint {
+		case entity.ConstraintNoCarry, entity.ConstraintNoBorrow, entity.ConstraintIntegerResult, entity.ConstraintNonNegative:
+			continue
+		}
+		match := comparisonPattern.FindStringSubmatch(constraint)
+		if match == nil {
+			return fmt.Errorf("无法识别的约束条件: %s", constraint)
+		}
+		for _, side := range []string{match[1], match[3]} {
+			if _, err := substitute(side, values); err != nil {
+				return fmt.Errorf("约束条件 %s 错误: %v", constraint, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Generate 按种子生成题目，同一模板和种子总是得到相同的题目
+func (s *Spec) Generate(seed int64) (*Variant, error) {
+	rng := rand.New(rand.NewSource(seed))
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		values := make(map[string]int, len(s.Variables))
+		for _, v := range s.Variables {
+			step := v.Step
+			if step <= 0 {
+				step = 1
+			}
+			values[v.Name] = v.Min + rng.Intn((v.Max-v.Min)/step+1)*step
+		}
+
+		answer, ok, err := s.evaluate(values)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		return &Variant{
+			Seed:   seed,
+			Values: values,
+			Stem:   s.render(values),
+			Answer: formatNumber(answer),
+		}, nil
+	}
+	return nil, ErrUnsatisfiable
+}
+
+// evaluate 计算答案并检查约束，取值不满足约束或算式无意义（如除以0）时返回 false
+func (s *Spec) evaluate(values map[string]int) (float64, bool, error) {
+	expr, err := substitute(s.Expression, values)
+	if err != nil {
+		return 0, false, err
+	}
+	answer, err := grading.EvaluateExpression(expr)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	for _, constraint := range s.Constraints {
+		ok, err := s.check(constraint, values, answer)
+		if err != nil || !ok {
+			return 0, false, err
+		}
+	}
+	return answer, true, nil
+}
+
+// check 检查单个约束条件
+func (s *Spec) check(constraint string, values map[string]int, answer float64) (bool, error) {
+	ordered := make([]int, len(s.Variables))
+	for i, v := range s.Variables {
+		ordered[i] = values[v.Name]
+	}
+
+	switch constraint {
+	case entity.ConstraintNoCarry:
+		return noCarry(ordered), nil
+	case entity.ConstraintNoBorrow:
+		return noBorrow(ordered), nil
+	case entity.ConstraintIntegerResult:
+		return isInteger(answer), nil
+	case entity.ConstraintNonNegative:
+		return answer >= 0, nil
+	}
+
+	match := comparisonPattern.FindStringSubmatch(constraint)
+	if match == nil {
+		return false, fmt.Errorf("无法识别的约束条件: %s", constraint)
+	}
+	lhs, err := evaluateWith(match[1], values)
+	if err != nil {
+		return false, nil
+	}
+	rhs, err := evaluateWith(match[3], values)
+	if err != nil {
+		return false, nil
+	}
+	switch match[2] {
+	case "<":
+		return lhs < rhs, nil
+	case "<=":
+		return lhs <= rhs, nil
+	case ">":
+		return lhs > rhs, nil
+	case ">=":
+		return lhs >= rhs, nil
+	case "!=":
+		return lhs != rhs, nil
+	default:
+		return lhs == rhs, nil
+	}
+}
+
+// render 将题干中的 {变量名} 替换为取值
+func (s *Spec) render(values map[string]int) string {
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range s.Variables {
+		pairs = append(pairs, "{"+v.Name+"}", strconv.Itoa(values[v.Name]))
+	}
+	return strings.NewReplacer(pairs...).Replace(s.Stem)
+}
+
+// substitute 将算式中的变量名替换为取值，负数加括号；出现未定义的变量时报错
+func substitute(expr string, values map[string]int) (string, error) {
+	var unknown string
+	replaced := identifierPattern.ReplaceAllStringFunc(grading.NormalizeMath(expr), func(name string) string {
+		value, ok := values[name]
+		if !ok {
+			unknown = name
+			return name
+		}
+		if value < 0 {
+			return "(" + strconv.Itoa(value) + ")"
+		}
+		return strconv.Itoa(value)
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("未定义的变量: %s", unknown)
+	}
+	return replaced, nil
+}
+
+// evaluateWith 代入变量后计算算式
+func evaluateWith(expr string, values map[string]int) (float64, error) {
+	replaced, err := substitute(expr, values)
+	if err != nil {
+		return 0, err
+	}
+	return grading.EvaluateExpression(replaced)
+}
+
+// noCarry 各数逐位相加均不超过9
+func noCarry(numbers []int) bool {
+	for {
+		sum, remaining := 0, false
+		for i, n := range numbers {
+			if n < 0 {
+				return false
+			}
+			sum += n % 10
+			numbers[i] = n / 10
+			if numbers[i] > 0 {
+				remaining = true
+			}
+		}
+		if sum > 9 {
+			return false
+		}
+		if !remaining {
+			return true
+		}
+	}
+}
+
+// noBorrow 第一个数依次减去其余各数，每一步逐位相减都不需要退位
+func noBorrow(numbers []int) bool {
+	if len(numbers) == 0 {
+		return true
+	}
+	current := numbers[0]
+	for _, n := range numbers[1:] {
+		if n < 0 || current < n {
+			return false
+		}
+		for a, b := current, n; b > 0; a, b = a/10, b/10 {
+			if a%10 < b%10 {
+				return false
+			}
+		}
+		current -= n
+	}
+	return true
+}
+
+func isInteger(value float64) bool {
+	return math.Abs(value-math.Round(value)) < 1e-9
+}
+
+// formatNumber 整数不带小数点，小数最多保留6位
+func formatNumber(value float64) string {
+	if isInteger(value) {
+		return strconv.FormatInt(int64(math.Round(value)), 10)
+	}
+	return strconv.FormatFloat(math.Round(value*1e6)/1e6, 'f', -1, 64)
+}
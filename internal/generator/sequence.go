@@ -0,0 +1,172 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// SequenceKinds 支持生成的数列类型
+var SequenceKinds = []string{
+	entity.SequenceArithmetic,
+	entity.SequenceGeometric,
+	entity.SequenceAlternating,
+	entity.SequenceRepeating,
+}
+
+// GenerateSequence 按类型和难度（1-5）生成数字序列，同一种子总是得到相同的结果
+// 难度越高数列越长、数值越大、缺失的项越多；缺失项可以出现在任意位置，但保证规律仍可推出
+func GenerateSequence(kind string, difficulty int, seed int64) (*entity.SequenceData, error) {
+	if difficulty < 1 {
+		difficulty = 1
+	}
+	if difficulty > 5 {
+		difficulty = 5
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	var terms []int
+	period := 0
+	length := 5 + difficulty/2
+	switch kind {
+	case entity.SequenceArithmetic:
+		step := 1 + rng.Intn(2+2*difficulty)
+		start := 1 + rng.Intn(10*difficulty)
+		if difficulty >= 4 && rng.Intn(2) == 0 {
+			// 较难时出现递减数列，起始值保证各项不为负
+			start += step * (length - 1)
+			step = -step
+		}
+		for i := 0; i < length; i++ {
+			terms = append(terms, start+i*step)
+		}
+	case entity.SequenceGeometric:
+		length = 4 + difficulty/3
+		ratio := 2 + rng.Intn(1+difficulty/2)
+		value := 1 + rng.Intn(difficulty+2)
+		for i := 0; i < length; i++ {
+			terms = append(terms, value)
+			value *= ratio
+		}
+	case entity.SequenceAlternating:
+		up := 2 + rng.Intn(2+difficulty)
+		down := 1 + rng.Intn(up-1)
+		value := 1 + rng.Intn(5*difficulty)
+		for i := 0; i < length; i++ {
+			terms = append(terms, value)
+			if i%2 == 0 {
+				value += up
+			} else {
+				value -= down
+			}
+		}
+		period = 2
+	case entity.SequenceRepeating:
+		period = 2 + (difficulty-1)/2
+		if length < 2*period+1 {
+			length = 2*period + 1
+		}
+		limit := 9
+		if difficulty >= 4 {
+			limit = 20
+		}
+		block := rng.Perm(limit)[:period]
+		for i := 0; i < length; i++ {
+			terms = append(terms, block[i%period]+1)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的数列类型: %s", kind)
+	}
+
+	missingCount := 1
+	switch {
+	case difficulty >= 5:
+		missingCount = 3
+	case difficulty >= 3:
+		missingCount = 2
+	}
+
+	return &entity.SequenceData{
+		Kind:       kind,
+		Terms:      terms,
+		Missing:    pickMissing(rng, len(terms), missingCount, period),
+		Difficulty: difficulty,
+	}, nil
+}
+
+// SequenceTitle 生成数列题的题干，缺失项显示为括号
+func SequenceTitle(data *entity.SequenceData) string {
+	missing := map[int]bool{}
+	for _, index := range data.Missing {
+		missing[index] = true
+	}
+	items := make([]string, len(data.Terms))
+	for i, term := range data.Terms {
+		if missing[i] {
+			items[i] = "（  ）"
+		} else {
+			items[i] = strconv.Itoa(term)
+		}
+	}
+	return "找规律，填一填：" + strings.Join(items, "，")
+}
+
+// pickMissing 随机选择缺失项的位置，保证剩余的项仍能推出规律：
+// 至少保留一对相邻的已知项；有周期的数列（交替、循环）每个周期位置至少保留一项，
+// 交替数列还需两种步长各保留一对相邻已知项
+func pickMissing(rng *rand.Rand, length, count, period int) []int {
+	for attempt := 0; attempt < 100; attempt++ {
+		positions := rng.Perm(length)[:count]
+		if solvable(length, positions, period) {
+			sort.Ints(positions)
+			return positions
+		}
+	}
+	// 兜底：缺失末尾的项
+	positions := make([]int, count)
+	for i := range positions {
+		positions[i] = length - count + i
+	}
+	return positions
+}
+
+func solvable(length int, missing []int, period int) bool {
+	hidden := make([]bool, length)
+	for _, index := range missing {
+		hidden[index] = true
+	}
+
+	// 相邻已知项，按起点奇偶区分（交替数列需要两种步长）
+	pairs := [2]bool{}
+	for i := 0; i+1 < length; i++ {
+		if !hidden[i] && !hidden[i+1] {
+			pairs[i%2] = true
+		}
+	}
+	if !pairs[0] && !pairs[1] {
+		return false
+	}
+	if period == 2 && !(pairs[0] && pairs[1]) {
+		return false
+	}
+
+	if period > 0 {
+		for offset := 0; offset < period; offset++ {
+			visible := false
+			for i := offset; i < length; i += period {
+				if !hidden[i] {
+					visible = true
+					break
+				}
+			}
+			if !visible {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package grading
+
+import (
+	"encoding/json"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// gradeChoice 选择题判分，支持字母答案与选项内容互相映射
+func gradeChoice(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	correct := strings.TrimSpace(question.Answer)
+	user := strings.TrimSpace(answer)
+
+	// 最简单的情况：直接比较（忽略大小写）
+	if strings.EqualFold(correct, user) {
+		return newResult(true)
+	}
+
+	options := ParseOptionTexts(question.Options)
+	if len(options) == 0 {
+		return newResult(false)
+	}
+
+	// 字母答案转换为对应的选项内容后再比较
+	return newResult(strings.EqualFold(resolveOption(correct, options), resolveOption(user, options)))
+}
+
+// gradeJudge 判断题判分，兼容"对/错"、"√/×"、"true/false"等写法
+func gradeJudge(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	correct := strings.TrimSpace(question.Answer)
+	user := strings.TrimSpace(answer)
+	if strings.EqualFold(correct, user) {
+		return newResult(true)
+	}
+	return newResult(NormalizeJudgeAnswer(correct) == NormalizeJudgeAnswer(user))
+}
+
+// NormalizeJudgeAnswer 标准化判断题答案
+func NormalizeJudgeAnswer(answer string) string {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "true", "正确", "对", "是", "√", "1", "t":
+		return "true"
+	case "false", "错误", "错", "否", "×", "0", "f":
+		return "false"
+	default:
+		return answer
+	}
+}
+
+// ParseOptionTexts 解析选项JSON，兼容字符串数组和 QuestionOption 数组两种格式
+func ParseOptionTexts(options string) []string {
+	if strings.TrimSpace(options) == "" {
+		return nil
+	}
+
+	var texts []string
+	if err := json.Unmarshal([]byte(options), &texts); err == nil {
+		return texts
+	}
+
+	var structured []entity.QuestionOption
+	if err := json.Unmarshal([]byte(options), &structured); err == nil {
+		texts = make([]string, len(structured))
+		for i, option := range structured {
+			texts[i] = option.Text
+			if texts[i] == "" {
+				texts[i] = option.Value
+			}
+		}
+		return texts
+	}
+
+	return nil
+}
+
+// optionIndex 将单个字母答案（A/a、B/b...）转换为选项下标，非字母返回-1
+func optionIndex(answer string) int {
+	if len(answer) != 1 {
+		return -1
+	}
+	switch ch := answer[0]; {
+	case ch >= 'A' && ch <= 'Z':
+		return int(ch - 'A')
+	case ch >= 'a' && ch <= 'z':
+		return int(ch - 'a')
+	default:
+		return -1
+	}
+}
+
+// resolveOption 字母答案映射为选项内容，其他答案原样返回
+func resolveOption(answer string, options []string) string {
+	if index := optionIndex(answer); index >= 0 && index < len(options) {
+		return strings.TrimSpace(options[index])
+	}
+	return answer
+}
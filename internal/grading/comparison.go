@@ -0,0 +1,218 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// 比较关系
+const (
+	RelationMore  = "more"
+	RelationLess  = "less"
+	RelationEqual = "equal"
+)
+
+// comparisonWords 各比较类型对应的关系用词：多/少/一样多、大/小/一样大、长/短/一样长
+var comparisonWords = map[string]map[string]string{
+	"quantity": {RelationMore: "多", RelationLess: "少", RelationEqual: "一样多"},
+	"size":     {RelationMore: "大", RelationLess: "小", RelationEqual: "一样大"},
+	"length":   {RelationMore: "长", RelationLess: "短", RelationEqual: "一样长"},
+}
+
+var digitsPattern = regexp.MustCompile(`\d+`)
+
+// ComparisonKey 由比较题数据推导出的标准答案
+type ComparisonKey struct {
+	Subject    string `json:"subject"`    // 比较主体，即模板中的 {0}
+	Object     string `json:"object"`     // 比较对象，即模板中的 {1}
+	Relation   string `json:"relation"`   // 主体相对对象的关系：more, less, equal
+	Difference int    `json:"difference"` // 相差的数量
+}
+
+// ComparisonAnswer 学生提交的结构化比较题答案
+type ComparisonAnswer struct {
+	Element    string `json:"element"`    // 作为主语的元素，为空时视为 {0}
+	Relation   string `json:"relation"`   // more/less/equal 或 多/少/一样多 等
+	Difference *int   `json:"difference"` // 相差的数量，可选
+}
+
+// ParseComparisonData 解析比较题数据，至少需要两个元素
+func ParseComparisonData(elementData string) (*entity.ComparisonData, bool) {
+	if strings.TrimSpace(elementData) == "" {
+		return nil, false
+	}
+	var data entity.ComparisonData
+	if err := json.Unmarshal([]byte(elementData), &data); err != nil || len(data.Elements) < 2 {
+		return nil, false
+	}
+	return &data, true
+}
+
+// DeriveComparisonKey 根据元素数量推导标准答案
+func DeriveComparisonKey(data *entity.ComparisonData) ComparisonKey {
+	subject, object := data.Elements[0], data.Elements[1]
+	key := ComparisonKey{Subject: subject.Name, Object: object.Name}
+	switch {
+	case subject.Count > object.Count:
+		key.Relation = RelationMore
+		key.Difference = subject.Count - object.Count
+	case subject.Count < object.Count:
+		key.Relation = RelationLess
+		key.Difference = object.Count - subject.Count
+	default:
+		key.Relation = RelationEqual
+	}
+	return key
+}
+
+// ComparisonAnswerText 生成比较题标准答案文本，如"蝴蝶比花朵多2"，用于回写 Question.Answer
+func ComparisonAnswerText(data *entity.ComparisonData) string {
+	key := DeriveComparisonKey(data)
+	words := comparisonWordsFor(data.QuestionType)
+	if key.Relation == RelationEqual {
+		return fmt.Sprintf("%s和%s%s", key.Subject, key.Object, words[RelationEqual])
+	}
+	text := fmt.Sprintf("%s比%s%s", key.Subject, key.Object, words[key.Relation])
+	if asksDifference(data) {
+		text += strconv.Itoa(key.Difference)
+	}
+	return text
+}
+
+// gradeComparison 比较题判分，标准答案由比较题数据推导，不依赖 Question.Answer
+func gradeComparison(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	data, ok := ParseComparisonData(question.ElementData)
+	if !ok {
+		return gradeText(question, answer, config)
+	}
+
+	key := DeriveComparisonKey(data)
+	words := comparisonWordsFor(data.QuestionType)
+	user := parseComparisonAnswer(answer, key, words)
+
+	relation := PartResult{ID: "relation", Answer: user.Relation}
+	if user.Relation == key.Relation {
+		relation.IsCorrect = true
+		relation.Score = 1
+	} else {
+		relation.Feedback = "比较关系错误"
+	}
+	parts := []PartResult{relation}
+
+	// 模板要求回答相差数量，或学生给出了数量时才判这一项
+	if key.Relation != RelationEqual && (asksDifference(data) || user.Difference != nil) {
+		difference := PartResult{ID: "difference"}
+		switch {
+		case user.Difference == nil:
+			difference.Feedback = "未填写相差的数量"
+		case *user.Difference == key.Difference:
+			difference.Answer = strconv.Itoa(*user.Difference)
+			difference.IsCorrect = true
+			difference.Score = 1
+		default:
+			difference.Answer = strconv.Itoa(*user.Difference)
+			difference.Feedback = "相差的数量不对"
+		}
+		parts = append(parts, difference)
+	}
+
+	return combineParts(parts)
+}
+
+// parseComparisonAnswer 解析学生答案，统一转换为以 {0} 为主语的关系
+// 支持 JSON 结构化答案，也支持"花朵比蝴蝶少2"、"多"、"一样多"等文本
+func parseComparisonAnswer(answer string, key ComparisonKey, words map[string]string) ComparisonAnswer {
+	var parsed ComparisonAnswer
+	if err := json.Unmarshal([]byte(answer), &parsed); err != nil {
+		parsed = parseComparisonText(NormalizeMath(answer), key, words)
+	}
+
+	parsed.Relation = normalizeRelation(parsed.Relation, words)
+	if element := strings.TrimSpace(parsed.Element); element != "" && element == key.Object && key.Object != key.Subject {
+		// 以 {1} 为主语时关系取反，"花朵比蝴蝶少" 等价于 "蝴蝶比花朵多"
+		switch parsed.Relation {
+		case RelationMore:
+			parsed.Relation = RelationLess
+		case RelationLess:
+			parsed.Relation = RelationMore
+		}
+	}
+	return parsed
+}
+
+// parseComparisonText 从文本答案中识别主语、关系和数量
+func parseComparisonText(text string, key ComparisonKey, words map[string]string) ComparisonAnswer {
+	var parsed ComparisonAnswer
+
+	subjectAt := strings.Index(text, key.Subject)
+	objectAt := strings.Index(text, key.Object)
+	switch {
+	case subjectAt >= 0 && (objectAt < 0 || subjectAt < objectAt):
+		parsed.Element = key.Subject
+	case objectAt >= 0:
+		parsed.Element = key.Object
+	}
+
+	// 去掉元素名称后再识别关系和数量，避免名称中的字干扰
+	rest := strings.NewReplacer(key.Subject, "", key.Object, "").Replace(text)
+	parsed.Relation = rest
+	if digits := digitsPattern.FindString(rest); digits != "" {
+		if n, err := strconv.Atoi(digits); err == nil {
+			parsed.Difference = &n
+		}
+	}
+	return parsed
+}
+
+// normalizeRelation 将关系用词统一为 more/less/equal
+func normalizeRelation(relation string, words map[string]string) string {
+	relation = strings.ToLower(strings.TrimSpace(relation))
+	switch relation {
+	case RelationMore, RelationLess, RelationEqual:
+		return relation
+	}
+	// "一样多" 中也包含 "多"，必须先判断相等
+	if strings.Contains(relation, "一样") || strings.Contains(relation, "相等") || strings.Contains(relation, "同样") {
+		return RelationEqual
+	}
+	if strings.Contains(relation, words[RelationMore]) {
+		return RelationMore
+	}
+	if strings.Contains(relation, words[RelationLess]) {
+		return RelationLess
+	}
+	return relation
+}
+
+// comparisonWordsFor 获取比较类型对应的关系用词，默认按数量比较
+func comparisonWordsFor(questionType string) map[string]string {
+	if words, ok := comparisonWords[questionType]; ok {
+		return words
+	}
+	return comparisonWords["quantity"]
+}
+
+// asksDifference 模板是否要求回答相差的数量，如 "{0}比{1}多几个"、"{0}比{1}多{2}"
+func asksDifference(data *entity.ComparisonData) bool {
+	return strings.Contains(data.CompareFormat, "{2}") || strings.Contains(data.CompareFormat, "几")
+}
+
+// combineParts 汇总分项结果：全部正确才算正确，得分为各项平均分
+func combineParts(parts []PartResult) Result {
+	result := Result{IsCorrect: len(parts) > 0, Parts: parts}
+	for _, part := range parts {
+		result.Score += part.Score
+		if !part.IsCorrect {
+			result.IsCorrect = false
+		}
+	}
+	if len(parts) > 0 {
+		result.Score /= float64(len(parts))
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package grading
+
+import (
+	"encoding/json"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// gradeFillIn 填空题判分，复杂填空题按填空逐个判分，其余按整体文本比较
+func gradeFillIn(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	blanks := parseComplexBlanks(question.ElementData)
+	if len(blanks) == 0 {
+		return gradeText(question, answer, config)
+	}
+	return gradeBlanks(blanks, ParseBlankAnswers(answer, blanks), config)
+}
+
+// gradeBlanks 逐个填空判分并计算部分得分
+// 必填项始终计分；选填项仅在作答后计分，未作答不扣分
+// 每个填空使用题目配置的匹配规则，并接受该填空自己的可接受答案
+func gradeBlanks(blanks []entity.BlankItem, answers map[string]string, config entity.GradingConfig) Result {
+	result := Result{IsCorrect: true}
+	var earned, total float64
+
+	for _, blank := range blanks {
+		userAnswer := strings.TrimSpace(answers[blank.ID])
+		part := PartResult{ID: blank.ID, Answer: userAnswer}
+
+		match, matched := MatchAnswer(config, append([]string{blank.Answer}, blank.AcceptedAnswers...), userAnswer)
+		switch {
+		case userAnswer == "" && !blank.Required:
+			part.Feedback = "选填，未作答"
+			result.Parts = append(result.Parts, part)
+			continue
+		case userAnswer == "":
+			part.Feedback = "未作答"
+		case matched:
+			part.IsCorrect = true
+			part.Score = 1
+			part.MatchedRule = match.Rule
+		default:
+			part.Feedback = "答案错误"
+		}
+
+		total++
+		earned += part.Score
+		if !part.IsCorrect {
+			result.IsCorrect = false
+		}
+		result.Parts = append(result.Parts, part)
+	}
+
+	if total > 0 {
+		result.Score = earned / total
+	} else {
+		result.Score = 1
+	}
+	return result
+}
+
+// parseComplexBlanks 从元素数据中解析复杂填空题的全部填空项
+func parseComplexBlanks(elementData string) []entity.BlankItem {
+	if strings.TrimSpace(elementData) == "" {
+		return nil
+	}
+
+	var data entity.ComplexQuestionData
+	if err := json.Unmarshal([]byte(elementData), &data); err != nil {
+		return nil
+	}
+
+	var blanks []entity.BlankItem
+	for _, sub := range data.SubQuestions {
+		blanks = append(blanks, sub.Blanks...)
+	}
+	return blanks
+}
+
+// ParseBlankAnswers 解析按填空ID提交的答案（JSON对象）
+// 只有一个填空时也接受直接提交的文本答案
+func ParseBlankAnswers(answer string, blanks []entity.BlankItem) map[string]string {
+	answers := map[string]string{}
+	if err := json.Unmarshal([]byte(answer), &answers); err == nil {
+		return answers
+	}
+	if len(blanks) == 1 {
+		answers[blanks[0].ID] = answer
+	}
+	return answers
+}
+
+// EncodeBlankAnswers 将按填空ID提交的答案编码为可存储的答案文本
+func EncodeBlankAnswers(answers map[string]string) string {
+	data, err := json.Marshal(answers)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// matchText 去除前后空格后忽略大小写比较
+func matchText(expected, actual string) bool {
+	return strings.EqualFold(strings.TrimSpace(expected), strings.TrimSpace(actual))
+}
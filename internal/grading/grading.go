@@ -0,0 +1,138 @@
+package grading
+
+import (
+	"encoding/json"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// Result 判分结果
+type Result struct {
+	IsCorrect bool         `json:"is_correct"`
+	Score     float64      `json:"score"`           // 得分率，0-1
+	Points    float64      `json:"points"`          // 实际得分 = 得分率 × 题目分值
+	Parts     []PartResult `json:"parts,omitempty"` // 分项判分结果（如多个填空）
+
+	MatchedRule   string `json:"matched_rule,omitempty"`   // 命中的答案匹配规则
+	MatchedAnswer string `json:"matched_answer,omitempty"` // 命中的标准答案或正则表达式
+}
+
+// PartResult 单个判分项的结果
+type PartResult struct {
+	ID        string  `json:"id"`
+	Answer    string  `json:"answer"`
+	IsCorrect bool    `json:"is_correct"`
+	Score     float64 `json:"score"`
+	Feedback  string  `json:"feedback,omitempty"`
+
+	MatchedRule string `json:"matched_rule,omitempty"` // 命中的答案匹配规则
+}
+
+// Grader 判分器，每种题型对应一个实现，config 为合并试卷默认值后的判分配置
+type Grader interface {
+	Grade(question *entity.Question, answer string, config entity.GradingConfig) Result
+}
+
+// GraderFunc 允许普通函数作为判分器使用
+type GraderFunc func(question *entity.Question, answer string, config entity.GradingConfig) Result
+
+// Grade 实现 Grader 接口
+func (f GraderFunc) Grade(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	return f(question, answer, config)
+}
+
+var registry = map[entity.QuestionType]Grader{}
+
+// defaultGrader 未注册题型使用的判分器
+var defaultGrader Grader = GraderFunc(gradeText)
+
+func init() {
+	Register(entity.TypeChoice, GraderFunc(gradeChoice))
+	Register(entity.TypeMultiChoice, GraderFunc(gradeMultiChoice))
+	Register(entity.TypeJudge, GraderFunc(gradeJudge))
+	Register(entity.TypeFillIn, GraderFunc(gradeFillIn))
+	Register(entity.TypeMath, GraderFunc(gradeMath))
+	Register(entity.TypeComparison, GraderFunc(gradeComparison))
+	Register(entity.TypeCircleSelect, GraderFunc(gradeCircleSelect))
+	Register(entity.TypeReasoning, GraderFunc(gradeReasoning))
+}
+
+// Register 为题型注册判分器，重复注册会覆盖之前的判分器
+func Register(questionType entity.QuestionType, grader Grader) {
+	registry[questionType] = grader
+}
+
+// Lookup 获取题型对应的判分器，未注册时返回默认判分器
+func Lookup(questionType entity.QuestionType) Grader {
+	if grader, ok := registry[questionType]; ok {
+		return grader
+	}
+	return defaultGrader
+}
+
+// Grade 使用题型对应的判分器对答案判分
+func Grade(question *entity.Question, answer string) Result {
+	return GradeWithDefaults(question, answer, entity.GradingConfig{})
+}
+
+// GradeWithDefaults 判分时以 defaults（如试卷的计分规则）补全题目未配置的判分项
+// 题目自身的配置优先于试卷配置
+func GradeWithDefaults(question *entity.Question, answer string, defaults entity.GradingConfig) Result {
+	config := mergeGradingConfig(ParseGradingConfig(question.GradingConfig), defaults)
+	result := Lookup(question.Type).Grade(question, answer, config)
+	result.Points = result.Score * QuestionPoints(config)
+	return result
+}
+
+// QuestionPoints 题目分值，未配置时为1分
+func QuestionPoints(config entity.GradingConfig) float64 {
+	if config.Points > 0 {
+		return config.Points
+	}
+	return 1
+}
+
+// ParseGradingConfig 解析题目的判分配置，为空或格式错误时返回默认配置
+func ParseGradingConfig(raw string) entity.GradingConfig {
+	var config entity.GradingConfig
+	if strings.TrimSpace(raw) != "" {
+		json.Unmarshal([]byte(raw), &config)
+	}
+	return config
+}
+
+// mergeGradingConfig 用默认配置补全题目未设置的判分项
+func mergeGradingConfig(config, defaults entity.GradingConfig) entity.GradingConfig {
+	if config.Tolerance <= 0 {
+		config.Tolerance = defaults.Tolerance
+	}
+	if config.ScoringPolicy == "" {
+		config.ScoringPolicy = defaults.ScoringPolicy
+	}
+	if config.Points <= 0 {
+		config.Points = defaults.Points
+	}
+	return config
+}
+
+// newResult 根据是否正确构造整题结果
+func newResult(correct bool) Result {
+	if correct {
+		return Result{IsCorrect: true, Score: 1}
+	}
+	return Result{}
+}
+
+// newMatchResult 根据文本匹配结果构造整题结果
+func newMatchResult(match Match, ok bool) Result {
+	result := newResult(ok)
+	result.MatchedRule = match.Rule
+	result.MatchedAnswer = match.Key
+	return result
+}
+
+// gradeText 文本题判分：将答案与标准答案及其他可接受答案按配置的规则比较
+func gradeText(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	return newMatchResult(MatchAnswer(config, acceptedKeys(question.Answer, config), answer))
+}
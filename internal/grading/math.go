@@ -0,0 +1,236 @@
+package grading
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"testogo/internal/model/entity"
+)
+
+// defaultTolerance 未配置容差时数值比较使用的默认容差
+const defaultTolerance = 1e-9
+
+// 算式的最大长度和括号、正负号的最大嵌套层数，学生答案超出时不按算式计算，避免递归过深
+const (
+	maxExpressionLength = 200
+	maxExpressionDepth  = 32
+)
+
+var errInvalidExpression = errors.New("无法识别的算式")
+
+// gradeMath 数学题判分
+// 默认按数值比较，"5"、"5.0"、"05"、"５"、"2+3" 视为相同答案；
+// 题目要求严格形式时仅做全角半角和空白的规范化后逐字比较
+func gradeMath(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	correct := NormalizeMath(question.Answer)
+	user := NormalizeMath(answer)
+
+	if correct == user {
+		return newResult(true)
+	}
+	if match, ok := MatchAnswer(config, config.AcceptedAnswers, answer); ok {
+		return newMatchResult(match, true)
+	}
+	if config.ExactForm {
+		return newResult(false)
+	}
+
+	expected, err := EvaluateExpression(correct)
+	if err != nil {
+		// 标准答案不是算式时退回文本比较
+		return newMatchResult(MatchAnswer(config, []string{correct}, user))
+	}
+	actual, err := EvaluateExpression(user)
+	if err != nil {
+		return newResult(false)
+	}
+
+	tolerance := config.Tolerance
+	if tolerance <= 0 {
+		tolerance = defaultTolerance
+	}
+	return newResult(math.Abs(expected-actual) <= tolerance)
+}
+
+// NormalizeMath 规范化数学答案：全角转半角、统一运算符、去除空白和开头的等号
+func NormalizeMath(answer string) string {
+	var b strings.Builder
+	for _, r := range answer {
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r >= 0xFF01 && r <= 0xFF5E: // 全角ASCII字符
+			r -= 0xFEE0
+		case r == '×' || r == '·':
+			r = '*'
+		case r == '÷':
+			r = '/'
+		case r == '−' || r == '—':
+			r = '-'
+		case r == '（':
+			r = '('
+		case r == '）':
+			r = ')'
+		case r == '。':
+			r = '.'
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimPrefix(b.String(), "=")
+}
+
+// EvaluateExpression 计算只包含数字、小数、四则运算和括号的简单算式，分数按除法处理
+// 超过 maxExpressionLength 个字符或嵌套超过 maxExpressionDepth 层的算式视为无法识别
+func EvaluateExpression(expr string) (float64, error) {
+	input := []rune(expr)
+	if len(input) > maxExpressionLength {
+		return 0, errInvalidExpression
+	}
+	p := &exprParser{input: input}
+	value, err := p.parseSum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos != len(p.input) {
+		return 0, errInvalidExpression
+	}
+	return value, nil
+}
+
+// exprParser 递归下降算式解析器
+type exprParser struct {
+	input []rune
+	pos   int
+	depth int // 当前括号和正负号的嵌套层数
+}
+
+// enter 进入一层嵌套，超过最大层数时返回错误，调用方在返回时执行 p.depth--
+func (p *exprParser) enter() error {
+	p.depth++
+	if p.depth > maxExpressionDepth {
+		return errInvalidExpression
+	}
+	return nil
+}
+
+func (p *exprParser) peek() rune {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// parseSum 解析加减法
+func (p *exprParser) parseSum() (float64, error) {
+	value, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			rhs, err := p.parseProduct()
+			if err != nil {
+				return 0, err
+			}
+			value += rhs
+		case '-':
+			p.pos++
+			rhs, err := p.parseProduct()
+			if err != nil {
+				return 0, err
+			}
+			value -= rhs
+		default:
+			return value, nil
+		}
+	}
+}
+
+// parseProduct 解析乘除法
+func (p *exprParser) parseProduct() (float64, error) {
+	value, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '*', 'x', 'X':
+			p.pos++
+			rhs, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			value *= rhs
+		case '/':
+			p.pos++
+			rhs, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			if rhs == 0 {
+				return 0, errInvalidExpression
+			}
+			value /= rhs
+		default:
+			return value, nil
+		}
+	}
+}
+
+// parseUnary 解析正负号
+func (p *exprParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-', '+':
+		sign := 1.0
+		if p.peek() == '-' {
+			sign = -1
+		}
+		p.pos++
+		defer func() { p.depth-- }()
+		if err := p.enter(); err != nil {
+			return 0, err
+		}
+		value, err := p.parseUnary()
+		return sign * value, err
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary 解析数字和括号
+func (p *exprParser) parsePrimary() (float64, error) {
+	if p.peek() == '(' {
+		p.pos++
+		defer func() { p.depth-- }()
+		if err := p.enter(); err != nil {
+			return 0, err
+		}
+		value, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, errInvalidExpression
+		}
+		p.pos++
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && isNumberRune(p.input[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, errInvalidExpression
+	}
+	return strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+}
+
+// isNumberRune 判断是否为数字或小数点
+func isNumberRune(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '.'
+}
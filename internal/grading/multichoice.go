@@ -0,0 +1,129 @@
+package grading
+
+import (
+	"math"
+	"strconv"
+
+	"testogo/internal/model/entity"
+)
+
+// gradeMultiChoice 多选题判分：答案按选项集合比较，与顺序、分隔符和大小写无关
+// 部分得分按判分配置中的计分规则计算
+func gradeMultiChoice(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	options := ParseOptionTexts(question.Options)
+	expected := ParseChoiceSet(question.Answer, options)
+	if len(expected) == 0 {
+		return gradeChoice(question, answer, config)
+	}
+	selected := ParseChoiceSet(answer, options)
+
+	expectedSet := toSet(expected)
+	selectedSet := toSet(selected)
+
+	result := Result{IsCorrect: true}
+	hits, wrongs := 0, 0
+	for _, key := range expected {
+		part := PartResult{ID: key}
+		if selectedSet[selectionKey(key)] {
+			part.Answer = key
+			part.IsCorrect = true
+			part.Score = 1
+			hits++
+		} else {
+			part.Feedback = "漏选"
+			result.IsCorrect = false
+		}
+		result.Parts = append(result.Parts, part)
+	}
+	for _, key := range selected {
+		if !expectedSet[selectionKey(key)] {
+			result.Parts = append(result.Parts, PartResult{ID: key, Answer: key, Feedback: "错选"})
+			result.IsCorrect = false
+			wrongs++
+		}
+	}
+
+	result.Score = choiceSetScore(config.ScoringPolicy, hits, wrongs, len(expected))
+	return result
+}
+
+// choiceSetScore 按计分规则计算多选题得分率
+func choiceSetScore(policy entity.ScoringPolicy, hits, wrongs, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	switch policy {
+	case entity.ScoringProportional:
+		if wrongs > 0 {
+			return 0
+		}
+		return float64(hits) / float64(total)
+	case entity.ScoringPenalty:
+		return math.Max(0, float64(hits-wrongs)/float64(total))
+	default:
+		if hits == total && wrongs == 0 {
+			return 1
+		}
+		return 0
+	}
+}
+
+// ParseChoiceSet 将多选题答案解析为选项字母集合（大写，去重）
+// 支持 "AC"、"A,C"、"A、C"、["A","C"] 以及直接填写选项内容，无法对应到选项的内容原样保留
+func ParseChoiceSet(answer string, options []string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, item := range ParseSelection(answer) {
+		if index := optionTextIndex(item, options); index >= 0 {
+			add(optionLetter(index))
+			continue
+		}
+		if letters, ok := splitOptionLetters(item, len(options)); ok {
+			for _, letter := range letters {
+				add(letter)
+			}
+			continue
+		}
+		add(selectionKey(item))
+	}
+	return keys
+}
+
+// optionTextIndex 查找与答案内容一致的选项下标，找不到返回-1
+func optionTextIndex(item string, options []string) int {
+	for i, option := range options {
+		if matchText(option, item) {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitOptionLetters 将 "AC"、"a" 这样的连写字母拆分为选项字母
+// 有选项时字母必须在选项范围内
+func splitOptionLetters(item string, optionCount int) ([]string, bool) {
+	var letters []string
+	for _, r := range item {
+		index := optionIndex(string(r))
+		if index < 0 || (optionCount > 0 && index >= optionCount) {
+			return nil, false
+		}
+		letters = append(letters, optionLetter(index))
+	}
+	return letters, len(letters) > 0
+}
+
+// optionLetter 选项下标转换为大写字母
+func optionLetter(index int) string {
+	if index >= 0 && index < 26 {
+		return string(rune('A' + index))
+	}
+	return strconv.Itoa(index)
+}
@@ -0,0 +1,88 @@
+package grading
+
+import (
+	"encoding/json"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// selectionSeparators 文本形式的选择集合可使用的分隔符
+var selectionSeparators = strings.NewReplacer("，", ",", "、", ",", "；", ",", ";", ",", " ", ",")
+
+// gradeCircleSelect 圈选题判分：答案为被圈选元素ID的无序集合
+// 得分 = 圈对的数量 /（应圈数量 + 多圈数量），漏圈和多圈都会扣分
+func gradeCircleSelect(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	expected := ParseSelection(question.Answer)
+	if len(expected) == 0 {
+		return gradeText(question, answer, config)
+	}
+	selected := ParseSelection(answer)
+
+	expectedSet := toSet(expected)
+	selectedSet := toSet(selected)
+
+	result := Result{IsCorrect: true}
+	hits, extras := 0, 0
+	for _, id := range expected {
+		part := PartResult{ID: id}
+		if selectedSet[selectionKey(id)] {
+			part.Answer = id
+			part.IsCorrect = true
+			part.Score = 1
+			hits++
+		} else {
+			part.Feedback = "漏圈"
+			result.IsCorrect = false
+		}
+		result.Parts = append(result.Parts, part)
+	}
+	for _, id := range selected {
+		if !expectedSet[selectionKey(id)] {
+			result.Parts = append(result.Parts, PartResult{ID: id, Answer: id, Feedback: "多圈"})
+			result.IsCorrect = false
+			extras++
+		}
+	}
+
+	result.Score = float64(hits) / float64(len(expected)+extras)
+	return result
+}
+
+// ParseSelection 解析选择集合，支持 JSON 数组和逗号、顿号、空格等分隔的文本，结果去重
+func ParseSelection(answer string) []string {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil
+	}
+
+	var items []string
+	if err := json.Unmarshal([]byte(answer), &items); err != nil {
+		items = strings.Split(selectionSeparators.Replace(answer), ",")
+	}
+
+	var selection []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[selectionKey(item)] {
+			continue
+		}
+		seen[selectionKey(item)] = true
+		selection = append(selection, item)
+	}
+	return selection
+}
+
+// selectionKey 集合元素比较时忽略大小写
+func selectionKey(item string) string {
+	return strings.ToLower(strings.TrimSpace(item))
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[selectionKey(item)] = true
+	}
+	return set
+}
@@ -0,0 +1,131 @@
+package grading
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// sequenceSeparators 文本形式的多个答案可使用的分隔符
+var sequenceSeparators = strings.NewReplacer("，", ",", "、", ",", "；", ",", ";", ",", " ", ",", "　", ",")
+
+// gradeReasoning 推理题判分：数字序列题按缺失的每一项分别判分，其余推理题按文本比较
+func gradeReasoning(question *entity.Question, answer string, config entity.GradingConfig) Result {
+	data, ok := ParseSequenceData(question.ElementData)
+	if !ok {
+		return gradeText(question, answer, config)
+	}
+
+	answers := parseSequenceAnswer(answer, data)
+	parts := make([]PartResult, 0, len(data.Missing))
+	for _, index := range data.Missing {
+		part := PartResult{ID: strconv.Itoa(index)}
+		value, answered := answers[index]
+		switch {
+		case !answered:
+			part.Feedback = "未作答"
+		case value == float64(data.Terms[index]):
+			part.Answer = formatTerm(value)
+			part.IsCorrect = true
+			part.Score = 1
+		default:
+			part.Answer = formatTerm(value)
+			part.Feedback = "答案错误"
+		}
+		parts = append(parts, part)
+	}
+	return combineParts(parts)
+}
+
+// ParseSequenceData 解析数字序列数据，缺失项的下标必须有效
+func ParseSequenceData(elementData string) (*entity.SequenceData, bool) {
+	if strings.TrimSpace(elementData) == "" {
+		return nil, false
+	}
+	var data entity.SequenceData
+	if err := json.Unmarshal([]byte(elementData), &data); err != nil || len(data.Terms) == 0 || len(data.Missing) == 0 {
+		return nil, false
+	}
+	for _, index := range data.Missing {
+		if index < 0 || index >= len(data.Terms) {
+			return nil, false
+		}
+	}
+	sort.Ints(data.Missing)
+	return &data, true
+}
+
+// SequenceAnswerText 生成序列题的标准答案文本，缺失项按位置顺序以逗号分隔
+func SequenceAnswerText(data *entity.SequenceData) string {
+	values := make([]string, len(data.Missing))
+	for i, index := range data.Missing {
+		values[i] = strconv.Itoa(data.Terms[index])
+	}
+	return strings.Join(values, ",")
+}
+
+// parseSequenceAnswer 将学生答案解析为 下标 -> 数值
+// 支持按下标提交的 JSON 对象 {"2": 6}、按缺失项顺序提交的数组或文本 "6, 12"，
+// 也支持直接写出整个数列
+func parseSequenceAnswer(answer string, data *entity.SequenceData) map[int]float64 {
+	answers := map[int]float64{}
+
+	var byIndex map[string]json.Number
+	if err := json.Unmarshal([]byte(answer), &byIndex); err == nil {
+		for key, value := range byIndex {
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			if number, ok := parseTerm(value.String()); ok {
+				answers[index] = number
+			}
+		}
+		return answers
+	}
+
+	var items []string
+	var numbers []json.Number
+	if err := json.Unmarshal([]byte(answer), &numbers); err == nil {
+		for _, number := range numbers {
+			items = append(items, number.String())
+		}
+	} else {
+		for _, item := range strings.Split(sequenceSeparators.Replace(answer), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	// 写出了整个数列时按位置取缺失项，否则按缺失项的顺序对应
+	positions := data.Missing
+	if len(items) == len(data.Terms) && len(items) != len(data.Missing) {
+		positions = make([]int, len(items))
+		for i := range items {
+			positions[i] = i
+		}
+	}
+	for i, item := range items {
+		if i >= len(positions) {
+			break
+		}
+		if number, ok := parseTerm(item); ok {
+			answers[positions[i]] = number
+		}
+	}
+	return answers
+}
+
+// parseTerm 解析单个数值，允许全角数字
+func parseTerm(text string) (float64, bool) {
+	value, err := strconv.ParseFloat(NormalizeMath(text), 64)
+	return value, err == nil
+}
+
+func formatTerm(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,140 @@
+package grading
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"testogo/internal/model/entity"
+)
+
+// 文本答案匹配规则，按顺序逐条放宽，命中时记录在判分结果中
+const (
+	RuleExact             = "exact"              // 去除前后空白后完全一致
+	RuleIgnoreCase        = "ignore_case"        // 忽略大小写
+	RuleFoldWidth         = "fold_width"         // 全角半角折叠
+	RuleIgnoreWhitespace  = "ignore_whitespace"  // 忽略所有空白
+	RuleIgnorePunctuation = "ignore_punctuation" // 忽略标点符号
+	RuleRegex             = "regex"              // 正则表达式
+)
+
+// textRule 一条文本规范化规则
+type textRule struct {
+	name      string
+	enabled   func(config entity.GradingConfig) bool
+	normalize func(s string) string
+}
+
+var textRules = []textRule{
+	{RuleIgnoreCase, func(c entity.GradingConfig) bool { return !c.CaseSensitive }, strings.ToLower},
+	{RuleFoldWidth, func(c entity.GradingConfig) bool { return c.FoldWidth }, foldWidth},
+	{RuleIgnoreWhitespace, func(c entity.GradingConfig) bool { return c.IgnoreWhitespace }, removeRunes(unicode.IsSpace)},
+	{RuleIgnorePunctuation, func(c entity.GradingConfig) bool { return c.IgnorePunctuation }, removeRunes(unicode.IsPunct)},
+}
+
+// Match 文本答案的匹配结果
+type Match struct {
+	Rule string // 命中的规则
+	Key  string // 命中的标准答案或正则表达式
+}
+
+// MatchAnswer 依次用各条规则将答案与全部可接受答案比较，返回第一个命中的规则
+// keys 为空字符串的项会被忽略；正则表达式在所有文本规则都未命中后才检查
+func MatchAnswer(config entity.GradingConfig, keys []string, answer string) (Match, bool) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return Match{}, false
+	}
+
+	var candidates []string
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			candidates = append(candidates, key)
+		}
+	}
+
+	normalized := append([]string(nil), candidates...)
+	user := answer
+	for i, key := range normalized {
+		if key == user {
+			return Match{Rule: RuleExact, Key: candidates[i]}, true
+		}
+	}
+	for _, rule := range textRules {
+		if !rule.enabled(config) {
+			continue
+		}
+		// 规则逐条累加：后面的规则在前面规则的基础上继续放宽
+		user = rule.normalize(user)
+		for i := range normalized {
+			normalized[i] = rule.normalize(normalized[i])
+			if normalized[i] == user {
+				return Match{Rule: rule.name, Key: candidates[i]}, true
+			}
+		}
+	}
+
+	for _, pattern := range config.Patterns {
+		re, err := compilePattern(pattern, config.CaseSensitive)
+		if err == nil && re.MatchString(answer) {
+			return Match{Rule: RuleRegex, Key: pattern}, true
+		}
+	}
+	return Match{}, false
+}
+
+// ValidateGradingConfig 校验判分配置：计分规则必须有效，正则表达式必须能编译
+func ValidateGradingConfig(config *entity.GradingConfig) error {
+	if config == nil {
+		return nil
+	}
+	if !entity.IsValidScoringPolicy(config.ScoringPolicy) {
+		return fmt.Errorf("无效的计分规则: %s", config.ScoringPolicy)
+	}
+	for _, pattern := range config.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("无效的正则表达式 %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// compilePattern 编译需完整匹配的正则表达式，不区分大小写时加上 (?i)
+func compilePattern(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	flags := ""
+	if !caseSensitive {
+		flags = "(?i)"
+	}
+	return regexp.Compile(flags + `^(?:` + pattern + `)$`)
+}
+
+// acceptedKeys 题目的全部可接受答案：标准答案在前，其余答案按配置顺序
+func acceptedKeys(answer string, config entity.GradingConfig) []string {
+	return append([]string{answer}, config.AcceptedAnswers...)
+}
+
+// foldWidth 全角字符转半角，全角空格转普通空格
+func foldWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= 0xFF01 && r <= 0xFF5E:
+			return r - 0xFEE0
+		}
+		return r
+	}, s)
+}
+
+// removeRunes 生成去除指定字符的规范化函数
+func removeRunes(drop func(rune) bool) func(string) string {
+	return func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if drop(r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+}
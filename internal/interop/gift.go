@@ -0,0 +1,357 @@
+package interop
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// giftSpecialChars GIFT格式中需要用反斜杠转义的字符
+const giftSpecialChars = `~=#{}:\`
+
+// ParseGIFT 解析GIFT格式的题目文本，题目之间用空行分隔
+// GIFT格式无法携带图片，题干中的 <img> 只保留外部地址
+func ParseGIFT(data []byte) ([]Item, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var items []Item
+	var block []string
+	flush := func() {
+		if len(block) > 0 {
+			items = append(items, parseGIFTQuestion(strings.Join(block, "\n"), len(items)+1))
+			block = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"), strings.HasPrefix(trimmed, "$CATEGORY:"):
+		default:
+			block = append(block, line)
+		}
+	}
+	flush()
+	return items, nil
+}
+
+// parseGIFTQuestion 解析一道GIFT题目，index 为题目序号，题目没有名称时用于报告
+func parseGIFTQuestion(text string, index int) Item {
+	item := Item{Identifier: fmt.Sprintf("第%d题", index)}
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "::") {
+		if end := giftIndex(text[2:], "::"); end >= 0 {
+			item.Identifier = giftUnescape(strings.TrimSpace(text[2 : 2+end]))
+			text = strings.TrimSpace(text[2+end+2:])
+		}
+	}
+	format := "moodle"
+	if strings.HasPrefix(text, "[") {
+		if end := strings.Index(text, "]"); end > 0 {
+			format = strings.ToLower(text[1:end])
+			text = strings.TrimSpace(text[end+1:])
+		}
+	}
+
+	open := giftIndex(text, "{")
+	if open < 0 {
+		item.Title = giftStem(text, format)
+		item.Error = "题目没有答案（说明文字）"
+		return item
+	}
+	close := giftIndex(text[open:], "}")
+	if close < 0 {
+		item.Title = giftStem(text, format)
+		item.Error = "答案缺少结尾的 }"
+		return item
+	}
+	close += open
+	// 答案在题干中间时，原位置作为填空
+	before, answers := text[:open], strings.TrimSpace(text[open+1:close])
+	if after := text[close+1:]; strings.TrimSpace(after) != "" {
+		before += qtiBlankText + after
+	}
+	var images []string
+	item.Title = giftStem(before, format)
+	if format == "html" {
+		item.Title, images = htmlText(item.Title)
+		for _, src := range images {
+			if isExternalURL(src) {
+				item.MediaURLs = append(item.MediaURLs, src)
+			}
+		}
+	}
+	if item.Title == "" {
+		item.Title = item.Identifier
+	}
+
+	// 总体反馈作为解析
+	if i := giftIndex(answers, "####"); i >= 0 {
+		item.Explanation = giftStem(answers[i+4:], format)
+		answers = strings.TrimSpace(answers[:i])
+	}
+	parseGIFTAnswers(&item, answers)
+	return item
+}
+
+// parseGIFTAnswers 解析花括号中的答案
+func parseGIFTAnswers(item *Item, answers string) {
+	switch {
+	case answers == "":
+		item.Error = "不支持的题型: 问答题"
+		return
+	case giftIndex(answers, "->") >= 0:
+		item.Error = "不支持的题型: 匹配题"
+		return
+	}
+
+	if value := strings.ToUpper(giftCut(answers, "#")); value == "T" || value == "TRUE" || value == "F" || value == "FALSE" {
+		item.Type = entity.TypeJudge
+		item.Answer = judgeFalse
+		if strings.HasPrefix(value, "T") {
+			item.Answer = judgeTrue
+		}
+		return
+	}
+
+	// 数值题：{#3} {#3:0.5} {#=3 =%50%2}
+	if strings.HasPrefix(answers, "#") {
+		answers = strings.TrimSpace(answers[1:])
+		if !strings.HasPrefix(answers, "=") {
+			value := giftCut(answers, ":")
+			if strings.Contains(value, "..") {
+				item.Error = "暂不支持范围形式的数值答案"
+				return
+			}
+			item.Type, item.Answer = entity.TypeFillIn, value
+			return
+		}
+	}
+
+	type choice struct {
+		text    string
+		correct bool
+		full    bool // 满分答案
+		wrong   bool // 以 ~ 开头
+	}
+	var choices []choice
+	for _, token := range giftTokens(answers) {
+		c := choice{wrong: token[0] == '~', correct: token[0] == '=', full: token[0] == '='}
+		body := strings.TrimSpace(token[1:])
+		if strings.HasPrefix(body, "%") {
+			if end := strings.Index(body[1:], "%"); end >= 0 {
+				weight, _ := strconv.ParseFloat(body[1:1+end], 64)
+				c.correct, c.full = weight > 0, weight >= 100
+				body = strings.TrimSpace(body[end+2:])
+			}
+		}
+		c.text = giftUnescape(giftCut(body, "#"))
+		if c.text == "" {
+			item.Error = "答案中有空选项"
+			return
+		}
+		choices = append(choices, c)
+	}
+	if len(choices) == 0 {
+		item.Error = "无法识别答案"
+		return
+	}
+
+	hasWrong := false
+	for _, c := range choices {
+		hasWrong = hasWrong || c.wrong
+	}
+	if !hasWrong {
+		// 只有 = 开头的答案是简答题，全部满分答案都可接受
+		var accepted []string
+		for _, c := range choices {
+			if c.full {
+				accepted = append(accepted, c.text)
+			}
+		}
+		accepted = uniqueStrings(accepted)
+		if len(accepted) == 0 {
+			item.Error = "缺少正确答案"
+			return
+		}
+		item.Type = entity.TypeFillIn
+		item.Answer, item.AcceptedAnswers = accepted[0], accepted[1:]
+		return
+	}
+
+	var letters []string
+	for i, c := range choices {
+		item.Options = append(item.Options, c.text)
+		if c.correct {
+			letters = append(letters, letter(i))
+		}
+	}
+	switch {
+	case len(choices) < 2:
+		item.Error = "选择题至少需要两个选项"
+	case len(letters) == 0:
+		item.Error = "缺少正确答案"
+	case len(letters) == 1:
+		item.Type, item.Answer = entity.TypeChoice, letters[0]
+	default:
+		item.Type, item.Answer = entity.TypeMultiChoice, strings.Join(letters, "")
+	}
+}
+
+// giftTokens 按未转义的 = 和 ~ 拆分答案，每项以 = 或 ~ 开头
+func giftTokens(answers string) []string {
+	var tokens []string
+	start := -1
+	for i := 0; i < len(answers); i++ {
+		switch answers[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			if start >= 0 {
+				tokens = append(tokens, answers[start:i])
+			}
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, answers[start:])
+	}
+	return tokens
+}
+
+// giftIndex 查找第一个未转义的 sep 的位置
+func giftIndex(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// giftCut 返回第一个未转义的 sep 之前的内容
+func giftCut(s, sep string) string {
+	if i := giftIndex(s, sep); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// giftStem 题干或反馈文字：合并行、去掉转义
+func giftStem(s, format string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	s = strings.Join(lines, " ")
+	if format != "html" {
+		s = strings.ReplaceAll(s, `\n`, "\n")
+	}
+	return giftUnescape(s)
+}
+
+func giftUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(giftSpecialChars, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r < 128 && strings.ContainsRune(giftSpecialChars, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// GIFTWriter 将题目写为GIFT文本，GIFT格式无法携带图片，图片地址写在注释中
+type GIFTWriter struct {
+	w       io.Writer
+	skipped []Skipped
+}
+
+// NewGIFTWriter 创建GIFT写入器
+func NewGIFTWriter(w io.Writer) *GIFTWriter {
+	return &GIFTWriter{w: w}
+}
+
+// Write 写入一道题
+func (w *GIFTWriter) Write(question *entity.Question, media []Media) error {
+	item, err := exportItem(question)
+	if err != nil {
+		skipped := Skipped{QuestionID: question.ID, Reason: err.Error()}
+		w.skipped = append(w.skipped, skipped)
+		_, err := fmt.Fprintf(w.w, "// %s\n\n", skippedNote(skipped))
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// question: %d\n", question.ID)
+	for _, m := range media {
+		fmt.Fprintf(&b, "// 图片未导出: %s\n", m.URL)
+	}
+	fmt.Fprintf(&b, "::%s::%s{\n", item.Identifier, giftEscape(item.Title))
+	switch item.Type {
+	case entity.TypeChoice, entity.TypeMultiChoice:
+		for i, option := range item.Options {
+			correct := strings.Contains(item.Answer, letter(i))
+			switch {
+			case item.Type == entity.TypeChoice && correct:
+				fmt.Fprintf(&b, "\t=%s\n", giftEscape(option))
+			case item.Type == entity.TypeMultiChoice && correct:
+				fmt.Fprintf(&b, "\t~%%%s%%%s\n", formatFraction(100/float64(len(item.Answer))), giftEscape(option))
+			default:
+				fmt.Fprintf(&b, "\t~%s\n", giftEscape(option))
+			}
+		}
+	case entity.TypeJudge:
+		if item.Answer == judgeTrue {
+			b.WriteString("\tTRUE\n")
+		} else {
+			b.WriteString("\tFALSE\n")
+		}
+	case entity.TypeFillIn:
+		for _, answer := range append([]string{item.Answer}, item.AcceptedAnswers...) {
+			fmt.Fprintf(&b, "\t=%s\n", giftEscape(answer))
+		}
+	}
+	if item.Explanation != "" {
+		fmt.Fprintf(&b, "\t####%s\n", giftEscape(item.Explanation))
+	}
+	b.WriteString("}\n\n")
+	_, err = io.WriteString(w.w, b.String())
+	return err
+}
+
+// Close GIFT文件没有结尾
+func (w *GIFTWriter) Close() error {
+	return nil
+}
+
+// Skipped 跳过的题目
+func (w *GIFTWriter) Skipped() []Skipped {
+	return w.skipped
+}
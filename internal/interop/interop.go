@@ -0,0 +1,185 @@
+// Package interop 实现题库与其他平台交换题目的格式：IMS QTI 2.1、Moodle XML 和 GIFT
+// 导入时把各格式的题目转换为 Item，导出时把题目写成对应格式；
+// 支持的题型为单选、多选、判断和填空，无法转换的题目单独报告，不影响同一文件中的其他题目
+package interop
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+)
+
+// Item 交换格式中的一道题
+type Item struct {
+	Identifier      string // 原文件中的题目标识或名称，用于报告
+	Type            entity.QuestionType
+	Title           string
+	Options         []string
+	Answer          string   // 选择题为选项字母，判断题为 对/错
+	AcceptedAnswers []string // 填空题的其他可接受答案
+	Explanation     string
+	MediaURLs       []string
+	Error           string // 无法转换的原因，不为空时其余字段可能不完整
+}
+
+// Media 题目引用的图片，Data 为空时只能在导出文件中引用原地址
+type Media struct {
+	Name string
+	URL  string
+	Data []byte
+}
+
+// MediaSaver 保存导入文件中的图片，返回访问地址
+type MediaSaver func(name string, data []byte) (string, error)
+
+// Skipped 导出时跳过的题目
+type Skipped struct {
+	QuestionID uint
+	Reason     string
+}
+
+// Writer 逐题写入交换格式，Close 时补全文件结尾
+// 无法转换的题目不会导致写入失败，而是记录在 Skipped 中，并在文件中留下注释
+type Writer interface {
+	Write(question *entity.Question, media []Media) error
+	Close() error
+	Skipped() []Skipped
+}
+
+// ParseXML 根据根元素识别Moodle XML题目文件或单个QTI题目文件
+func ParseXML(data []byte, save MediaSaver) ([]Item, error) {
+	root, err := parseNode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("XML格式错误: %v", err)
+	}
+	switch root.Name {
+	case "quiz":
+		return ParseMoodleXML(data, save)
+	case "assessmentItem":
+		item, err := ParseQTIItem(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Item{item}, nil
+	case "manifest":
+		return nil, fmt.Errorf("QTI内容包请将清单和题目文件一起打包为zip上传")
+	default:
+		return nil, fmt.Errorf("无法识别的XML格式: %s", root.Name)
+	}
+}
+
+// unsupported 生成不支持的题目
+func unsupported(identifier, format string, args ...interface{}) Item {
+	return Item{Identifier: identifier, Error: fmt.Sprintf(format, args...)}
+}
+
+// exportItem 将题目转换为导出用的 Item
+// 计算、比较、推理题以文字作答，按填空题导出；图片题和圈选题依赖题目元素，无法导出
+func exportItem(question *entity.Question) (Item, error) {
+	item := Item{
+		Identifier:  fmt.Sprintf("q%d", question.ID),
+		Title:       question.Title,
+		Answer:      strings.TrimSpace(question.Answer),
+		Explanation: question.Explanation,
+	}
+	if question.GradingConfig != "" {
+		item.AcceptedAnswers = grading.ParseGradingConfig(question.GradingConfig).AcceptedAnswers
+	}
+
+	switch question.Type {
+	case entity.TypeChoice, entity.TypeMultiChoice:
+		item.Type = question.Type
+		item.Options = grading.ParseOptionTexts(question.Options)
+		letters := grading.ParseChoiceSet(question.Answer, item.Options)
+		for _, letter := range letters {
+			if index := letterIndex(letter); index < 0 || index >= len(item.Options) {
+				return item, fmt.Errorf("答案 %s 不在选项范围内", letter)
+			}
+		}
+		if len(item.Options) < 2 || len(letters) == 0 {
+			return item, fmt.Errorf("选择题缺少选项或答案")
+		}
+		item.Answer = strings.Join(letters, "")
+	case entity.TypeJudge:
+		item.Type = entity.TypeJudge
+		switch grading.NormalizeJudgeAnswer(question.Answer) {
+		case "true":
+			item.Answer = judgeTrue
+		case "false":
+			item.Answer = judgeFalse
+		default:
+			return item, fmt.Errorf("判断题答案无效: %s", question.Answer)
+		}
+	case entity.TypeFillIn, entity.TypeMath, entity.TypeComparison, entity.TypeReasoning:
+		item.Type = entity.TypeFillIn
+		if item.Answer == "" {
+			return item, fmt.Errorf("题目没有答案")
+		}
+	default:
+		return item, fmt.Errorf("不支持导出的题型: %s", question.Type)
+	}
+	return item, nil
+}
+
+// 判断题答案
+const (
+	judgeTrue  = "对"
+	judgeFalse = "错"
+)
+
+// judgeAnswer 将 true/false、对/错 等判断题答案规范为 对/错，无法识别时返回空
+func judgeAnswer(text string) string {
+	switch grading.NormalizeJudgeAnswer(text) {
+	case "true":
+		return judgeTrue
+	case "false":
+		return judgeFalse
+	}
+	return ""
+}
+
+// letter 选项下标对应的字母
+func letter(index int) string {
+	return string(rune('A' + index))
+}
+
+// letterIndex 选项字母对应的下标，不是字母时返回-1
+func letterIndex(s string) int {
+	if len(s) != 1 || s[0] < 'A' || s[0] > 'Z' {
+		return -1
+	}
+	return int(s[0] - 'A')
+}
+
+var (
+	imgSrcPattern    = regexp.MustCompile(`(?i)<img[^>]*?\ssrc\s*=\s*["']([^"']+)["'][^>]*>`)
+	lineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(?:p|div|li|h[1-6]|tr)>`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// htmlText 将HTML片段转换为纯文本，并返回其中图片的地址
+func htmlText(fragment string) (string, []string) {
+	var images []string
+	for _, match := range imgSrcPattern.FindAllStringSubmatch(fragment, -1) {
+		images = append(images, html.UnescapeString(match[1]))
+	}
+	text := lineBreakPattern.ReplaceAllString(fragment, "\n")
+	text = html.UnescapeString(tagPattern.ReplaceAllString(text, ""))
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	lines := strings.Split(blankLinePattern.ReplaceAllString(text, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), images
+}
+
+// textHTML 将纯文本转换为HTML，保留换行
+func textHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br/>")
+}
@@ -0,0 +1,277 @@
+package interop
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// moodlePluginFile Moodle XML中嵌入文件的地址前缀
+const moodlePluginFile = "@@PLUGINFILE@@/"
+
+// ParseMoodleXML 解析Moodle XML题目文件，题目中嵌入的图片通过 save 保存
+// 题目分类（category）不是题目，直接忽略
+func ParseMoodleXML(data []byte, save MediaSaver) ([]Item, error) {
+	root, err := parseNode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Moodle XML格式错误: %v", err)
+	}
+	if root.Name != "quiz" {
+		return nil, fmt.Errorf("不是Moodle XML文件: %s", root.Name)
+	}
+
+	var items []Item
+	for i, question := range root.children("question") {
+		qType := question.Attrs["type"]
+		if qType == "category" {
+			continue
+		}
+		identifier := question.child("name").child("text").text()
+		if identifier == "" {
+			identifier = fmt.Sprintf("第%d题", i+1)
+		}
+		items = append(items, parseMoodleQuestion(question, qType, identifier, save))
+	}
+	return items, nil
+}
+
+// parseMoodleQuestion 将一道Moodle题目转换为 Item
+func parseMoodleQuestion(question *node, qType, identifier string, save MediaSaver) Item {
+	item := Item{Identifier: identifier}
+
+	questionText := question.child("questiontext")
+	embedded := saveMoodleFiles(questionText, save)
+	var images []string
+	item.Title, images = htmlText(questionText.child("text").text())
+	if item.Title == "" {
+		item.Title = identifier
+	}
+	for _, src := range images {
+		if strings.HasPrefix(src, moodlePluginFile) {
+			name, _ := url.PathUnescape(strings.TrimPrefix(src, moodlePluginFile))
+			src = embedded[path.Base(name)]
+		} else if !isExternalURL(src) {
+			src = ""
+		}
+		if src != "" {
+			item.MediaURLs = append(item.MediaURLs, src)
+		}
+	}
+	item.Explanation, _ = htmlText(question.child("generalfeedback").child("text").text())
+
+	type answer struct {
+		text     string
+		fraction float64
+	}
+	var answers []answer
+	for _, a := range question.children("answer") {
+		fraction, _ := strconv.ParseFloat(a.Attrs["fraction"], 64)
+		text, _ := htmlText(a.child("text").text())
+		answers = append(answers, answer{text: text, fraction: fraction})
+	}
+
+	switch qType {
+	case "multichoice":
+		single := question.child("single").text() != "false"
+		best := 0.0
+		for _, a := range answers {
+			if a.fraction > best {
+				best = a.fraction
+			}
+		}
+		var letters []string
+		for i, a := range answers {
+			if a.text == "" {
+				item.Error = fmt.Sprintf("选项%s没有文字", letter(i))
+				return item
+			}
+			item.Options = append(item.Options, a.text)
+			if a.fraction > 0 && (!single || a.fraction == best) {
+				letters = append(letters, letter(i))
+			}
+		}
+		switch {
+		case len(item.Options) < 2:
+			item.Error = "选择题至少需要两个选项"
+		case len(letters) == 0:
+			item.Error = "缺少正确答案"
+		case single:
+			item.Type, item.Answer = entity.TypeChoice, letters[0]
+		default:
+			item.Type, item.Answer = entity.TypeMultiChoice, strings.Join(letters, "")
+		}
+	case "truefalse":
+		for _, a := range answers {
+			if a.fraction >= 100 {
+				item.Answer = judgeAnswer(a.text)
+			}
+		}
+		if item.Answer == "" {
+			item.Error = "缺少正确答案"
+			break
+		}
+		item.Type = entity.TypeJudge
+	case "shortanswer", "numerical":
+		var accepted []string
+		for _, a := range answers {
+			if a.fraction >= 100 && a.text != "*" {
+				accepted = append(accepted, a.text)
+			}
+		}
+		accepted = uniqueStrings(accepted)
+		if len(accepted) == 0 {
+			item.Error = "缺少正确答案"
+			break
+		}
+		item.Type = entity.TypeFillIn
+		item.Answer, item.AcceptedAnswers = accepted[0], accepted[1:]
+	default:
+		item.Error = fmt.Sprintf("不支持的题型: %s", qType)
+	}
+	return item
+}
+
+// saveMoodleFiles 保存题干中以base64嵌入的文件，返回文件名到访问地址的映射
+func saveMoodleFiles(n *node, save MediaSaver) map[string]string {
+	saved := make(map[string]string)
+	if save == nil {
+		return saved
+	}
+	for _, file := range n.children("file") {
+		if file.Attrs["encoding"] != "base64" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(file.text()), ""))
+		if err != nil {
+			continue
+		}
+		name := path.Base(file.Attrs["name"])
+		if url, err := save(name, data); err == nil {
+			saved[name] = url
+		}
+	}
+	return saved
+}
+
+// MoodleWriter 将题目写为Moodle XML，图片以base64嵌入题干
+type MoodleWriter struct {
+	w       io.Writer
+	started bool
+	skipped []Skipped
+}
+
+// NewMoodleWriter 创建Moodle XML写入器
+func NewMoodleWriter(w io.Writer) *MoodleWriter {
+	return &MoodleWriter{w: w}
+}
+
+// Write 写入一道题
+func (w *MoodleWriter) Write(question *entity.Question, media []Media) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	item, err := exportItem(question)
+	if err != nil {
+		skipped := Skipped{QuestionID: question.ID, Reason: err.Error()}
+		w.skipped = append(w.skipped, skipped)
+		_, err := fmt.Fprintf(w.w, "  <!-- %s -->\n", xmlComment(skippedNote(skipped)))
+		return err
+	}
+
+	qType := map[entity.QuestionType]string{
+		entity.TypeChoice:      "multichoice",
+		entity.TypeMultiChoice: "multichoice",
+		entity.TypeJudge:       "truefalse",
+		entity.TypeFillIn:      "shortanswer",
+	}[item.Type]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  <question type=\"%s\">\n", qType)
+	fmt.Fprintf(&b, "    <name><text>%s</text></name>\n", xmlEscape(summary(item.Title, 40)))
+
+	questionHTML := textHTML(item.Title)
+	var files strings.Builder
+	for _, m := range media {
+		if m.Data == nil {
+			questionHTML += fmt.Sprintf(`<br/><img src="%s" alt=""/>`, html.EscapeString(m.URL))
+			continue
+		}
+		questionHTML += fmt.Sprintf(`<br/><img src="%s%s" alt=""/>`, moodlePluginFile, url.PathEscape(m.Name))
+		fmt.Fprintf(&files, "      <file name=\"%s\" path=\"/\" encoding=\"base64\">%s</file>\n",
+			xmlEscape(m.Name), base64.StdEncoding.EncodeToString(m.Data))
+	}
+	fmt.Fprintf(&b, "    <questiontext format=\"html\">\n      <text>%s</text>\n%s    </questiontext>\n", xmlEscape(questionHTML), files.String())
+	fmt.Fprintf(&b, "    <generalfeedback format=\"html\"><text>%s</text></generalfeedback>\n", xmlEscape(textHTML(item.Explanation)))
+	b.WriteString("    <defaultgrade>1</defaultgrade>\n")
+	fmt.Fprintf(&b, "    <idnumber>%s</idnumber>\n", item.Identifier)
+
+	writeAnswer := func(fraction float64, text string) {
+		fmt.Fprintf(&b, "    <answer fraction=\"%s\" format=\"html\"><text>%s</text></answer>\n", formatFraction(fraction), xmlEscape(textHTML(text)))
+	}
+	switch item.Type {
+	case entity.TypeChoice, entity.TypeMultiChoice:
+		fmt.Fprintf(&b, "    <single>%t</single>\n", item.Type == entity.TypeChoice)
+		b.WriteString("    <shuffleanswers>false</shuffleanswers>\n    <answernumbering>ABCD</answernumbering>\n")
+		for i, option := range item.Options {
+			fraction := 0.0
+			if strings.Contains(item.Answer, letter(i)) {
+				fraction = 100 / float64(len(item.Answer))
+			}
+			writeAnswer(fraction, option)
+		}
+	case entity.TypeJudge:
+		trueFraction, falseFraction := 100.0, 0.0
+		if item.Answer == judgeFalse {
+			trueFraction, falseFraction = 0, 100
+		}
+		writeAnswer(trueFraction, "true")
+		writeAnswer(falseFraction, "false")
+	case entity.TypeFillIn:
+		b.WriteString("    <usecase>0</usecase>\n")
+		for _, answer := range append([]string{item.Answer}, item.AcceptedAnswers...) {
+			writeAnswer(100, answer)
+		}
+	}
+	b.WriteString("  </question>\n")
+	_, err = io.WriteString(w.w, b.String())
+	return err
+}
+
+// Close 写入文件结尾
+func (w *MoodleWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "</quiz>\n")
+	return err
+}
+
+// Skipped 跳过的题目
+func (w *MoodleWriter) Skipped() []Skipped {
+	return w.skipped
+}
+
+func (w *MoodleWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	_, err := io.WriteString(w.w, xml.Header+"<quiz>\n")
+	return err
+}
+
+// formatFraction 格式化得分比例，保留5位小数（Moodle可识别的精度）
+func formatFraction(fraction float64) string {
+	s := strconv.FormatFloat(fraction, 'f', 5, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s
+}
@@ -0,0 +1,108 @@
+package interop
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// node 简单的XML文档树，保留文字与元素的先后顺序，用于解析QTI中的混合内容
+// 元素名和属性名都忽略命名空间
+type node struct {
+	Name     string // 文字节点为空
+	Attrs    map[string]string
+	Text     string // 仅文字节点
+	Children []*node
+}
+
+// parseNode 解析XML文档，返回根元素
+func parseNode(r io.Reader) (*node, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	root := &node{}
+	stack := []*node{root}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			child := &node{Name: t.Name.Local, Attrs: make(map[string]string, len(t.Attr))}
+			for _, attr := range t.Attr {
+				child.Attrs[attr.Name.Local] = attr.Value
+			}
+			parent.Children = append(parent.Children, child)
+			stack = append(stack, child)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.Children = append(parent.Children, &node{Text: string(t)})
+		}
+	}
+	for _, child := range root.Children {
+		if child.Name != "" {
+			return child, nil
+		}
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// child 第一个名为 name 的子元素，不存在时返回空元素
+func (n *node) child(name string) *node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return &node{}
+}
+
+// children 全部名为 name 的子元素
+func (n *node) children(name string) []*node {
+	var result []*node
+	for _, c := range n.Children {
+		if c.Name == name {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// descendants 按文档顺序查找全部名为 name 的后代元素
+func (n *node) descendants(name string) []*node {
+	var result []*node
+	for _, c := range n.Children {
+		if c.Name == name {
+			result = append(result, c)
+		}
+		result = append(result, c.descendants(name)...)
+	}
+	return result
+}
+
+// text 元素内全部文字，去掉首尾空白
+func (n *node) text() string {
+	var b strings.Builder
+	n.writeText(&b)
+	return strings.TrimSpace(b.String())
+}
+
+func (n *node) writeText(b *strings.Builder) {
+	if n.Name == "" {
+		b.WriteString(n.Text)
+		return
+	}
+	for _, c := range n.Children {
+		c.writeText(b)
+	}
+}
@@ -0,0 +1,566 @@
+package interop
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+const (
+	qtiNamespace       = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiManifestName    = "imsmanifest.xml"
+	qtiItemType        = "imsqti_item_xmlv2p1"
+	qtiMatchCorrect    = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	qtiMapResponse     = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"
+	qtiResponseID      = "RESPONSE"
+	qtiBlankMarkup     = `<textEntryInteraction responseIdentifier="RESPONSE" expectedLength="15"/>`
+	qtiBlankText       = "____"
+	qtiExplanationID   = "EXPLANATION"
+	qtiFeedbackOutcome = "FEEDBACK"
+
+	// qtiMaxFileSize 内容包中单个文件解压后的最大大小，与上传文件的大小限制一致
+	qtiMaxFileSize = 10 << 20
+)
+
+// qtiBlockElements 转换为纯文本时需要换行的元素
+var qtiBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"table": true, "tr": true, "blockquote": true, "pre": true,
+}
+
+// qtiSkippedElements 题干中不属于题目内容的元素
+var qtiSkippedElements = map[string]bool{
+	"feedbackInline": true, "feedbackBlock": true, "rubricBlock": true, "templateBlock": true, "templateInline": true,
+}
+
+// qtiBlankPattern 导出填空题时替换为填空交互的位置
+var qtiBlankPattern = regexp.MustCompile(`（\s*）|\(\s*\)|_{2,}|＿{2,}`)
+
+// ParseQTIPackage 解析QTI 2.1内容包（zip），按清单中的顺序读取全部题目，包内的图片通过 save 保存
+// 没有清单时读取包内全部题目文件
+func ParseQTIPackage(r *zip.Reader, save MediaSaver) ([]Item, error) {
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	hrefs, err := qtiManifestItems(files)
+	if err != nil {
+		return nil, err
+	}
+	fromManifest := len(hrefs) > 0
+	if !fromManifest {
+		for name := range files {
+			if strings.HasSuffix(strings.ToLower(name), ".xml") && name != qtiManifestName {
+				hrefs = append(hrefs, name)
+			}
+		}
+		sort.Strings(hrefs)
+	}
+
+	var items []Item
+	for _, href := range hrefs {
+		f := files[href]
+		if f == nil {
+			items = append(items, unsupported(href, "内容包中缺少题目文件"))
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			items = append(items, unsupported(href, "读取题目文件失败: %v", err))
+			continue
+		}
+		root, err := parseNode(bytes.NewReader(data))
+		if err != nil {
+			items = append(items, unsupported(href, "题目文件格式错误: %v", err))
+			continue
+		}
+		// 没有清单时跳过包内其他XML文件（如试卷结构、元数据）
+		if !fromManifest && root.Name != "assessmentItem" {
+			continue
+		}
+
+		dir := path.Dir(href)
+		resolve := func(src string) string {
+			if isExternalURL(src) {
+				return src
+			}
+			f := files[path.Join(dir, src)]
+			if f == nil || save == nil {
+				return ""
+			}
+			data, err := readZipFile(f)
+			if err != nil {
+				return ""
+			}
+			url, err := save(path.Base(f.Name), data)
+			if err != nil {
+				return ""
+			}
+			return url
+		}
+		item := parseQTIItem(root, resolve)
+		if item.Identifier == "" {
+			item.Identifier = href
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ParseQTIItem 解析单个QTI 2.1题目文件，文件中只能引用外部图片地址
+func ParseQTIItem(data []byte) (Item, error) {
+	root, err := parseNode(bytes.NewReader(data))
+	if err != nil {
+		return Item{}, fmt.Errorf("QTI文件格式错误: %v", err)
+	}
+	return parseQTIItem(root, func(src string) string {
+		if isExternalURL(src) {
+			return src
+		}
+		return ""
+	}), nil
+}
+
+// qtiManifestItems 读取清单中题目文件的路径
+func qtiManifestItems(files map[string]*zip.File) ([]string, error) {
+	f := files[qtiManifestName]
+	if f == nil {
+		return nil, nil
+	}
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseNode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("内容包清单格式错误: %v", err)
+	}
+
+	var hrefs []string
+	for _, resource := range root.descendants("resource") {
+		if strings.HasPrefix(resource.Attrs["type"], "imsqti_item") && resource.Attrs["href"] != "" {
+			hrefs = append(hrefs, path.Clean(resource.Attrs["href"]))
+		}
+	}
+	return hrefs, nil
+}
+
+// qtiResponse 作答变量的声明
+type qtiResponse struct {
+	cardinality string
+	correct     []string
+	mapped      []string // 映射中得分为正的答案
+}
+
+// qtiBody 遍历题干时收集的内容
+type qtiBody struct {
+	resolve      func(src string) string
+	text         strings.Builder
+	images       []string
+	interactions []*node
+	unsupported  []string
+}
+
+func (b *qtiBody) walk(n *node) {
+	for _, c := range n.Children {
+		switch {
+		case c.Name == "":
+			b.text.WriteString(c.Text)
+		case c.Name == "img":
+			if url := b.resolve(c.Attrs["src"]); url != "" {
+				b.images = append(b.images, url)
+			}
+		case c.Name == "object" && strings.HasPrefix(c.Attrs["type"], "image/"):
+			if url := b.resolve(c.Attrs["data"]); url != "" {
+				b.images = append(b.images, url)
+			}
+		case c.Name == "choiceInteraction":
+			b.interactions = append(b.interactions, c)
+			b.text.WriteString("\n")
+			b.walk(c.child("prompt"))
+			b.text.WriteString("\n")
+		case c.Name == "textEntryInteraction":
+			b.interactions = append(b.interactions, c)
+			b.text.WriteString(qtiBlankText)
+		case strings.HasSuffix(c.Name, "Interaction"):
+			b.unsupported = append(b.unsupported, c.Name)
+		case qtiSkippedElements[c.Name]:
+		default:
+			if qtiBlockElements[c.Name] {
+				b.text.WriteString("\n")
+			}
+			b.walk(c)
+			if qtiBlockElements[c.Name] {
+				b.text.WriteString("\n")
+			}
+		}
+	}
+}
+
+// content 题干的纯文本，每行合并多余空白，去掉空行
+func (b *qtiBody) content() string {
+	var lines []string
+	for _, line := range strings.Split(b.text.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseQTIItem 将 assessmentItem 转换为 Item，resolve 将图片地址转换为题库中的访问地址，失败时返回空
+func parseQTIItem(root *node, resolve func(src string) string) Item {
+	identifier := root.Attrs["identifier"]
+	switch root.Name {
+	case "assessmentItem":
+	case "questestinterop":
+		return unsupported(identifier, "不支持QTI 1.2格式，请导出为QTI 2.1")
+	default:
+		return unsupported(identifier, "不是QTI 2.1题目: %s", root.Name)
+	}
+
+	responses := make(map[string]*qtiResponse)
+	for _, decl := range root.children("responseDeclaration") {
+		response := &qtiResponse{cardinality: decl.Attrs["cardinality"]}
+		for _, value := range decl.child("correctResponse").children("value") {
+			response.correct = append(response.correct, value.text())
+		}
+		for _, entry := range decl.child("mapping").children("mapEntry") {
+			if value := entry.Attrs["mappedValue"]; value != "" && value != "0" && !strings.HasPrefix(value, "-") {
+				response.mapped = append(response.mapped, entry.Attrs["mapKey"])
+			}
+		}
+		responses[decl.Attrs["identifier"]] = response
+	}
+
+	body := &qtiBody{resolve: resolve}
+	body.walk(root.child("itemBody"))
+	item := Item{
+		Identifier: identifier,
+		Title:      body.content(),
+		MediaURLs:  body.images,
+	}
+	var feedback []string
+	for _, modal := range root.children("modalFeedback") {
+		if text := modal.text(); text != "" {
+			feedback = append(feedback, text)
+		}
+	}
+	item.Explanation = strings.Join(feedback, "\n")
+
+	switch {
+	case len(body.unsupported) > 0:
+		item.Error = fmt.Sprintf("不支持的题型: %s", strings.Join(body.unsupported, ", "))
+		return item
+	case len(body.interactions) == 0:
+		item.Error = "题目中没有作答交互"
+		return item
+	case len(body.interactions) > 1:
+		item.Error = "暂不支持包含多个作答交互的题目"
+		return item
+	}
+
+	interaction := body.interactions[0]
+	response := responses[interaction.Attrs["responseIdentifier"]]
+	if response == nil {
+		response = &qtiResponse{}
+	}
+	if interaction.Name == "textEntryInteraction" {
+		answers := uniqueStrings(append(append([]string(nil), response.correct...), response.mapped...))
+		if len(answers) == 0 {
+			item.Error = "缺少正确答案"
+			return item
+		}
+		item.Type = entity.TypeFillIn
+		item.Answer = answers[0]
+		item.AcceptedAnswers = answers[1:]
+		return item
+	}
+
+	correct := make(map[string]bool)
+	for _, id := range response.correct {
+		correct[id] = true
+	}
+	var letters []string
+	for i, choice := range interaction.children("simpleChoice") {
+		text := strings.Join(strings.Fields(choice.text()), " ")
+		if text == "" {
+			item.Error = fmt.Sprintf("选项%s没有文字", letter(i))
+			return item
+		}
+		item.Options = append(item.Options, text)
+		if correct[choice.Attrs["identifier"]] {
+			letters = append(letters, letter(i))
+		}
+	}
+	if len(letters) == 0 {
+		item.Error = "缺少正确答案"
+		return item
+	}
+
+	item.Type = entity.TypeChoice
+	item.Answer = strings.Join(letters, "")
+	switch {
+	case response.cardinality == "multiple" || len(letters) > 1:
+		item.Type = entity.TypeMultiChoice
+	case len(item.Options) == 2 && judgeAnswer(item.Options[0]) != "" && judgeAnswer(item.Options[1]) != "":
+		item.Type = entity.TypeJudge
+		item.Answer = judgeAnswer(item.Options[letterIndex(letters[0])])
+		item.Options = nil
+	}
+	return item
+}
+
+// qtiResource 内容包中的一个题目及其引用的文件
+type qtiResource struct {
+	identifier string
+	href       string
+	files      []string
+}
+
+// QTIWriter 将题目写为QTI 2.1内容包（zip），每道题一个文件，图片放在 media/ 目录
+type QTIWriter struct {
+	zw        *zip.Writer
+	resources []qtiResource
+	media     map[string]bool
+	skipped   []Skipped
+}
+
+// NewQTIWriter 创建QTI内容包写入器
+func NewQTIWriter(w io.Writer) *QTIWriter {
+	return &QTIWriter{zw: zip.NewWriter(w), media: make(map[string]bool)}
+}
+
+// Write 写入一道题
+func (w *QTIWriter) Write(question *entity.Question, media []Media) error {
+	item, err := exportItem(question)
+	if err != nil {
+		w.skipped = append(w.skipped, Skipped{QuestionID: question.ID, Reason: err.Error()})
+		return nil
+	}
+
+	resource := qtiResource{identifier: item.Identifier, href: "items/" + item.Identifier + ".xml"}
+	resource.files = append(resource.files, resource.href)
+	var images []string
+	for _, m := range media {
+		if m.Data == nil {
+			images = append(images, m.URL)
+			continue
+		}
+		name := "media/" + m.Name
+		if !w.media[name] {
+			f, err := w.zw.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(m.Data); err != nil {
+				return err
+			}
+			w.media[name] = true
+		}
+		resource.files = append(resource.files, name)
+		images = append(images, "../"+name)
+	}
+
+	f, err := w.zw.Create(resource.href)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, qtiItemXML(item, images)); err != nil {
+		return err
+	}
+	w.resources = append(w.resources, resource)
+	return nil
+}
+
+// Close 写入清单并结束zip文件
+func (w *QTIWriter) Close() error {
+	f, err := w.zw.Create(qtiManifestName)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1" identifier="MANIFEST-testogo">` + "\n")
+	b.WriteString("  <metadata><schema>QTIv2.1 Package</schema><schemaversion>1.0.0</schemaversion></metadata>\n")
+	b.WriteString("  <organizations/>\n  <resources>\n")
+	for _, resource := range w.resources {
+		fmt.Fprintf(&b, `    <resource identifier="%s" type="%s" href="%s">`+"\n", xmlEscape(resource.identifier), qtiItemType, xmlEscape(resource.href))
+		for _, file := range resource.files {
+			fmt.Fprintf(&b, `      <file href="%s"/>`+"\n", xmlEscape(file))
+		}
+		b.WriteString("    </resource>\n")
+	}
+	b.WriteString("  </resources>\n")
+	for _, skipped := range w.skipped {
+		fmt.Fprintf(&b, "  <!-- %s -->\n", xmlComment(skippedNote(skipped)))
+	}
+	b.WriteString("</manifest>\n")
+	if _, err := io.WriteString(f, b.String()); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+// Skipped 跳过的题目
+func (w *QTIWriter) Skipped() []Skipped {
+	return w.skipped
+}
+
+// qtiItemXML 生成一道题的 assessmentItem 文件
+func qtiItemXML(item Item, images []string) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<assessmentItem xmlns="%s" identifier="%s" title="%s" adaptive="false" timeDependent="false">`+"\n",
+		qtiNamespace, xmlEscape(item.Identifier), xmlEscape(summary(item.Title, 40)))
+
+	cardinality, baseType := "single", "identifier"
+	if item.Type == entity.TypeMultiChoice {
+		cardinality = "multiple"
+	}
+	if item.Type == entity.TypeFillIn {
+		baseType = "string"
+	}
+	fmt.Fprintf(&b, `  <responseDeclaration identifier="%s" cardinality="%s" baseType="%s">`+"\n", qtiResponseID, cardinality, baseType)
+	b.WriteString("    <correctResponse>\n")
+	switch item.Type {
+	case entity.TypeJudge:
+		answer := "A"
+		if item.Answer == judgeFalse {
+			answer = "B"
+		}
+		fmt.Fprintf(&b, "      <value>%s</value>\n", answer)
+	case entity.TypeFillIn:
+		fmt.Fprintf(&b, "      <value>%s</value>\n", xmlEscape(item.Answer))
+	default:
+		for _, r := range item.Answer {
+			fmt.Fprintf(&b, "      <value>%c</value>\n", r)
+		}
+	}
+	b.WriteString("    </correctResponse>\n")
+	template := qtiMatchCorrect
+	if item.Type == entity.TypeFillIn && len(item.AcceptedAnswers) > 0 {
+		template = qtiMapResponse
+		b.WriteString(`    <mapping defaultValue="0">` + "\n")
+		for _, answer := range append([]string{item.Answer}, item.AcceptedAnswers...) {
+			fmt.Fprintf(&b, `      <mapEntry mapKey="%s" mappedValue="1"/>`+"\n", xmlEscape(answer))
+		}
+		b.WriteString("    </mapping>\n")
+	}
+	b.WriteString("  </responseDeclaration>\n")
+	b.WriteString(`  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float"><defaultValue><value>0</value></defaultValue></outcomeDeclaration>` + "\n")
+	if item.Explanation != "" {
+		fmt.Fprintf(&b, `  <outcomeDeclaration identifier="%s" cardinality="single" baseType="identifier"/>`+"\n", qtiFeedbackOutcome)
+	}
+
+	b.WriteString("  <itemBody>\n")
+	title := textHTML(item.Title)
+	if item.Type == entity.TypeFillIn {
+		if loc := qtiBlankPattern.FindStringIndex(title); loc != nil {
+			title = title[:loc[0]] + qtiBlankMarkup + title[loc[1]:]
+		} else {
+			title += " " + qtiBlankMarkup
+		}
+	}
+	fmt.Fprintf(&b, "    <p>%s</p>\n", title)
+	for _, image := range images {
+		fmt.Fprintf(&b, `    <p><img src="%s" alt=""/></p>`+"\n", xmlEscape(image))
+	}
+	switch item.Type {
+	case entity.TypeChoice, entity.TypeMultiChoice, entity.TypeJudge:
+		options := item.Options
+		maxChoices := "1"
+		if item.Type == entity.TypeJudge {
+			options = []string{judgeTrue, judgeFalse}
+		}
+		if item.Type == entity.TypeMultiChoice {
+			maxChoices = "0"
+		}
+		fmt.Fprintf(&b, `    <choiceInteraction responseIdentifier="%s" shuffle="false" maxChoices="%s">`+"\n", qtiResponseID, maxChoices)
+		for i, option := range options {
+			fmt.Fprintf(&b, `      <simpleChoice identifier="%s">%s</simpleChoice>`+"\n", letter(i), xmlEscape(option))
+		}
+		b.WriteString("    </choiceInteraction>\n")
+	}
+	b.WriteString("  </itemBody>\n")
+	fmt.Fprintf(&b, `  <responseProcessing template="%s"/>`+"\n", template)
+	if item.Explanation != "" {
+		fmt.Fprintf(&b, `  <modalFeedback outcomeIdentifier="%s" identifier="%s" showHide="hide"><p>%s</p></modalFeedback>`+"\n",
+			qtiFeedbackOutcome, qtiExplanationID, textHTML(item.Explanation))
+	}
+	b.WriteString("</assessmentItem>\n")
+	return b.String()
+}
+
+// readZipFile 读取内容包中的文件，解压后超过 qtiMaxFileSize 时返回错误
+// 压缩文件头中记录的大小可能与实际不符，读取时同样限制大小
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > qtiMaxFileSize {
+		return nil, fmt.Errorf("文件 %s 超过最大大小限制", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, qtiMaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > qtiMaxFileSize {
+		return nil, fmt.Errorf("文件 %s 超过最大大小限制", f.Name)
+	}
+	return data, nil
+}
+
+func isExternalURL(src string) bool {
+	lower := strings.ToLower(src)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(src, "/")
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlComment 去掉注释中不允许出现的 "--"
+func xmlComment(s string) string {
+	return strings.ReplaceAll(s, "--", "- -")
+}
+
+// skippedNote 跳过题目的说明，写在导出文件的注释中
+func skippedNote(skipped Skipped) string {
+	return fmt.Sprintf("题目 %d 未导出：%s", skipped.QuestionID, skipped.Reason)
+}
+
+// summary 截取文本开头作为名称
+func summary(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > limit {
+		return string(runes[:limit]) + "…"
+	}
+	return text
+}
+
+func uniqueStrings(items []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" && !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
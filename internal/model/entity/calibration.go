@@ -0,0 +1,62 @@
+package entity
+
+import "time"
+
+// CalibrationStatus 难度校准任务状态
+type CalibrationStatus string
+
+const (
+	CalibrationQueued    CalibrationStatus = "queued"    // 等待运行
+	CalibrationRunning   CalibrationStatus = "running"   // 运行中
+	CalibrationCompleted CalibrationStatus = "completed" // 已完成
+	CalibrationFailed    CalibrationStatus = "failed"    // 运行失败
+)
+
+// CalibrationRun 难度校准任务，按答题记录用Rasch模型估计题目难度和学生能力
+// 只使用每名学生对每道题的首次作答，首次作答查看过提示时该题不计入
+type CalibrationRun struct {
+	ID               uint              `gorm:"primarykey" json:"id"`
+	CreatorID        uint              `json:"creator_id"`
+	Status           CalibrationStatus `gorm:"type:varchar(20);index" json:"status"`
+	ObservationCount int               `json:"observation_count"` // 参与估计的作答数
+	ItemCount        int               `json:"item_count"`        // 校准的题目数
+	LearnerCount     int               `json:"learner_count"`     // 估计能力的学生数
+	Iterations       int               `json:"iterations"`
+	Converged        bool              `json:"converged"`
+	ErrorMessage     string            `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	CompletedAt      *time.Time        `json:"completed_at,omitempty"`
+}
+
+// ItemCalibration 一次校准中题目的难度估计
+type ItemCalibration struct {
+	ID                  uint       `gorm:"primarykey" json:"id"`
+	RunID               uint       `gorm:"index" json:"run_id"`
+	QuestionID          uint       `gorm:"index" json:"question_id"`
+	Difficulty          float64    `json:"difficulty"` // Rasch难度（logit），题目难度均值为0
+	StdError            float64    `json:"std_error"`
+	Responses           int        `json:"responses"`
+	CorrectCount        int        `json:"correct_count"`
+	Extreme             bool       `json:"extreme"`              // 全部答对或全部答错，估计值仅供参考
+	AuthoredDifficulty  int        `json:"authored_difficulty"`  // 校准时题目的难度
+	SuggestedDifficulty int        `json:"suggested_difficulty"` // 由Rasch难度换算的1-5难度
+	AcceptedAt          *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// LearnerAbility 一次校准中学生的能力估计
+type LearnerAbility struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	RunID        uint      `gorm:"index" json:"run_id"`
+	UserID       uint      `gorm:"index" json:"user_id"`
+	Ability      float64   `json:"ability"` // 能力（logit），与题目难度在同一量尺上
+	StdError     float64   `json:"std_error"`
+	Responses    int       `json:"responses"`
+	CorrectCount int       `json:"correct_count"`
+	Extreme      bool      `json:"extreme"` // 全部答对或全部答错，估计值仅供参考
+	CreatedAt    time.Time `json:"created_at"`
+
+	// 关联关系
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
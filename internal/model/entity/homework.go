@@ -41,6 +41,9 @@ type Homework struct {
 	UpdatedAt             time.Time            `json:"updated_at"`
 	DeletedAt             gorm.DeletedAt       `gorm:"index" json:"-"`
 
+	// Sharing scope among teachers, see ResourceShare for the share list
+	Visibility Visibility `gorm:"type:varchar(20);default:'private';index" json:"visibility"`
+
 	// Relations
 	Creator              User                   `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
 	HomeworkAssignments  []HomeworkAssignment   `gorm:"foreignKey:HomeworkID" json:"assignments,omitempty"`
@@ -105,9 +108,16 @@ type HomeworkQuestionAnswer struct {
 	QuestionID   uint   `json:"question_id"`
 	Answer       string `gorm:"type:text" json:"answer"`
 	IsCorrect    bool   `json:"is_correct"`
+	Score        float64 `gorm:"default:0" json:"score"` // points earned for this question
 	TimeSpent    int    `json:"time_spent"` // seconds
 	CreatedAt    time.Time `json:"created_at"`
 
+	// Question revision that was answered, see QuestionRevision.Revision
+	QuestionRevision int `gorm:"default:0" json:"question_revision"`
+
+	// Hints revealed before answering, 0 means unaided; see HintReveal
+	HintsUsed int `gorm:"default:0" json:"hints_used"`
+
 	// Relations
 	Submission HomeworkSubmission `gorm:"foreignKey:SubmissionID" json:"submission,omitempty"`
 	Question   Question           `gorm:"foreignKey:QuestionID" json:"question,omitempty"`
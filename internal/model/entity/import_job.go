@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+// ImportJobStatus 导入任务状态
+type ImportJobStatus string
+
+const (
+	ImportJobQueued         ImportJobStatus = "queued"          // 等待解析
+	ImportJobParsing        ImportJobStatus = "parsing"         // 解析中
+	ImportJobAwaitingReview ImportJobStatus = "awaiting_review" // 解析完成，等待老师核对并确认导入
+	ImportJobCommitted      ImportJobStatus = "committed"       // 已导入题库
+	ImportJobFailed         ImportJobStatus = "failed"          // 解析失败
+)
+
+// 导入预览题目的状态
+const (
+	ImportItemPending  = "pending"  // 待核对
+	ImportItemApproved = "approved" // 已确认，确认导入时写入题库
+	ImportItemRejected = "rejected" // 不导入
+	ImportItemImported = "imported" // 已写入题库
+)
+
+// ImportJob 题目导入任务，上传的文件在后台解析，解析结果保存为预览题目供老师逐题核对
+type ImportJob struct {
+	ID             uint            `gorm:"primarykey" json:"id"`
+	CreatorID      uint            `gorm:"index" json:"creator_id"`
+	FileName       string          `gorm:"type:varchar(255)" json:"file_name"` // 上传时的文件名
+	FileID         string          `gorm:"type:varchar(255)" json:"file_id"`   // 导入目录中保存的文件名，导入完成或删除任务后清理
+	Format         string          `gorm:"type:varchar(10)" json:"format"`     // 文件扩展名
+	AutoDetect     bool            `json:"auto_detect"`
+	DefaultGrade   string          `gorm:"type:varchar(20)" json:"default_grade"`
+	DefaultSubject string          `gorm:"type:varchar(50)" json:"default_subject"`
+	Status         ImportJobStatus `gorm:"type:varchar(20);index" json:"status"`
+	Progress       int             `json:"progress"` // 进度百分比，解析和确认导入时分别从0到100
+	TotalCount     int             `json:"total_count"`
+	DetectedCount  int             `json:"detected_count"`
+	ImportedCount  int             `json:"imported_count"`
+	ErrorMessage   string          `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"` // 导入题库的时间
+}
+
+// ImportJobItem 导入任务中的一道预览题目
+type ImportJobItem struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	JobID         uint      `gorm:"index" json:"job_id"`
+	OriginalIndex int       `json:"original_index"` // 在原文件中的位置
+	Status        string    `gorm:"type:varchar(20);index" json:"status"`
+	Data          string    `gorm:"type:text" json:"data"` // JSON格式存储解析结果，老师编辑后覆盖
+	QuestionID    *uint     `json:"question_id,omitempty"` // 写入题库后的题目ID
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,9 @@
+package entity
+
+import "time"
+
+// DataMigration 已完成的一次性数据迁移，启动时跳过已完成的迁移
+type DataMigration struct {
+	Name        string    `gorm:"type:varchar(100);primarykey" json:"name"`
+	CompletedAt time.Time `json:"completed_at"`
+}
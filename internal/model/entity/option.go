@@ -21,6 +21,22 @@ type ComparisonData struct {
 	CompareFormat string              `json:"compare_format"`  // 比较格式模板，如 "{0}比{1}多/少"
 }
 
+// 数字序列的规律类型
+const (
+	SequenceArithmetic  = "arithmetic"  // 等差数列
+	SequenceGeometric   = "geometric"   // 等比数列
+	SequenceAlternating = "alternating" // 交替加减，如 +3、-1、+3、-1
+	SequenceRepeating   = "repeating"   // 一组数循环出现
+)
+
+// SequenceData 推理题的数字序列数据
+type SequenceData struct {
+	Kind       string `json:"kind"`       // arithmetic, geometric, alternating, repeating
+	Terms      []int  `json:"terms"`      // 完整数列
+	Missing    []int  `json:"missing"`    // 需要填写的项的下标（从0开始，可在任意位置）
+	Difficulty int    `json:"difficulty"` // 生成时的难度 1-5
+}
+
 // ContentSegment 内容片段，支持文字、图片、填空
 type ContentSegment struct {
 	Type    string `json:"type"`              // text, image, blank
@@ -34,6 +50,8 @@ type BlankItem struct {
 	Answer      string `json:"answer"`                // 正确答案
 	Placeholder string `json:"placeholder,omitempty"` // 填空提示文字
 	Required    bool   `json:"required"`              // 是否必填
+
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"` // 其他可接受的答案
 }
 
 // SubQuestion 子题结构
@@ -49,4 +67,38 @@ type ComplexQuestionData struct {
 	HasMainImage bool          `json:"has_main_image"`          // 是否有主题目图片
 	MainImageURL string        `json:"main_image_url,omitempty"` // 主题目图片URL
 	SubQuestions []SubQuestion `json:"sub_questions"`           // 子题列表
-}
\ No newline at end of file
+}
+
+// ScoringPolicy 多选题计分规则
+type ScoringPolicy string
+
+const (
+	ScoringAllOrNothing ScoringPolicy = "all_or_nothing" // 全部选对才得分（默认）
+	ScoringProportional ScoringPolicy = "proportional"   // 按选对比例得分，有错选不得分
+	ScoringPenalty      ScoringPolicy = "penalty"        // 按选对比例得分，每个错选倒扣一项，最低0分
+)
+
+// GradingConfig 题目判分配置
+type GradingConfig struct {
+	Tolerance     float64       `json:"tolerance,omitempty"`      // 数值答案允许的误差
+	ExactForm     bool          `json:"exact_form,omitempty"`     // 是否要求答案形式完全一致（如分数必须化简）
+	ScoringPolicy ScoringPolicy `json:"scoring_policy,omitempty"` // 多选题计分规则，为空时使用试卷规则或全对才得分
+	Points        float64       `json:"points,omitempty"`         // 题目分值，默认1分
+
+	// 文本答案匹配规则，默认忽略大小写
+	AcceptedAnswers   []string `json:"accepted_answers,omitempty"`   // 除 Question.Answer 外其他可接受的答案
+	Patterns          []string `json:"patterns,omitempty"`           // 可接受答案的正则表达式，需完整匹配
+	CaseSensitive     bool     `json:"case_sensitive,omitempty"`     // 区分大小写
+	FoldWidth         bool     `json:"fold_width,omitempty"`         // 全角半角视为相同
+	IgnoreWhitespace  bool     `json:"ignore_whitespace,omitempty"`  // 忽略所有空白
+	IgnorePunctuation bool     `json:"ignore_punctuation,omitempty"` // 忽略标点符号
+}
+
+// IsValidScoringPolicy 校验计分规则，空值表示使用默认规则
+func IsValidScoringPolicy(policy ScoringPolicy) bool {
+	switch policy {
+	case "", ScoringAllOrNothing, ScoringProportional, ScoringPenalty:
+		return true
+	}
+	return false
+}
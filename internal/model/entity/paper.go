@@ -24,6 +24,12 @@ type Paper struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// 多选题计分规则，题目未单独配置时使用
+	ScoringPolicy ScoringPolicy `gorm:"type:varchar(20)" json:"scoring_policy"`
+
+	// 教师之间的共享范围，共享成员见 ResourceShare
+	Visibility Visibility `gorm:"type:varchar(20);default:'school';index" json:"visibility"`
+
 	// 关联
 	Creator User `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
 }
@@ -20,33 +20,78 @@ const (
 	TypeCircleSelect QuestionType = "circleselect" // 圈选题（把一样多的圈起来）
 )
 
+// IsValidQuestionType 校验题目类型
+func IsValidQuestionType(t QuestionType) bool {
+	switch t {
+	case TypeChoice, TypeMultiChoice, TypeJudge, TypeFillIn, TypeMath,
+		TypeComparison, TypeReasoning, TypeVisual, TypeCircleSelect:
+		return true
+	}
+	return false
+}
+
+// QuestionStatus 题目审核状态
+type QuestionStatus string
+
+const (
+	QuestionStatusDraft     QuestionStatus = "draft"     // 草稿，仅教师可见
+	QuestionStatusInReview  QuestionStatus = "in_review" // 待审核
+	QuestionStatusPublished QuestionStatus = "published" // 已发布，可用于练习、试卷和作业
+	QuestionStatusRetired   QuestionStatus = "retired"   // 已停用，不能再被选用，历史成绩仍可查看
+)
+
 type Question struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	Title       string         `gorm:"type:text" json:"title"`
-	Type        QuestionType   `gorm:"type:varchar(20)" json:"type"`
-	Difficulty  int            `gorm:"type:tinyint;default:1" json:"difficulty"` // 1-5
-	Grade       string         `gorm:"type:varchar(20)" json:"grade"`            // 年级: grade1, grade2, etc.
-	SubjectID   *uint          `json:"subject_id"`                               // 科目外键
-	TopicID     *uint          `json:"topic_id"`                                 // 主题外键
-	Subject     string         `gorm:"type:varchar(50)" json:"subject"`          // 科目: math, vocabulary, reading, literacy (保持向后兼容)
-	Topic       string         `gorm:"type:varchar(100)" json:"topic"`           // 主题: addition, subtraction, etc. (保持向后兼容)
-	Options     string         `gorm:"type:text" json:"options"`                 // JSON格式存储选项 - 支持文字和图片混合
-	Answer      string         `gorm:"type:text" json:"answer"`
-	Explanation string         `gorm:"type:text" json:"explanation"` // 答案解释
-	CreatorID   uint           `json:"creator_id"`
-	MediaURL    string         `gorm:"type:varchar(255)" json:"media_url"` // 单个媒体资源URL（保留兼容性）
-	MediaURLs   string         `gorm:"type:text" json:"media_urls"`        // JSON格式存储多个媒体资源URL
-	LayoutType  string         `gorm:"type:varchar(50)" json:"layout_type"` // 布局类型：single, horizontal, vertical, grid
-	ElementData string         `gorm:"type:text" json:"element_data"`       // JSON格式存储元素位置和标签信息
-	Tags        string         `gorm:"type:varchar(255)" json:"tags"`      // 逗号分隔的标签
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint           `gorm:"primarykey" json:"id"`
+	Title         string         `gorm:"type:text" json:"title"`
+	Type          QuestionType   `gorm:"type:varchar(20)" json:"type"`
+	Difficulty    int            `gorm:"type:tinyint;default:1" json:"difficulty"` // 1-5
+	Grade         string         `gorm:"type:varchar(20)" json:"grade"`            // 年级: grade1, grade2, etc.
+	SubjectID     *uint          `json:"subject_id"`                               // 科目外键
+	TopicID       *uint          `json:"topic_id"`                                 // 主题外键
+	Subject       string         `gorm:"type:varchar(50)" json:"subject"`          // 科目: math, vocabulary, reading, literacy (保持向后兼容)
+	Topic         string         `gorm:"type:varchar(100)" json:"topic"`           // 主题: addition, subtraction, etc. (保持向后兼容)
+	Options       string         `gorm:"type:text" json:"options"`                 // JSON格式存储选项 - 支持文字和图片混合
+	Answer        string         `gorm:"type:text" json:"answer"`
+	Explanation   string         `gorm:"type:text" json:"explanation"` // 答案解释
+	CreatorID     uint           `json:"creator_id"`
+	MediaURL      string         `gorm:"type:varchar(255)" json:"media_url"`  // 单个媒体资源URL（保留兼容性）
+	MediaURLs     string         `gorm:"type:text" json:"media_urls"`         // JSON格式存储多个媒体资源URL
+	LayoutType    string         `gorm:"type:varchar(50)" json:"layout_type"` // 布局类型：single, horizontal, vertical, grid
+	ElementData   string         `gorm:"type:text" json:"element_data"`       // JSON格式存储元素位置和标签信息
+	Tags          string         `gorm:"type:text" json:"tags"`               // 逗号分隔的标签，保存时同步到 TagRefs
+	GradingConfig string         `gorm:"type:text" json:"grading_config"`     // JSON格式存储判分配置
+	TemplateID    *uint          `gorm:"index" json:"template_id,omitempty"`  // 由模板生成时对应的模板ID
+	TemplateSeed  int64          `json:"template_seed,omitempty"`             // 生成时使用的随机种子
+	Revision      int            `gorm:"default:0" json:"revision"`           // 当前版本号，对应 QuestionRevision.Revision
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 审核流程
+	Status     QuestionStatus `gorm:"type:varchar(20);default:'draft';index" json:"status"`
+	ReviewerID *uint          `json:"reviewer_id"` // 指定的审核人
+
+	// 查重指纹，保存题目时计算
+	ContentHash string `gorm:"type:varchar(64);index" json:"-"` // 规范化后的题干、选项和答案的哈希
+	MediaHash   string `gorm:"type:varchar(64)" json:"-"`       // 媒体文件内容的哈希
+
+	// 逐级提示，JSON格式存储 []QuestionHint，作答时按顺序逐条显示
+	Hints     string `gorm:"type:text" json:"hints"`
+	HintCount int    `gorm:"-" json:"hint_count,omitempty"` // 返回给学生时代替 Hints，提示内容只能逐条查看
+
+	// 最近一次难度校准的结果，见 ItemCalibration；作答数不足未参与校准时为空
+	CalibratedDifficulty *float64 `json:"calibrated_difficulty"`                 // Rasch难度（logit）
+	SuggestedDifficulty  int      `gorm:"default:0" json:"suggested_difficulty"` // 由校准难度换算的1-5难度，0表示未校准
+	CalibrationRunID     *uint    `json:"calibration_run_id,omitempty"`
+
+	// 教师之间的共享范围，共享成员见 ResourceShare
+	Visibility Visibility `gorm:"type:varchar(20);default:'school';index" json:"visibility"`
 
 	// 关联关系
 	SubjectRef *Subject `gorm:"foreignKey:SubjectID" json:"subject_ref,omitempty"`
 	TopicRef   *Topic   `gorm:"foreignKey:TopicID" json:"topic_ref,omitempty"`
 	Creator    User     `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
+	TagRefs    []Tag    `gorm:"many2many:question_tag" json:"tag_refs,omitempty"`
 }
 
 type UserAnswer struct {
@@ -56,11 +101,18 @@ type UserAnswer struct {
 	QuestionID uint           `json:"question_id"`
 	Answer     string         `gorm:"type:text" json:"answer"`
 	IsCorrect  bool           `json:"is_correct"`
+	Score      float64        `gorm:"default:0" json:"score"`                               // 本题实际得分
 	AnswerType string         `gorm:"type:varchar(20);default:'single'" json:"answer_type"` // single|paper
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// 作答时的题目版本号，对应 QuestionRevision.Revision
+	QuestionRevision int `gorm:"default:0" json:"question_revision"`
+
+	// 作答前查看的提示条数，0 表示独立完成，见 HintReveal
+	HintsUsed int `gorm:"default:0" json:"hints_used"`
+
 	// 关联关系
 	Question Question `gorm:"foreignKey:QuestionID" json:"question,omitempty"`
 	User     User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
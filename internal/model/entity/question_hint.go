@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// 提示内容的类型
+const (
+	HintText  = "text"  // 文字提示
+	HintImage = "image" // 图片提示，Content 为图片URL
+	HintAudio = "audio" // 音频提示，Content 为音频URL
+)
+
+// QuestionHint 题目的一条提示，Question.Hints 中按顺序保存，作答时逐条显示
+type QuestionHint struct {
+	Type    string `json:"type"`    // text, image, audio
+	Content string `json:"content"` // 文字内容或图片、音频URL
+}
+
+// 查看提示的场景
+const (
+	HintContextPractice = "practice" // 单题练习
+	HintContextPaper    = "paper"    // 试卷
+	HintContextHomework = "homework" // 作业
+)
+
+// HintReveal 学生查看提示的记录
+// 提交答案时，该题在同一场景下尚未关联答题记录的查看记录会关联到新的答题记录，
+// 答题记录的 HintsUsed 为作答前查看的提示条数，用于区分独立答对和借助提示答对
+type HintReveal struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UserID     uint      `gorm:"index:idx_hint_reveal_scope" json:"user_id"`
+	QuestionID uint      `gorm:"index:idx_hint_reveal_scope" json:"question_id"`
+	Context    string    `gorm:"type:varchar(20);index:idx_hint_reveal_scope" json:"context"` // practice, paper, homework
+	ContextID  uint      `gorm:"index:idx_hint_reveal_scope" json:"context_id"`               // 试卷或作业ID，单题练习为0
+	Level      int       `json:"level"`                                                       // 第几条提示，从1开始
+	AnswerID   *uint     `gorm:"index" json:"answer_id,omitempty"`                            // 关联的答题记录，作业为 HomeworkQuestionAnswer.ID，其余为 UserAnswer.ID
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// 审核操作
+const (
+	ReviewActionSubmit  = "submit"  // 提交审核
+	ReviewActionAssign  = "assign"  // 指定审核人
+	ReviewActionComment = "comment" // 审核意见
+	ReviewActionApprove = "approve" // 审核通过并发布
+	ReviewActionReject  = "reject"  // 驳回为草稿
+	ReviewActionRetire  = "retire"  // 停用
+	ReviewActionRevise  = "revise"  // 已发布或待审核的题目内容被修改，退回草稿
+)
+
+// QuestionReview 题目审核记录，包括状态变更和审核意见
+type QuestionReview struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	QuestionID uint           `gorm:"index" json:"question_id"`
+	UserID     uint           `json:"user_id"` // 操作人
+	Action     string         `gorm:"type:varchar(20)" json:"action"`
+	FromStatus QuestionStatus `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus   QuestionStatus `gorm:"type:varchar(20)" json:"to_status"`
+	ReviewerID *uint          `json:"reviewer_id,omitempty"` // 指定审核人时的审核人
+	Comment    string         `gorm:"type:text" json:"comment"`
+	Revision   int            `json:"revision"` // 操作时的题目版本号
+	CreatedAt  time.Time      `json:"created_at"`
+
+	// 关联关系
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
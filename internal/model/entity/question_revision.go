@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// QuestionRevision 题目修订记录，每次创建或修改题目都会保存一份完整快照，记录后不再修改
+type QuestionRevision struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	QuestionID uint      `gorm:"uniqueIndex:idx_question_revision" json:"question_id"`
+	Revision   int       `gorm:"uniqueIndex:idx_question_revision" json:"revision"` // 版本号，从1开始递增
+	Snapshot   string    `gorm:"type:text" json:"snapshot"`                         // JSON格式存储 QuestionSnapshot
+	EditorID   uint      `json:"editor_id"`
+	Comment    string    `gorm:"type:varchar(255)" json:"comment"` // 修改说明
+	CreatedAt  time.Time `json:"created_at"`
+
+	// 关联关系
+	Editor User `gorm:"foreignKey:EditorID" json:"editor,omitempty"`
+}
+
+// QuestionSnapshot 题目内容快照，包含影响题目展示和判分的全部字段
+type QuestionSnapshot struct {
+	Title         string       `json:"title"`
+	Type          QuestionType `json:"type"`
+	Difficulty    int          `json:"difficulty"`
+	Grade         string       `json:"grade"`
+	SubjectID     *uint        `json:"subject_id"`
+	TopicID       *uint        `json:"topic_id"`
+	Subject       string       `json:"subject"`
+	Topic         string       `json:"topic"`
+	Options       string       `json:"options"`
+	Answer        string       `json:"answer"`
+	Explanation   string       `json:"explanation"`
+	MediaURL      string       `json:"media_url"`
+	MediaURLs     string       `json:"media_urls"`
+	LayoutType    string       `json:"layout_type"`
+	ElementData   string       `json:"element_data"`
+	Tags          string       `json:"tags"`
+	GradingConfig string       `json:"grading_config"`
+	Hints         string       `json:"hints"`
+}
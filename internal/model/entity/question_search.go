@@ -0,0 +1,10 @@
+package entity
+
+// QuestionSearchTerm 题目全文检索的倒排索引，每行记录一个词在题目某个字段中出现的次数
+type QuestionSearchTerm struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	QuestionID uint   `gorm:"index" json:"question_id"`
+	Term       string `gorm:"type:varchar(64);index" json:"term"`
+	Field      string `gorm:"type:varchar(20)" json:"field"` // title, options, explanation, tags
+	Frequency  int    `json:"frequency"`
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QuestionTemplate 参数化数学题模板，按随机种子生成具体题目
+type QuestionTemplate struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Title       string         `gorm:"type:varchar(200)" json:"title"`           // 模板名称
+	Stem        string         `gorm:"type:text" json:"stem"`                    // 题干模板，变量写作 {a}，如 "{a} + {b} = ?"
+	Expression  string         `gorm:"type:varchar(255)" json:"expression"`      // 答案算式，如 "a+b"
+	Variables   string         `gorm:"type:text" json:"variables"`               // JSON格式存储变量定义 []TemplateVariable
+	Constraints string         `gorm:"type:text" json:"constraints"`             // JSON格式存储约束条件，如 ["no_carry", "a>b"]
+	Grade       string         `gorm:"type:varchar(20)" json:"grade"`            // 年级
+	Subject     string         `gorm:"type:varchar(50)" json:"subject"`          // 科目
+	Topic       string         `gorm:"type:varchar(100)" json:"topic"`           // 主题
+	Difficulty  int            `gorm:"type:tinyint;default:1" json:"difficulty"` // 1-5
+	CreatorID   uint           `json:"creator_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 审核状态，模板审核通过后生成的题目直接发布，不需要逐题审核
+	Status        QuestionStatus `gorm:"type:varchar(20);default:'draft';index" json:"status"`
+	ReviewComment string         `gorm:"type:text" json:"review_comment"` // 最近一次审核操作的意见
+
+	// 关联关系
+	Creator User `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
+}
+
+// TemplateVariable 模板变量，在 [Min, Max] 范围内按步长取整数
+type TemplateVariable struct {
+	Name string `json:"name"`           // 变量名，如 a
+	Min  int    `json:"min"`            // 最小值
+	Max  int    `json:"max"`            // 最大值
+	Step int    `json:"step,omitempty"` // 步长，默认1
+}
+
+// 模板内置约束
+const (
+	ConstraintNoCarry       = "no_carry"       // 各变量相加不进位
+	ConstraintNoBorrow      = "no_borrow"      // 第一个变量依次减去其余变量不退位
+	ConstraintIntegerResult = "integer_result" // 答案为整数（用于除法）
+	ConstraintNonNegative   = "non_negative"   // 答案不小于0
+)
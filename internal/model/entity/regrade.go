@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// RegradeJobStatus 重新判分任务状态
+type RegradeJobStatus string
+
+const (
+	RegradeJobQueued    RegradeJobStatus = "queued"    // 等待运行
+	RegradeJobRunning   RegradeJobStatus = "running"   // 运行中
+	RegradeJobCompleted RegradeJobStatus = "completed" // 已完成
+	RegradeJobFailed    RegradeJobStatus = "failed"    // 运行失败
+)
+
+// RegradeJob 重新判分任务，在后台按题目运行时的标准答案和判分配置重新判定全部历史答题记录
+// 同一道题排队中的任务只保留一个，运行时总是使用最新的标准答案
+type RegradeJob struct {
+	ID             uint             `gorm:"primarykey" json:"id"`
+	QuestionID     uint             `gorm:"index" json:"question_id"`
+	CreatorID      uint             `json:"creator_id"`
+	Status         RegradeJobStatus `gorm:"type:varchar(20);index" json:"status"`
+	AnswersChecked int              `json:"answers_checked"`        // 检查的练习、试卷和作业答题记录数
+	AnswersChanged int              `json:"answers_changed"`        // 判分结果发生变化的记录数
+	Result         string           `gorm:"type:longtext" json:"-"` // JSON格式存储重新判分结果汇总
+	ErrorMessage   string           `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
+}
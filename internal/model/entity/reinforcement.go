@@ -54,6 +54,9 @@ type ReinforcementSetting struct {
 	UpdatedAt         time.Time                 `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt            `gorm:"index" json:"-"`
 
+	// Sharing scope among teachers, see ResourceShare for the share list
+	Visibility Visibility `gorm:"type:varchar(20);default:'private';index" json:"visibility"`
+
 	// Relations
 	Creator                     User                        `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
 	ReinforcementItems          []ReinforcementItem         `gorm:"many2many:reinforcement_setting_items;" json:"items,omitempty"`
@@ -157,7 +160,7 @@ type Topic struct {
 
 	// Relations
 	Subject   Subject    `gorm:"foreignKey:SubjectID" json:"subject,omitempty"`
-	Questions []Question `gorm:"foreignKey:Tags" json:"questions,omitempty"` // Questions tagged with this topic
+	Questions []Question `gorm:"foreignKey:TopicID" json:"questions,omitempty"` // Questions belonging to this topic
 }
 
 // UserPerformance represents user learning statistics
@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+// Visibility 教师之间的共享范围
+// 只控制教师能否查看和修改，学生仍按发布状态和作业布置访问
+type Visibility string
+
+const (
+	VisibilityPrivate Visibility = "private" // 仅创建者
+	VisibilityShared  Visibility = "shared"  // 创建者和共享成员
+	VisibilitySchool  Visibility = "school"  // 全校教师可查看，共享成员中的协作编辑者可修改
+)
+
+// ShareResourceType 可共享的资源类型
+type ShareResourceType string
+
+const (
+	ShareQuestion             ShareResourceType = "question"
+	SharePaper                ShareResourceType = "paper"
+	ShareHomework             ShareResourceType = "homework"
+	ShareReinforcementSetting ShareResourceType = "reinforcement_setting"
+)
+
+// ShareRole 共享成员的角色
+type ShareRole string
+
+const (
+	ShareViewer   ShareRole = "viewer"    // 可查看和使用，如组卷、布置作业、复制
+	ShareCoEditor ShareRole = "co_editor" // 还可以修改内容，不能删除和修改共享设置
+)
+
+// ResourceShare 资源共享给指定教师的记录，私有资源没有共享成员
+// 创建者和管理员始终拥有全部权限，不需要记录
+type ResourceShare struct {
+	ID           uint              `gorm:"primarykey" json:"id"`
+	ResourceType ShareResourceType `gorm:"type:varchar(30);uniqueIndex:idx_resource_share" json:"resource_type"`
+	ResourceID   uint              `gorm:"uniqueIndex:idx_resource_share" json:"resource_id"`
+	UserID       uint              `gorm:"uniqueIndex:idx_resource_share;index" json:"user_id"`
+	Role         ShareRole         `gorm:"type:varchar(20)" json:"role"`
+	CreatedBy    uint              `json:"created_by"`
+	CreatedAt    time.Time         `json:"created_at"`
+
+	// 关联关系
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTagNameLength 标签名称的最大字数
+const MaxTagNameLength = 50
+
+// Tag 题目标签，与题目为多对多关系（关联表 question_tag）
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"type:varchar(50);uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ParseTagNames 解析逗号分隔的标签字符串，兼容中文逗号、顿号和分号
+// 去除首尾空白，忽略空标签，按名称去重（不区分大小写），超长的标签名会被截断
+func ParseTagNames(tags string) []string {
+	fields := strings.FieldsFunc(tags, func(r rune) bool {
+		switch r {
+		case ',', '，', '、', ';', '；', '\n':
+			return true
+		}
+		return false
+	})
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range fields {
+		name = strings.TrimSpace(name)
+		if utf8.RuneCountInString(name) > MaxTagNameLength {
+			name = string([]rune(name)[:MaxTagNameLength])
+		}
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// JoinTagNames 将标签名拼接为逗号分隔的字符串，与 Question.Tags 的存储格式一致
+func JoinTagNames(names []string) string {
+	return strings.Join(names, ",")
+}
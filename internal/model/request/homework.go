@@ -57,9 +57,10 @@ type SubmitHomeworkRequest struct {
 
 // HomeworkQuestionAnswerRequest represents individual question answer in submission
 type HomeworkQuestionAnswerRequest struct {
-	QuestionID uint   `json:"question_id" binding:"required"`
-	Answer     string `json:"answer" binding:"required"`
-	TimeSpent  int    `json:"time_spent"`
+	QuestionID uint              `json:"question_id" binding:"required"`
+	Answer     string            `json:"answer" binding:"required_without=Blanks"`
+	Blanks     map[string]string `json:"blanks"` // Complex fill-in answers keyed by blank ID
+	TimeSpent  int               `json:"time_spent"`
 }
 
 // AdjustHomeworkRequest represents homework adjustment by teacher
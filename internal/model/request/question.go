@@ -1,6 +1,10 @@
 package request
 
-import "time"
+import (
+	"time"
+
+	"testogo/internal/model/entity"
+)
 
 type CreateQuestionRequest struct {
 	Title       string `json:"title" binding:"required"`
@@ -18,6 +22,11 @@ type CreateQuestionRequest struct {
 	LayoutType  string `json:"layout_type"`  // 布局类型
 	ElementData string `json:"element_data"` // JSON格式存储元素信息
 	Tags        string `json:"tags"`
+
+	GradingConfig *entity.GradingConfig `json:"grading_config"` // 判分配置（容差、是否要求严格形式）
+
+	// 逐级提示，JSON数组，每项为 {"type":"text|image|audio","content":"..."}，也可以是文字提示的字符串数组
+	Hints string `json:"hints"`
 }
 
 type UpdateQuestionRequest struct {
@@ -35,6 +44,14 @@ type UpdateQuestionRequest struct {
 	LayoutType  string `json:"layout_type"`  // 布局类型
 	ElementData string `json:"element_data"` // JSON格式存储元素信息
 	Tags        string `json:"tags"`
+
+	GradingConfig *entity.GradingConfig `json:"grading_config"` // 判分配置（容差、是否要求严格形式）
+	Regrade       bool                  `json:"regrade"`        // 标准答案或判分配置变化时在后台重新判定历史答题记录
+
+	RevisionComment string `json:"revision_comment"` // 修改说明，记录在修订历史中
+
+	// 逐级提示，格式同 CreateQuestionRequest.Hints，未提供时保留原有提示
+	Hints *string `json:"hints"`
 }
 
 type CreatePaperRequest struct {
@@ -48,17 +65,28 @@ type CreatePaperRequest struct {
 	QuestionIDs []uint     `json:"question_ids"`
 	StartTime   *time.Time `json:"start_time"`
 	EndTime     *time.Time `json:"end_time"`
+
+	// 多选题计分规则：all_or_nothing 全对才得分，proportional 按比例得分，penalty 错选倒扣
+	ScoringPolicy entity.ScoringPolicy `json:"scoring_policy" binding:"omitempty,oneof=all_or_nothing proportional penalty"`
 }
 
 type SubmitAnswerRequest struct {
-	PaperID    uint   `json:"paper_id" binding:"required"`
-	QuestionID uint   `json:"question_id" binding:"required"`
-	Answer     string `json:"answer" binding:"required"`
+	PaperID    uint              `json:"paper_id" binding:"required"`
+	QuestionID uint              `json:"question_id" binding:"required"`
+	Answer     string            `json:"answer" binding:"required_without=Blanks"`
+	Blanks     map[string]string `json:"blanks"` // 复杂填空题按填空ID提交的答案
 }
 
 // SingleAnswerRequest 单题答题请求
 type SingleAnswerRequest struct {
-	Answer string `json:"answer" binding:"required"`
+	Answer string            `json:"answer" binding:"required_without=Blanks"`
+	Blanks map[string]string `json:"blanks"` // 复杂填空题按填空ID提交的答案
+}
+
+// RevealHintRequest 查看下一条提示请求，在试卷或作业中作答时提供对应的ID，都不提供时为单题练习
+type RevealHintRequest struct {
+	PaperID    uint `json:"paper_id"`
+	HomeworkID uint `json:"homework_id"`
 }
 
 // RandomQuestionRequest 随机获取题目请求
@@ -86,6 +114,8 @@ type ImportQuestionData struct {
 	Tags         string   `json:"tags"`
 	Status       string   `json:"status"`        // pending, approved, rejected
 	ErrorMessage string   `json:"error_message"` // 错误信息
+
+	GradingConfig *entity.GradingConfig `json:"grading_config"` // 判分配置（可接受的其他答案）
 }
 
 // ConfirmImportRequest 确认导入请求
@@ -94,6 +124,18 @@ type ConfirmImportRequest struct {
 	Questions []ImportQuestionData `json:"questions" binding:"required"`
 }
 
+// ReviewImportItemsRequest 批量设置导入预览题目状态请求
+type ReviewImportItemsRequest struct {
+	ItemIDs []uint `json:"item_ids"` // 为空时处理任务中全部待核对的题目
+	Status  string `json:"status" binding:"required,oneof=pending approved rejected"`
+}
+
+// AcceptCalibrationRequest 接受难度校准请求，将题目难度改为校准建议的难度
+type AcceptCalibrationRequest struct {
+	QuestionIDs    []uint `json:"question_ids"`    // 为空时处理本次校准中全部建议难度与当前难度不同的题目
+	IncludeExtreme bool   `json:"include_extreme"` // 未指定题目时是否包括全部答对或全部答错的题目
+}
+
 // BatchUpdateQuestionsRequest 批量编辑题目请求
 type BatchUpdateQuestionsRequest struct {
 	IDs     []uint                     `json:"ids" binding:"required,min=1"`
@@ -107,3 +149,26 @@ type BatchUpdateQuestionFields struct {
 	Topic      string `json:"topic"`
 	Difficulty string `json:"difficulty"`
 }
+
+// QuestionReviewRequest 题目审核操作请求
+type QuestionReviewRequest struct {
+	ReviewerID *uint  `json:"reviewer_id"` // 提交审核或指定审核人时使用
+	Comment    string `json:"comment"`     // 审核意见，驳回时必填
+}
+
+// TagRequest 创建或重命名标签请求
+type TagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// MergeTagsRequest 合并标签请求，源标签下的题目全部改为目标标签，之后删除源标签
+type MergeTagsRequest struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+	TargetID  uint   `json:"target_id" binding:"required"`
+}
+
+// MergeDuplicatesRequest 合并重复题目请求，重复题目的答题记录、作业和试卷引用改为指向保留的题目
+type MergeDuplicatesRequest struct {
+	SurvivorID   uint   `json:"survivor_id" binding:"required"`
+	DuplicateIDs []uint `json:"duplicate_ids" binding:"required,min=1"`
+}
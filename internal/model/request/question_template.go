@@ -0,0 +1,34 @@
+package request
+
+import "testogo/internal/model/entity"
+
+// QuestionTemplateRequest 创建/更新题目模板请求
+type QuestionTemplateRequest struct {
+	Title       string                    `json:"title" binding:"required"`
+	Stem        string                    `json:"stem" binding:"required"`       // 题干模板，如 "{a} + {b} = ?"
+	Expression  string                    `json:"expression" binding:"required"` // 答案算式，如 "a+b"
+	Variables   []entity.TemplateVariable `json:"variables" binding:"required,min=1"`
+	Constraints []string                  `json:"constraints"` // no_carry, no_borrow, integer_result, non_negative 或 "a>b" 形式的比较
+	Grade       string                    `json:"grade"`
+	Subject     string                    `json:"subject"`
+	Topic       string                    `json:"topic"`
+	Difficulty  int                       `json:"difficulty" binding:"omitempty,min=1,max=5"`
+}
+
+// InstantiateTemplateRequest 按模板生成题目请求
+type InstantiateTemplateRequest struct {
+	Seed  *int64 `json:"seed"`                                   // 起始种子，为空时随机；第 i 道题使用 seed+i
+	Count int    `json:"count" binding:"omitempty,min=1,max=50"` // 生成数量，默认1
+}
+
+// GenerateSequenceRequest 生成数字序列推理题请求
+type GenerateSequenceRequest struct {
+	Kind       string `json:"kind" binding:"required,oneof=arithmetic geometric alternating repeating"`
+	Difficulty int    `json:"difficulty" binding:"required,min=1,max=5"`
+	Seed       *int64 `json:"seed"`                                   // 起始种子，为空时随机；第 i 道题使用 seed+i
+	Count      int    `json:"count" binding:"omitempty,min=1,max=50"` // 生成数量，默认1
+	Grade      string `json:"grade"`
+	Subject    string `json:"subject"`
+	Topic      string `json:"topic"`
+	Preview    bool   `json:"preview"` // 仅预览，不保存
+}
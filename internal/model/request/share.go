@@ -0,0 +1,13 @@
+package request
+
+// UpdateSharingRequest 修改共享设置请求，共享成员整体替换
+type UpdateSharingRequest struct {
+	Visibility string               `json:"visibility" binding:"required,oneof=private shared school"`
+	Shares     []ShareMemberRequest `json:"shares" binding:"omitempty,dive"` // 私有资源必须为空
+}
+
+// ShareMemberRequest 一名共享成员
+type ShareMemberRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"omitempty,oneof=viewer co_editor"` // 默认 viewer
+}
@@ -1,6 +1,10 @@
 package response
 
-import "time"
+import (
+	"time"
+
+	"testogo/internal/model/entity"
+)
 
 // QuestionResponse 题目响应
 type QuestionResponse struct {
@@ -19,11 +23,44 @@ type QuestionResponse struct {
 
 // QuestionAnswerResponse 单题答题响应
 type QuestionAnswerResponse struct {
-	QuestionID  uint      `json:"question_id"`
-	UserAnswer  string    `json:"user_answer"`
-	IsCorrect   bool      `json:"is_correct"`
-	Explanation string    `json:"explanation,omitempty"` // 答案解释
-	AnsweredAt  time.Time `json:"answered_at"`
+	QuestionID  uint                 `json:"question_id"`
+	UserAnswer  string               `json:"user_answer"`
+	IsCorrect   bool                 `json:"is_correct"`
+	Score       float64              `json:"score"`                 // 得分率，0-1
+	Points      float64              `json:"points"`                // 实际得分
+	Parts       []AnswerPartResponse `json:"parts,omitempty"`       // 分项判分结果
+	Explanation string               `json:"explanation,omitempty"` // 答案解释
+	AnsweredAt  time.Time            `json:"answered_at"`
+
+	MatchedRule   string `json:"matched_rule,omitempty"`   // 命中的答案匹配规则
+	MatchedAnswer string `json:"matched_answer,omitempty"` // 命中的标准答案或正则表达式
+
+	HintsUsed int `json:"hints_used"` // 作答前查看的提示条数
+}
+
+// AnswerPartResponse 分项判分结果（如每个填空的对错）
+type AnswerPartResponse struct {
+	ID        string  `json:"id"`
+	Answer    string  `json:"answer"`
+	IsCorrect bool    `json:"is_correct"`
+	Score     float64 `json:"score"`
+	Feedback  string  `json:"feedback,omitempty"`
+
+	MatchedRule string `json:"matched_rule,omitempty"` // 命中的答案匹配规则
+}
+
+// GradedAnswerResponse 试卷/作业提交后单题的判分结果
+type GradedAnswerResponse struct {
+	QuestionID uint                 `json:"question_id"`
+	IsCorrect  bool                 `json:"is_correct"`
+	Score      float64              `json:"score"`  // 得分率，0-1
+	Points     float64              `json:"points"` // 实际得分
+	Parts      []AnswerPartResponse `json:"parts,omitempty"`
+
+	MatchedRule   string `json:"matched_rule,omitempty"`   // 命中的答案匹配规则
+	MatchedAnswer string `json:"matched_answer,omitempty"` // 命中的标准答案或正则表达式
+
+	HintsUsed int `json:"hints_used"` // 作答前查看的提示条数
 }
 
 // UserAnswerHistoryResponse 用户答题历史响应
@@ -45,6 +82,50 @@ type QuestionStatisticsResponse struct {
 	TotalAttempts int64   `json:"total_attempts"`
 	CorrectCount  int64   `json:"correct_count"`
 	AccuracyRate  float64 `json:"accuracy_rate"`
+
+	// 答对的记录中独立完成和查看过提示的数量
+	UnaidedCorrectCount int64 `json:"unaided_correct_count"`
+	HintedCorrectCount  int64 `json:"hinted_correct_count"`
+}
+
+// QuestionAnalysisResponse 题目分析响应，基于试卷和作业中的作答计算，单题练习没有测验总分不参与分析
+type QuestionAnalysisResponse struct {
+	QuestionID        uint    `json:"question_id"`
+	Title             string  `json:"title"`
+	Type              string  `json:"type"`
+	Responses         int     `json:"responses"`          // 参与分析的作答数
+	PaperResponses    int     `json:"paper_responses"`    // 其中试卷作答数，每名学生每份试卷只计首次作答
+	HomeworkResponses int     `json:"homework_responses"` // 其中作业作答数
+	PValue            float64 `json:"p_value"`            // 难度，即答对比例
+	PointBiserial     float64 `json:"point_biserial"`     // 本题对错与其余题目总分的相关系数
+	Discrimination    float64 `json:"discrimination"`     // 高分组（前27%）答对比例减低分组（后27%）答对比例
+	UpperCount        int     `json:"upper_count"`
+	LowerCount        int     `json:"lower_count"`
+
+	Options    []OptionAnalysisResponse `json:"options,omitempty"` // 选择题的选项分析
+	Flags      []string                 `json:"flags"`             // negative_discrimination, non_functioning_distractor
+	Sufficient bool                     `json:"sufficient"`        // 作答数达到 min_responses 时才给出问题标记
+}
+
+// OptionAnalysisResponse 选项的选择情况
+type OptionAnalysisResponse struct {
+	Letter         string  `json:"letter"`
+	Text           string  `json:"text"`
+	Correct        bool    `json:"correct"`
+	Count          int     `json:"count"`
+	Rate           float64 `json:"rate"`            // 选择率
+	UpperRate      float64 `json:"upper_rate"`      // 高分组的选择率
+	LowerRate      float64 `json:"lower_rate"`      // 低分组的选择率
+	NonFunctioning bool    `json:"non_functioning"` // 错误选项且几乎没有人选择
+}
+
+// HintResponse 查看提示响应
+type HintResponse struct {
+	QuestionID uint   `json:"question_id"`
+	Level      int    `json:"level"` // 第几条提示，从1开始
+	Total      int    `json:"total"` // 提示总条数
+	Type       string `json:"type"`  // text, image, audio
+	Content    string `json:"content"`
 }
 
 // QuestionWithStatsResponse 带统计数据的题目响应（用于列表）
@@ -67,7 +148,122 @@ type QuestionWithStatsResponse struct {
 	Tags        string    `json:"tags"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// 判分配置
+	GradingConfig string `json:"grading_config"`
+	// 审核状态
+	Status string `json:"status"`
+	// 全文检索相关度和高亮摘要，仅在按关键词搜索时返回
+	SearchScore float64           `json:"search_score,omitempty"`
+	Highlights  map[string]string `json:"highlights,omitempty"`
 	// 统计字段
 	UsageCount   int64   `json:"usageCount"`   // 使用次数（总答题次数）
 	CorrectRate  float64 `json:"correctRate"`  // 答对率
-}
\ No newline at end of file
+}
+// RegradeSummaryResponse 重新判分结果汇总
+type RegradeSummaryResponse struct {
+	QuestionID             uint                    `json:"question_id"`
+	DryRun                 bool                    `json:"dry_run"`                  // 仅预览，未写入数据库
+	UserAnswersChecked     int                     `json:"user_answers_checked"`     // 检查的练习/试卷答题记录数
+	UserAnswersChanged     int                     `json:"user_answers_changed"`     // 判分结果发生变化的记录数
+	HomeworkAnswersChecked int                     `json:"homework_answers_checked"` // 检查的作业答题记录数
+	HomeworkAnswersChanged int                     `json:"homework_answers_changed"` // 判分结果发生变化的作业答题记录数
+	SubmissionsUpdated     int                     `json:"submissions_updated"`      // 重新计算成绩的作业提交数
+	PerformancesUpdated    int                     `json:"performances_updated"`     // 更新的学习表现统计数
+	Changes                []RegradeChangeResponse `json:"changes"`                  // 发生变化的答题记录
+}
+
+// RegradeChangeResponse 单条答题记录的判分变化
+type RegradeChangeResponse struct {
+	Source       string  `json:"source"` // user_answer, homework_answer
+	ID           uint    `json:"id"`
+	UserID       uint    `json:"user_id"`
+	SubmissionID uint    `json:"submission_id,omitempty"`
+	WasCorrect   bool    `json:"was_correct"`
+	IsCorrect    bool    `json:"is_correct"`
+	OldScore     float64 `json:"old_score"`
+	NewScore     float64 `json:"new_score"`
+}
+
+// QuestionRevisionResponse 题目修订记录响应
+type QuestionRevisionResponse struct {
+	ID         uint                    `json:"id"`
+	QuestionID uint                    `json:"question_id"`
+	Revision   int                     `json:"revision"`
+	EditorID   uint                    `json:"editor_id"`
+	EditorName string                  `json:"editor_name,omitempty"`
+	Comment    string                  `json:"comment"`
+	Snapshot   entity.QuestionSnapshot `json:"snapshot"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+// QuestionRevisionDiffResponse 两个版本之间的差异
+type QuestionRevisionDiffResponse struct {
+	QuestionID uint                  `json:"question_id"`
+	From       int                   `json:"from"`
+	To         int                   `json:"to"`
+	Changes    []FieldChangeResponse `json:"changes"`
+}
+
+// FieldChangeResponse 单个字段的变化
+type FieldChangeResponse struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// QuestionReviewResponse 题目审核记录响应
+type QuestionReviewResponse struct {
+	ID         uint      `json:"id"`
+	QuestionID uint      `json:"question_id"`
+	UserID     uint      `json:"user_id"`
+	UserName   string    `json:"user_name,omitempty"`
+	Action     string    `json:"action"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ReviewerID *uint     `json:"reviewer_id,omitempty"`
+	Comment    string    `json:"comment"`
+	Revision   int       `json:"revision"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TagResponse 标签响应
+type TagResponse struct {
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	QuestionCount int64     `json:"question_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// DuplicateMatchResponse 疑似重复的题目
+type DuplicateMatchResponse struct {
+	QuestionID uint    `json:"question_id"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"` // 相似度，0-1
+	Exact      bool    `json:"exact"`      // 内容和媒体完全相同
+}
+
+// DuplicateClusterResponse 一组相互疑似重复的题目
+type DuplicateClusterResponse struct {
+	SurvivorID uint                        `json:"survivor_id"` // 建议保留的题目
+	Questions  []DuplicateQuestionResponse `json:"questions"`
+}
+
+// DuplicateQuestionResponse 重复分组中的题目
+type DuplicateQuestionResponse struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	AnswerCount int64     `json:"answer_count"`
+	Similarity  float64   `json:"similarity"` // 与建议保留题目的相似度
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CalibrationItemResponse 难度校准结果中的一道题，与题目当前的难度对照显示
+type CalibrationItemResponse struct {
+	entity.ItemCalibration
+	Title             string `json:"title"`
+	Type              string `json:"type"`
+	CurrentDifficulty int    `json:"current_difficulty"` // 题目当前的难度
+	Changed           bool   `json:"changed"`            // 建议难度与当前难度不同
+}
@@ -0,0 +1,37 @@
+package response
+
+import (
+	"time"
+
+	"testogo/internal/model/entity"
+)
+
+// QuestionTemplateResponse 题目模板响应
+type QuestionTemplateResponse struct {
+	ID          uint                      `json:"id"`
+	Title       string                    `json:"title"`
+	Stem        string                    `json:"stem"`
+	Expression  string                    `json:"expression"`
+	Variables   []entity.TemplateVariable `json:"variables"`
+	Constraints []string                  `json:"constraints"`
+	Grade       string                    `json:"grade"`
+	Subject     string                    `json:"subject"`
+	Topic       string                    `json:"topic"`
+	Difficulty  int                       `json:"difficulty"`
+	CreatorID   uint                      `json:"creator_id"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	UpdatedAt   time.Time                 `json:"updated_at"`
+
+	// 审核状态，已发布的模板生成的题目直接发布
+	Status        string `json:"status"`
+	ReviewComment string `json:"review_comment"`
+}
+
+// TemplateVariantResponse 模板生成的题目
+type TemplateVariantResponse struct {
+	QuestionID uint           `json:"question_id,omitempty"` // 预览时为空
+	Seed       int64          `json:"seed"`
+	Values     map[string]int `json:"values"` // 各变量的取值
+	Title      string         `json:"title"`
+	Answer     string         `json:"answer"`
+}
@@ -0,0 +1,22 @@
+package response
+
+import "time"
+
+// SharingResponse 资源的共享设置
+type SharingResponse struct {
+	ResourceType string                `json:"resource_type"`
+	ResourceID   uint                  `json:"resource_id"`
+	CreatorID    uint                  `json:"creator_id"`
+	Visibility   string                `json:"visibility"`
+	Access       string                `json:"access"` // 当前用户的权限：view, edit, owner
+	Shares       []ShareMemberResponse `json:"shares"`
+}
+
+// ShareMemberResponse 一名共享成员
+type ShareMemberResponse struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
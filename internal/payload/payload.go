@@ -0,0 +1,441 @@
+// Package payload 校验并规范化题目中以JSON文本保存的字段（选项、媒体地址、元素数据、提示）
+// 每种题型有各自的结构：选择题选项为 []entity.QuestionOption，复杂填空题元素数据为 entity.ComplexQuestionData，
+// 比较题为 entity.ComparisonData，推理题为 entity.SequenceData；校验失败时返回字段级错误
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+)
+
+// FieldError 字段级校验错误，Field 为JSON路径，如 options[1].text、element_data.elements[0].count
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors 一道题的全部校验错误
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Field + ": " + err.Message
+	}
+	return strings.Join(messages, "；")
+}
+
+func (e *Errors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Payload 题目中按题型约束的字段，Options、MediaURLs、ElementData、Hints 为数据库中保存的JSON文本
+type Payload struct {
+	Type        entity.QuestionType
+	Options     string
+	Answer      string
+	MediaURLs   string
+	ElementData string
+	Hints       string
+}
+
+// MaxHints 一道题最多的提示条数
+const MaxHints = 10
+
+// Normalize 校验题目字段，并将兼容格式转换为规范格式：
+// 选项的字符串数组转换为 QuestionOption 数组，空数组保存为空字符串，JSON统一为紧凑格式
+func Normalize(p Payload) (Payload, Errors) {
+	var errs Errors
+	if !entity.IsValidQuestionType(p.Type) {
+		errs.add("type", "未知题型: %s", p.Type)
+		return p, errs
+	}
+
+	options, ok := normalizeOptions(p.Options, &errs)
+	if ok {
+		p.Options = encodeOptions(options)
+	}
+	if urls, ok := normalizeMediaURLs(p.MediaURLs, &errs); ok {
+		p.MediaURLs = encodeList(urls)
+	}
+	if data, ok := normalizeElementData(p.Type, p.ElementData, &errs); ok {
+		p.ElementData = data
+	}
+	if hints, ok := normalizeHints(p.Hints, &errs); ok {
+		p.Hints = encodeHints(hints)
+	}
+	validateAnswer(p, options, &errs)
+	return p, errs
+}
+
+// NormalizeLegacy 与 Normalize 相同，另外接受早期导入写入的逗号分隔的选项和媒体地址
+// 仅用于迁移已有数据，选项内容本身含有逗号时无法还原
+func NormalizeLegacy(p Payload) (Payload, Errors) {
+	p.Options = legacyList(p.Options)
+	p.MediaURLs = legacyList(p.MediaURLs)
+	return Normalize(p)
+}
+
+// legacyList 逗号分隔的文本转换为JSON字符串数组，已经是JSON数组的原样返回
+func legacyList(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.HasPrefix(raw, "[") {
+		return raw
+	}
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+// normalizeOptions 解析选项，兼容字符串数组和 QuestionOption 数组
+func normalizeOptions(raw string, errs *Errors) ([]entity.QuestionOption, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, true
+	}
+
+	var texts []string
+	if json.Unmarshal([]byte(raw), &texts) == nil {
+		// 字符串数组转换为选项结构，选项值为选项字母
+		options := make([]entity.QuestionOption, len(texts))
+		for i, text := range texts {
+			options[i] = entity.QuestionOption{Text: text, Value: letter(i)}
+		}
+		return options, validateOptions(options, errs)
+	}
+
+	var options []entity.QuestionOption
+	if err := json.Unmarshal([]byte(raw), &options); err != nil {
+		errs.add("options", "必须是选项数组，每项包含 text、image_url、value: %v", err)
+		return nil, false
+	}
+	return options, validateOptions(options, errs)
+}
+
+func validateOptions(options []entity.QuestionOption, errs *Errors) bool {
+	before := len(*errs)
+	if len(options) > 26 {
+		errs.add("options", "选项不能超过26个")
+	}
+	for i := range options {
+		options[i].Text = strings.TrimSpace(options[i].Text)
+		options[i].ImageURL = strings.TrimSpace(options[i].ImageURL)
+		options[i].Value = strings.TrimSpace(options[i].Value)
+		if options[i].Text == "" && options[i].ImageURL == "" {
+			errs.add(fmt.Sprintf("options[%d]", i), "选项需要文字或图片")
+		}
+	}
+	return len(*errs) == before
+}
+
+// normalizeMediaURLs 媒体地址必须是字符串数组
+func normalizeMediaURLs(raw string, errs *Errors) ([]string, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, true
+	}
+	var urls []string
+	if err := json.Unmarshal([]byte(raw), &urls); err != nil {
+		errs.add("media_urls", "必须是字符串数组")
+		return nil, false
+	}
+	ok := true
+	for i := range urls {
+		if urls[i] = strings.TrimSpace(urls[i]); urls[i] == "" {
+			errs.add(fmt.Sprintf("media_urls[%d]", i), "地址不能为空")
+			ok = false
+		}
+	}
+	return urls, ok
+}
+
+// normalizeHints 解析提示，兼容字符串数组（均为文字提示）和 QuestionHint 数组
+func normalizeHints(raw string, errs *Errors) ([]entity.QuestionHint, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, true
+	}
+
+	var hints []entity.QuestionHint
+	var texts []string
+	if json.Unmarshal([]byte(raw), &texts) == nil {
+		hints = make([]entity.QuestionHint, len(texts))
+		for i, text := range texts {
+			hints[i] = entity.QuestionHint{Type: entity.HintText, Content: text}
+		}
+	} else if err := json.Unmarshal([]byte(raw), &hints); err != nil {
+		errs.add("hints", "必须是提示数组，每项包含 type、content: %v", err)
+		return nil, false
+	}
+
+	before := len(*errs)
+	if len(hints) > MaxHints {
+		errs.add("hints", "提示不能超过%d条", MaxHints)
+	}
+	for i := range hints {
+		field := fmt.Sprintf("hints[%d]", i)
+		hints[i].Type = strings.TrimSpace(hints[i].Type)
+		hints[i].Content = strings.TrimSpace(hints[i].Content)
+		switch hints[i].Type {
+		case "":
+			hints[i].Type = entity.HintText
+		case entity.HintText, entity.HintImage, entity.HintAudio:
+		default:
+			errs.add(field+".type", "必须为 text、image 或 audio")
+		}
+		if hints[i].Content == "" {
+			errs.add(field+".content", "不能为空")
+		}
+	}
+	return hints, len(*errs) == before
+}
+
+// normalizeElementData 按题型校验元素数据，没有专用结构的题型只要求是JSON
+func normalizeElementData(questionType entity.QuestionType, raw string, errs *Errors) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		if questionType == entity.TypeComparison {
+			errs.add("element_data", "比较题需要元素数据")
+			return "", false
+		}
+		return "", true
+	}
+
+	if !json.Valid([]byte(raw)) {
+		errs.add("element_data", "必须是JSON")
+		return raw, false
+	}
+	ok := true
+	switch questionType {
+	case entity.TypeFillIn:
+		data := &entity.ComplexQuestionData{}
+		ok = decodeElementData(raw, data, errs) && validateComplexData(data, errs)
+	case entity.TypeComparison:
+		data := &entity.ComparisonData{}
+		ok = decodeElementData(raw, data, errs) && validateComparisonData(data, errs)
+	case entity.TypeReasoning:
+		data := &entity.SequenceData{}
+		ok = decodeElementData(raw, data, errs) && validateSequenceData(data, errs)
+	}
+	// 保留原始内容，前端附加的字段不会丢失
+	return compactJSON(raw), ok
+}
+
+// decodeElementData 按题型的结构解析元素数据
+func decodeElementData(raw string, v interface{}, errs *Errors) bool {
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		errs.add("element_data", "格式错误: %v", err)
+		return false
+	}
+	return true
+}
+
+// validateComplexData 复杂填空题：子题的填空ID在整道题中唯一，题干中的填空必须对应已定义的填空
+func validateComplexData(data *entity.ComplexQuestionData, errs *Errors) bool {
+	before := len(*errs)
+	if data.Type != "" && data.Type != "complex" {
+		errs.add("element_data.type", "必须为 complex")
+	}
+	if data.HasMainImage && strings.TrimSpace(data.MainImageURL) == "" {
+		errs.add("element_data.main_image_url", "has_main_image 为 true 时不能为空")
+	}
+	if len(data.SubQuestions) == 0 {
+		errs.add("element_data.sub_questions", "至少需要一道子题")
+	}
+
+	blankIDs := make(map[string]bool)
+	for i, sub := range data.SubQuestions {
+		prefix := fmt.Sprintf("element_data.sub_questions[%d]", i)
+		if strings.TrimSpace(sub.ID) == "" {
+			errs.add(prefix+".id", "不能为空")
+		}
+		if len(sub.Blanks) == 0 {
+			errs.add(prefix+".blanks", "至少需要一个填空")
+		}
+		for j, blank := range sub.Blanks {
+			field := fmt.Sprintf("%s.blanks[%d]", prefix, j)
+			switch {
+			case strings.TrimSpace(blank.ID) == "":
+				errs.add(field+".id", "不能为空")
+			case blankIDs[blank.ID]:
+				errs.add(field+".id", "填空ID重复: %s", blank.ID)
+			}
+			blankIDs[blank.ID] = true
+			if strings.TrimSpace(blank.Answer) == "" {
+				errs.add(field+".answer", "不能为空")
+			}
+		}
+	}
+	for i, sub := range data.SubQuestions {
+		for j, segment := range sub.Content {
+			field := fmt.Sprintf("element_data.sub_questions[%d].content[%d]", i, j)
+			switch segment.Type {
+			case "text", "image":
+				if strings.TrimSpace(segment.Content) == "" {
+					errs.add(field+".content", "不能为空")
+				}
+			case "blank":
+				if !blankIDs[segment.BlankID] {
+					errs.add(field+".blank_id", "没有对应的填空: %s", segment.BlankID)
+				}
+			default:
+				errs.add(field+".type", "必须为 text、image 或 blank")
+			}
+		}
+	}
+	return len(*errs) == before
+}
+
+// validateComparisonData 比较题：两个元素，数量不能为负
+func validateComparisonData(data *entity.ComparisonData, errs *Errors) bool {
+	before := len(*errs)
+	if len(data.Elements) != 2 {
+		errs.add("element_data.elements", "需要两个比较的元素")
+	}
+	for i, element := range data.Elements {
+		field := fmt.Sprintf("element_data.elements[%d]", i)
+		if strings.TrimSpace(element.Name) == "" {
+			errs.add(field+".name", "不能为空")
+		}
+		if element.Count < 0 {
+			errs.add(field+".count", "不能为负数")
+		}
+	}
+	switch data.QuestionType {
+	case "", "quantity", "size", "length":
+	default:
+		errs.add("element_data.question_type", "必须为 quantity、size 或 length")
+	}
+	return len(*errs) == before
+}
+
+// validateSequenceData 推理题：缺失项的下标必须在数列范围内且不重复
+func validateSequenceData(data *entity.SequenceData, errs *Errors) bool {
+	before := len(*errs)
+	switch data.Kind {
+	case "", entity.SequenceArithmetic, entity.SequenceGeometric, entity.SequenceAlternating, entity.SequenceRepeating:
+	default:
+		errs.add("element_data.kind", "未知的数列规律: %s", data.Kind)
+	}
+	if len(data.Terms) == 0 {
+		errs.add("element_data.terms", "不能为空")
+	}
+	if len(data.Missing) == 0 {
+		errs.add("element_data.missing", "至少需要一个缺失项")
+	}
+	seen := make(map[int]bool)
+	for i, index := range data.Missing {
+		field := fmt.Sprintf("element_data.missing[%d]", i)
+		switch {
+		case index < 0 || index >= len(data.Terms):
+			errs.add(field, "下标超出数列范围")
+		case seen[index]:
+			errs.add(field, "下标重复")
+		}
+		seen[index] = true
+	}
+	return len(*errs) == before
+}
+
+// validateAnswer 选择题答案必须对应选项，判断题答案必须是对错，其余题型在没有题目数据推导答案时必须有答案
+func validateAnswer(p Payload, options []entity.QuestionOption, errs *Errors) {
+	answer := strings.TrimSpace(p.Answer)
+	switch p.Type {
+	case entity.TypeChoice, entity.TypeMultiChoice:
+		if len(options) < 2 {
+			errs.add("options", "选择题至少需要两个选项")
+			return
+		}
+		if answer == "" {
+			errs.add("answer", "不能为空")
+			return
+		}
+		texts := grading.ParseOptionTexts(encodeOptions(options))
+		keys := grading.ParseChoiceSet(answer, texts)
+		for _, key := range keys {
+			key = strings.ToUpper(key)
+			if index := letterIndex(key); index < 0 || index >= len(options) {
+				errs.add("answer", "答案 %s 不在选项范围内", key)
+				return
+			}
+		}
+		if p.Type == entity.TypeChoice && len(keys) != 1 {
+			errs.add("answer", "单选题只能有一个答案")
+		}
+	case entity.TypeJudge:
+		if normalized := grading.NormalizeJudgeAnswer(answer); normalized != "true" && normalized != "false" {
+			errs.add("answer", "判断题答案必须为 对 或 错")
+		}
+	case entity.TypeComparison, entity.TypeVisual:
+		// 比较题答案由元素数据推导，图片题可以没有标准答案
+	case entity.TypeFillIn, entity.TypeReasoning:
+		if answer == "" && strings.TrimSpace(p.ElementData) == "" {
+			errs.add("answer", "不能为空")
+		}
+	default:
+		if answer == "" {
+			errs.add("answer", "不能为空")
+		}
+	}
+}
+
+func encodeOptions(options []entity.QuestionOption) string {
+	if len(options) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(options)
+	return string(data)
+}
+
+func encodeHints(hints []entity.QuestionHint) string {
+	if len(hints) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(hints)
+	return string(data)
+}
+
+// ParseHints 解析已保存的提示，格式错误时返回空
+func ParseHints(raw string) []entity.QuestionHint {
+	var hints []entity.QuestionHint
+	if strings.TrimSpace(raw) == "" || json.Unmarshal([]byte(raw), &hints) != nil {
+		return nil
+	}
+	return hints
+}
+
+func encodeList(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+func compactJSON(raw string) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(raw)); err != nil {
+		return raw
+	}
+	return b.String()
+}
+
+// letter 选项下标对应的字母
+func letter(index int) string {
+	return string(rune('A' + index))
+}
+
+// letterIndex 选项字母对应的下标，不是字母时返回-1
+func letterIndex(s string) int {
+	if len(s) != 1 || s[0] < 'A' || s[0] > 'Z' {
+		return -1
+	}
+	return int(s[0] - 'A')
+}
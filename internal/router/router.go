@@ -29,16 +29,88 @@ func InitRouter(r *gin.Engine) {
 			questions.GET("", controller.ListQuestions)
 			questions.GET("/:id", controller.GetQuestion)
 			questions.GET("/:id/statistics", controller.GetQuestionStatistics)
+			questions.GET("/:id/analysis", middleware.RoleMiddleware("teacher", "admin"), controller.GetQuestionAnalysis)
 			questions.POST("", middleware.RoleMiddleware("teacher", "admin"), controller.CreateQuestion)
 			questions.POST("/:id/answer", controller.AnswerQuestion)
+			questions.POST("/:id/hints/next", controller.RevealQuestionHint)
 			questions.PUT("/:id", middleware.RoleMiddleware("teacher", "admin"), controller.UpdateQuestion)
+			questions.POST("/:id/regrade", middleware.RoleMiddleware("teacher", "admin"), controller.RegradeQuestion)
+			questions.GET("/:id/regrade/jobs", middleware.RoleMiddleware("teacher", "admin"), controller.ListRegradeJobs)
+			questions.GET("/:id/regrade/jobs/:jobId", middleware.RoleMiddleware("teacher", "admin"), controller.GetRegradeJob)
 			questions.DELETE("/:id", middleware.RoleMiddleware("teacher", "admin"), controller.DeleteQuestion)
 
+			// 审核流程
+			questions.GET("/review-queue", middleware.RoleMiddleware("teacher", "admin"), controller.ListReviewQueue)
+			questions.GET("/:id/reviews", middleware.RoleMiddleware("teacher", "admin"), controller.ListQuestionReviews)
+			questions.POST("/:id/review/:action", middleware.RoleMiddleware("teacher", "admin"), controller.ReviewQuestion)
+
+			// 修订历史
+			questions.GET("/:id/revisions", middleware.RoleMiddleware("teacher", "admin"), controller.ListQuestionRevisions)
+			questions.GET("/:id/revisions/diff", middleware.RoleMiddleware("teacher", "admin"), controller.DiffQuestionRevisions)
+			questions.GET("/:id/revisions/:revision", middleware.RoleMiddleware("teacher", "admin"), controller.GetQuestionRevision)
+			questions.POST("/:id/revisions/:revision/restore", middleware.RoleMiddleware("teacher", "admin"), controller.RestoreQuestionRevision)
+
+			// 重复题目
+			questions.GET("/duplicates", middleware.RoleMiddleware("admin"), controller.ListDuplicateQuestions)
+			questions.POST("/duplicates/merge", middleware.RoleMiddleware("admin"), controller.MergeDuplicateQuestions)
+
+			// 全文检索
+			questions.POST("/search/reindex", middleware.RoleMiddleware("admin"), controller.RebuildSearchIndex)
+
+			// 生成数字序列推理题
+			questions.POST("/sequences", middleware.RoleMiddleware("teacher", "admin"), controller.GenerateSequenceQuestions)
+
 			// 批量操作
 			questions.PUT("/batch", middleware.RoleMiddleware("teacher", "admin"), controller.BatchUpdateQuestions)
 
 			// 导入导出
 			questions.POST("/import", middleware.RoleMiddleware("teacher", "admin"), controller.ImportQuestionsJSON)
+			questions.GET("/export", middleware.RoleMiddleware("teacher", "admin"), controller.ExportQuestions)
+		}
+
+		// 难度校准路由
+		calibrations := protected.Group("/calibrations")
+		calibrations.Use(middleware.RoleMiddleware("teacher", "admin"))
+		{
+			calibrations.GET("", controller.ListCalibrations)
+			calibrations.POST("", controller.StartCalibration)
+			calibrations.GET("/:id", controller.GetCalibration)
+			calibrations.GET("/:id/items", controller.ListCalibrationItems)
+			calibrations.GET("/:id/learners", controller.ListCalibrationLearners)
+			calibrations.POST("/:id/accept", controller.AcceptCalibration)
+		}
+
+		// 共享设置路由，resource 为 questions、papers、homework 或 reinforcement-settings
+		shares := protected.Group("/shares")
+		shares.Use(middleware.RoleMiddleware("teacher", "admin"))
+		{
+			shares.GET("/:resource/:id", controller.GetSharing)
+			shares.PUT("/:resource/:id", controller.UpdateSharing)
+		}
+
+		// 标签路由
+		tags := protected.Group("/tags")
+		{
+			tags.GET("", controller.ListTags)
+			tags.POST("", middleware.RoleMiddleware("teacher", "admin"), controller.CreateTag)
+			// 重命名、合并和删除标签会修改所有教师的题目，只有管理员可以操作
+			tags.POST("/merge", middleware.RoleMiddleware("admin"), controller.MergeTags)
+			tags.PUT("/:id", middleware.RoleMiddleware("admin"), controller.RenameTag)
+			tags.DELETE("/:id", middleware.RoleMiddleware("admin"), controller.DeleteTag)
+		}
+
+		// 题目模板路由
+		templates := protected.Group("/question-templates")
+		{
+			templates.GET("", controller.ListQuestionTemplates)
+			templates.GET("/:id", controller.GetQuestionTemplate)
+			templates.GET("/:id/preview", middleware.RoleMiddleware("teacher", "admin"), controller.PreviewQuestionTemplate)
+			// 学生练习时也可以按已发布的模板生成题目
+			templates.POST("/:id/instantiate", controller.InstantiateQuestionTemplate)
+			templates.POST("/:id/review/:action", middleware.RoleMiddleware("teacher", "admin"), controller.ReviewQuestionTemplate)
+			templates.POST("", middleware.RoleMiddleware("teacher", "admin"), controller.CreateQuestionTemplate)
+			templates.PUT("/:id", middleware.RoleMiddleware("teacher", "admin"), controller.UpdateQuestionTemplate)
+			templates.DELETE("/:id", middleware.RoleMiddleware("teacher", "admin"), controller.DeleteQuestionTemplate)
 		}
 
 		// 试卷相关路由
@@ -161,6 +233,14 @@ func InitRouter(r *gin.Engine) {
 		{
 			importGroup.POST("/questions", middleware.RoleMiddleware("teacher", "admin"), controller.ImportQuestions)
 			importGroup.POST("/questions/confirm", middleware.RoleMiddleware("teacher", "admin"), controller.ConfirmImportQuestions)
+			importGroup.GET("/questions/template", middleware.RoleMiddleware("teacher", "admin"), controller.DownloadImportTemplate)
+			importGroup.GET("/jobs", middleware.RoleMiddleware("teacher", "admin"), controller.ListImportJobs)
+			importGroup.GET("/jobs/:id", middleware.RoleMiddleware("teacher", "admin"), controller.GetImportJob)
+			importGroup.DELETE("/jobs/:id", middleware.RoleMiddleware("teacher", "admin"), controller.DeleteImportJob)
+			importGroup.GET("/jobs/:id/items", middleware.RoleMiddleware("teacher", "admin"), controller.ListImportJobItems)
+			importGroup.POST("/jobs/:id/items/review", middleware.RoleMiddleware("teacher", "admin"), controller.ReviewImportJobItems)
+			importGroup.PUT("/jobs/:id/items/:item_id", middleware.RoleMiddleware("teacher", "admin"), controller.UpdateImportJobItem)
+			importGroup.POST("/jobs/:id/confirm", middleware.RoleMiddleware("teacher", "admin"), controller.ConfirmImportJob)
 		}
 
 		// 静态文件服务
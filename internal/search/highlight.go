@@ -0,0 +1,94 @@
+package search
+
+import (
+	"html"
+	"strings"
+
+	"testogo/internal/model/entity"
+)
+
+// snippetWidth 摘要片段的最大字数，命中位置之前保留 snippetWidth/4 个字作为上下文
+const snippetWidth = 60
+
+// Highlights 为题目中命中检索词的字段生成高亮摘要，键为字段名
+// 命中部分用 <em></em> 包裹，其余文本做HTML转义，片段被截断处以省略号表示
+func Highlights(question *entity.Question, query string) map[string]string {
+	terms := QueryTerms(query)
+	result := make(map[string]string)
+	for _, f := range questionFields(question) {
+		if snippet, ok := highlight(f.text, terms, snippetWidth); ok {
+			result[f.field] = snippet
+		}
+	}
+	return result
+}
+
+// highlight 标出文本中所有检索词，返回以第一个命中位置为中心的片段
+func highlight(text string, terms []string, width int) (string, bool) {
+	original := []rune(text)
+	normalized := make([]rune, len(original))
+	for i, r := range original {
+		normalized[i] = normalizeRune(r)
+	}
+
+	// 标记被检索词覆盖的字符，相邻两字的检索词相互重叠时自然合并为一段
+	marked := make([]bool, len(original))
+	first := -1
+	for _, term := range terms {
+		pattern := []rune(term)
+		for i := 0; i+len(pattern) <= len(normalized); i++ {
+			if !runesEqual(normalized[i:i+len(pattern)], pattern) {
+				continue
+			}
+			for j := i; j < i+len(pattern); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start := first - width/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + width
+	if end > len(original) {
+		end = len(original)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		chunk := html.EscapeString(string(original[i:j]))
+		if marked[i] {
+			b.WriteString("<em>" + chunk + "</em>")
+		} else {
+			b.WriteString(chunk)
+		}
+		i = j
+	}
+	if end < len(original) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
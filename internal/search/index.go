@@ -0,0 +1,145 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"testogo/internal/grading"
+	"testogo/internal/model/entity"
+
+	"gorm.io/gorm"
+)
+
+// 参与检索的题目字段
+const (
+	FieldTitle       = "title"
+	FieldOptions     = "options"
+	FieldExplanation = "explanation"
+	FieldTags        = "tags"
+)
+
+// fieldWeights 各字段命中时的权重，标题命中比解析命中更相关
+var fieldWeights = map[string]float64{
+	FieldTitle:       3,
+	FieldTags:        2,
+	FieldOptions:     1.5,
+	FieldExplanation: 1,
+}
+
+// Hit 一条检索结果
+type Hit struct {
+	QuestionID uint
+	Score      float64
+}
+
+// fieldText 题目某个字段用于检索和生成摘要的文本
+type fieldText struct {
+	field string
+	text  string
+}
+
+// questionFields 按摘要优先级返回题目各字段的文本，选项只取选项内容，不含JSON结构
+func questionFields(question *entity.Question) []fieldText {
+	return []fieldText{
+		{FieldTitle, question.Title},
+		{FieldOptions, strings.Join(grading.ParseOptionTexts(question.Options), "  ")},
+		{FieldExplanation, question.Explanation},
+		{FieldTags, question.Tags},
+	}
+}
+
+// IndexQuestion 重建单个题目的索引，需在保存题目的同一事务中调用
+func IndexQuestion(tx *gorm.DB, question *entity.Question) error {
+	if err := RemoveQuestion(tx, question.ID); err != nil {
+		return err
+	}
+
+	var rows []entity.QuestionSearchTerm
+	for _, f := range questionFields(question) {
+		counts := make(map[string]int)
+		var order []string
+		for _, term := range Tokenize(f.text) {
+			if counts[term] == 0 {
+				order = append(order, term)
+			}
+			counts[term]++
+		}
+		for _, term := range order {
+			rows = append(rows, entity.QuestionSearchTerm{
+				QuestionID: question.ID,
+				Term:       term,
+				Field:      f.field,
+				Frequency:  counts[term],
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+// RemoveQuestion 删除题目的索引
+func RemoveQuestion(tx *gorm.DB, questionID uint) error {
+	return tx.Where("question_id = ?", questionID).Delete(&entity.QuestionSearchTerm{}).Error
+}
+
+// Search 检索包含全部检索词的题目，按相关度从高到低返回至多 limit 条
+// 相关度为各检索词的 IDF 与字段加权词频之积的和，词频做饱和处理，避免重复出现的词过度加分
+func Search(db *gorm.DB, query string, limit int) ([]Hit, error) {
+	terms := QueryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var total int64
+	if err := db.Model(&entity.QuestionSearchTerm{}).Distinct("question_id").Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []entity.QuestionSearchTerm
+	if err := db.Where("term IN ?", terms).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// 统计每个词出现在多少道题目中，以及每道题目各词的加权词频
+	docs := make(map[string]map[uint]bool)
+	weighted := make(map[uint]map[string]float64)
+	for _, row := range rows {
+		if docs[row.Term] == nil {
+			docs[row.Term] = make(map[uint]bool)
+		}
+		docs[row.Term][row.QuestionID] = true
+		if weighted[row.QuestionID] == nil {
+			weighted[row.QuestionID] = make(map[string]float64)
+		}
+		tf := float64(row.Frequency)
+		weighted[row.QuestionID][row.Term] += fieldWeights[row.Field] * tf / (tf + 1.2)
+	}
+
+	var hits []Hit
+	for questionID, termScores := range weighted {
+		if len(termScores) < len(terms) {
+			continue
+		}
+		score := 0.0
+		for term, s := range termScores {
+			df := float64(len(docs[term]))
+			idf := math.Log(1 + (float64(total)-df+0.5)/(df+0.5))
+			score += idf * s
+		}
+		hits = append(hits, Hit{QuestionID: questionID, Score: math.Round(score*1000) / 1000})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].QuestionID > hits[j].QuestionID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,96 @@
+package search
+
+import "unicode"
+
+// maxTermRunes 单个词的最大长度，超出部分截断，与索引表 term 列宽度匹配
+const maxTermRunes = 32
+
+// segment 一段连续的同类字符：汉字串或字母数字串
+type segment struct {
+	runes []rune
+	han   bool
+}
+
+// Tokenize 将文本切分为索引词
+// 连续汉字同时按单字和相邻两字切分，这样单字和多字查询都能命中；字母和数字按词切分并转为小写
+func Tokenize(text string) []string {
+	var terms []string
+	for _, seg := range segments(text) {
+		if !seg.han {
+			terms = append(terms, wordTerm(seg.runes))
+			continue
+		}
+		for i := range seg.runes {
+			terms = append(terms, string(seg.runes[i]))
+			if i+1 < len(seg.runes) {
+				terms = append(terms, string(seg.runes[i:i+2]))
+			}
+		}
+	}
+	return terms
+}
+
+// QueryTerms 将搜索词切分为检索词
+// 两个字以上的汉字串只取相邻两字，避免单字匹配带来大量无关结果；单个汉字按单字检索
+func QueryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	for _, seg := range segments(query) {
+		switch {
+		case !seg.han:
+			add(wordTerm(seg.runes))
+		case len(seg.runes) == 1:
+			add(string(seg.runes))
+		default:
+			for i := 0; i+1 < len(seg.runes); i++ {
+				add(string(seg.runes[i : i+2]))
+			}
+		}
+	}
+	return terms
+}
+
+// segments 按汉字、字母数字和其他字符切分文本，其他字符（空白、标点等）作为分隔符丢弃
+func segments(text string) []segment {
+	var result []segment
+	var current *segment
+	for _, r := range text {
+		r = normalizeRune(r)
+		han := unicode.Is(unicode.Han, r)
+		if !han && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			current = nil
+			continue
+		}
+		if current == nil || current.han != han {
+			result = append(result, segment{han: han})
+			current = &result[len(result)-1]
+		}
+		current.runes = append(current.runes, r)
+	}
+	return result
+}
+
+// normalizeRune 全角字符转半角并转为小写，一个字符只映射为一个字符，方便高亮时按位置对应原文
+func normalizeRune(r rune) rune {
+	switch {
+	case r == '　':
+		r = ' '
+	case r >= '！' && r <= '～':
+		r -= 0xFEE0
+	}
+	return unicode.ToLower(r)
+}
+
+func wordTerm(runes []rune) string {
+	if len(runes) > maxTermRunes {
+		runes = runes[:maxTermRunes]
+	}
+	return string(runes)
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"testogo/docs"
+	"testogo/internal/controller"
 	"testogo/internal/database"
 	"testogo/internal/middleware"
 	"testogo/internal/router"
@@ -31,6 +32,15 @@ func main() {
 		log.Printf("数据种子失败: %v", err)
 	}
 
+	// 恢复未完成的导入任务
+	controller.ResumeImportJobs()
+
+	// 恢复未完成的难度校准
+	controller.ResumeCalibrationRuns()
+
+	// 恢复未完成的重新判分
+	controller.ResumeRegradeJobs()
+
 	// 创建 Gin 引擎
 	app := gin.Default()
 
@@ -6,7 +6,10 @@ import (
 	"os"
 	"time"
 
+	"testogo/internal/dedup"
 	"testogo/internal/model/entity"
+	"testogo/internal/payload"
+	"testogo/internal/search"
 	"testogo/pkg/config"
 
 	"gorm.io/driver/mysql"
@@ -62,10 +65,22 @@ func Init() error {
 	sqlDB.SetMaxOpenConns(config.GetInt("database.maxOpenConns"))
 	sqlDB.SetConnMaxLifetime(time.Hour) // 设置连接最大生命周期
 
+	// 审核状态字段加入前创建的题目视为已发布
+	publishLegacyQuestions := db.Migrator().HasTable(&entity.Question{}) &&
+		!db.Migrator().HasColumn(&entity.Question{}, "status")
+	publishLegacyTemplates := db.Migrator().HasTable(&entity.QuestionTemplate{}) &&
+		!db.Migrator().HasColumn(&entity.QuestionTemplate{}, "status")
+
 	// 自动迁移数据库表
 	err = db.AutoMigrate(
 		&entity.User{},
 		&entity.Question{},
+		&entity.QuestionTemplate{},
+		&entity.QuestionRevision{},
+		&entity.QuestionReview{},
+		&entity.QuestionSearchTerm{},
+		&entity.HintReveal{},
+		&entity.Tag{},
 		&entity.Paper{},
 		&entity.UserAnswer{},
 		&entity.Grade{},
@@ -82,11 +97,145 @@ func Init() error {
 		&entity.ReinforcementLog{},
 		&entity.UserPerformance{},
 		&entity.UserSettings{},
+		&entity.ImportJob{},
+		&entity.ImportJobItem{},
+		&entity.CalibrationRun{},
+		&entity.ItemCalibration{},
+		&entity.LearnerAbility{},
+		&entity.RegradeJob{},
+		&entity.ResourceShare{},
+		&entity.DataMigration{},
 	)
 	if err != nil {
 		return err
 	}
 
+	if publishLegacyQuestions {
+		if err := db.Model(&entity.Question{}).Where("1 = 1").
+			UpdateColumn("status", entity.QuestionStatusPublished).Error; err != nil {
+			return err
+		}
+	}
+	if publishLegacyTemplates {
+		if err := db.Model(&entity.QuestionTemplate{}).Where("1 = 1").
+			UpdateColumn("status", entity.QuestionStatusPublished).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := migrateQuestionTags(db); err != nil {
+		return err
+	}
+
+	if err := migrateQuestionPayloads(db); err != nil {
+		return err
+	}
+
+	if err := migrateSearchIndex(db); err != nil {
+		return err
+	}
+
 	DB = db
 	return nil
 }
+
+// migrateQuestionTags 将尚未建立标签关联的题目的逗号分隔标签迁移到标签表
+func migrateQuestionTags(db *gorm.DB) error {
+	var questions []entity.Question
+	return db.Where("tags <> '' AND NOT EXISTS (SELECT 1 FROM question_tag WHERE question_tag.question_id = question.id)").
+		FindInBatches(&questions, 200, func(tx *gorm.DB, _ int) error {
+			for i := range questions {
+				names := entity.ParseTagNames(questions[i].Tags)
+				if len(names) == 0 {
+					continue
+				}
+				tags := make([]entity.Tag, len(names))
+				for j, name := range names {
+					if err := db.Where("name = ?", name).Attrs(entity.Tag{Name: name}).FirstOrCreate(&tags[j]).Error; err != nil {
+						return err
+					}
+				}
+				if err := db.Model(&questions[i]).Association("TagRefs").Replace(tags); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// migrateSearchIndex 为尚未建立全文检索索引的题目建立索引，启用搜索前已有的题目也能被检索到
+func migrateSearchIndex(db *gorm.DB) error {
+	var questions []entity.Question
+	return db.Where("NOT EXISTS (SELECT 1 FROM question_search_term WHERE question_search_term.question_id = question.id)").
+		FindInBatches(&questions, 200, func(tx *gorm.DB, _ int) error {
+			for i := range questions {
+				if err := search.IndexQuestion(db, &questions[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// migrateQuestionPayloads 将选项和媒体地址迁移为规范的JSON格式：
+// 选项的字符串数组和早期导入写入的逗号分隔文本转换为 QuestionOption 数组，媒体地址转换为字符串数组
+// 同时更新查重指纹和搜索索引；修订历史中的快照保持不变，恢复和比较版本时再转换
+// 不符合题型结构的题目保持原样并记录日志，由老师在编辑时修正；迁移只在首次启动时运行一次
+func migrateQuestionPayloads(db *gorm.DB) error {
+	const name = "question_payloads"
+	var done int64
+	if err := db.Model(&entity.DataMigration{}).Where("name = ?", name).Count(&done).Error; err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+
+	var questions []entity.Question
+	invalid := 0
+	err := db.Where("(options <> '' AND options NOT LIKE '[{%') OR (media_urls <> '' AND media_urls NOT LIKE '[%')").
+		FindInBatches(&questions, 200, func(tx *gorm.DB, _ int) error {
+			for i := range questions {
+				fields, errs := payload.NormalizeLegacy(payload.Payload{
+					Type:        questions[i].Type,
+					Options:     questions[i].Options,
+					Answer:      questions[i].Answer,
+					MediaURLs:   questions[i].MediaURLs,
+					ElementData: questions[i].ElementData,
+				})
+				if len(errs) > 0 {
+					invalid++
+					log.Printf("题目 %d 数据不符合题型结构，未迁移: %v", questions[i].ID, errs)
+					continue
+				}
+				if err := db.Transaction(func(tx *gorm.DB) error {
+					return migrateQuestionPayload(tx, &questions[i], fields)
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		log.Printf("%d 道题目的数据不符合题型结构，需要手动修正", invalid)
+	}
+	return db.Create(&entity.DataMigration{Name: name, CompletedAt: time.Now()}).Error
+}
+
+// migrateQuestionPayload 保存一道题目迁移后的选项和媒体地址
+// 媒体地址只改变格式，地址本身不变，媒体指纹不需要重新计算
+func migrateQuestionPayload(tx *gorm.DB, question *entity.Question, fields payload.Payload) error {
+	question.Options, question.MediaURLs = fields.Options, fields.MediaURLs
+	fp := dedup.NewFingerprint(question, question.MediaHash)
+	if err := tx.Model(question).UpdateColumns(map[string]interface{}{
+		"options":      fields.Options,
+		"media_urls":   fields.MediaURLs,
+		"content_hash": fp.ContentHash,
+	}).Error; err != nil {
+		return err
+	}
+	return search.IndexQuestion(tx, question)
+}